@@ -0,0 +1,120 @@
+package ttlcache
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// accessBufferBatches is the maximum number of full batches that may
+// wait to be drained. When it is reached, new batches are dropped.
+const accessBufferBatches = 16
+
+// touchBufferSize is the batch size of the access buffer that holds
+// the touches of items when a ConcurrentAccessPolicy is used and
+// buffered access recording is not enabled.
+const touchBufferSize = 16
+
+// access holds a single item retrieval that was made while the
+// cache's items were only read-locked.
+type access[K comparable, V any] struct {
+	item  *Item[K, V]
+	touch bool
+}
+
+// accessStripe holds the accesses that are being collected into a
+// single batch.
+type accessStripe[K comparable, V any] struct {
+	mu       sync.Mutex
+	accesses []access[K, V]
+}
+
+// accessBuffer is a lossy buffer of item accesses.
+// Accesses are spread across multiple stripes, so that concurrent
+// retrievals rarely contend for the same stripe. Full stripes are
+// handed over as batches that wait to be drained while the cache's
+// items are locked, while partially filled stripes are drained along
+// with them.
+// Accesses may be lost when the buffer is full or when their stripe
+// is being used by another retrieval.
+type accessBuffer[K comparable, V any] struct {
+	stripes []accessStripe[K, V]
+	next    uint32
+	size    int
+	batches chan []access[K, V]
+}
+
+// newAccessBuffer creates a new access buffer whose batches hold the
+// provided number of accesses.
+func newAccessBuffer[K comparable, V any](size int) *accessBuffer[K, V] {
+	n := 1
+	for n < runtime.GOMAXPROCS(0)*2 {
+		n <<= 1
+	}
+
+	b := &accessBuffer[K, V]{
+		stripes: make([]accessStripe[K, V], n),
+		size:    size,
+		batches: make(chan []access[K, V], accessBufferBatches),
+	}
+
+	for i := range b.stripes {
+		b.stripes[i].accesses = make([]access[K, V], 0, size)
+	}
+
+	return b
+}
+
+// push records the access of the provided item. It returns true if
+// the access completed a batch that should be drained.
+func (b *accessBuffer[K, V]) push(item *Item[K, V], touch bool) bool {
+	s := &b.stripes[atomic.AddUint32(&b.next, 1)&uint32(len(b.stripes)-1)]
+	if !s.mu.TryLock() {
+		// the stripe is busy, the access is dropped
+		return false
+	}
+
+	s.accesses = append(s.accesses, access[K, V]{item: item, touch: touch})
+
+	full := len(s.accesses) >= b.size
+	if full {
+		select {
+		case b.batches <- s.accesses:
+			s.accesses = make([]access[K, V], 0, b.size)
+		default:
+			// the buffer is full, the batch is dropped
+			s.accesses = s.accesses[:0]
+		}
+	}
+
+	s.mu.Unlock()
+
+	return full
+}
+
+// drain calls fn for every access of every full batch and of every
+// partially filled stripe.
+func (b *accessBuffer[K, V]) drain(fn func(access[K, V])) {
+	for drained := false; !drained; {
+		select {
+		case batch := <-b.batches:
+			for _, a := range batch {
+				fn(a)
+			}
+		default:
+			drained = true
+		}
+	}
+
+	for i := range b.stripes {
+		s := &b.stripes[i]
+
+		s.mu.Lock()
+		for _, a := range s.accesses {
+			fn(a)
+		}
+
+		s.accesses = s.accesses[:0]
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,80 @@
+package ttlcache
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newAccessBuffer(t *testing.T) {
+	b := newAccessBuffer[string, string](3)
+	require.NotNil(t, b)
+	assert.Equal(t, 3, b.size)
+	assert.Equal(t, accessBufferBatches, cap(b.batches))
+
+	assert.GreaterOrEqual(t, len(b.stripes), runtime.GOMAXPROCS(0))
+	assert.Zero(t, len(b.stripes)&(len(b.stripes)-1))
+
+	for i := range b.stripes {
+		assert.Empty(t, b.stripes[i].accesses)
+		assert.Equal(t, 3, cap(b.stripes[i].accesses))
+	}
+}
+
+func Test_accessBuffer_push(t *testing.T) {
+	b := newAccessBuffer[string, string](1)
+	item := &Item[string, string]{key: "1"}
+
+	assert.True(t, b.push(item, true))
+	require.Len(t, b.batches, 1)
+	assert.Equal(t, []access[string, string]{{item: item, touch: true}}, <-b.batches)
+
+	// full buffer
+	for i := 0; i < accessBufferBatches+5; i++ {
+		assert.True(t, b.push(item, false))
+	}
+
+	assert.Len(t, b.batches, accessBufferBatches)
+
+	// busy stripes drop accesses
+	b = newAccessBuffer[string, string](2)
+	for i := range b.stripes {
+		b.stripes[i].mu.Lock()
+	}
+
+	assert.False(t, b.push(item, false))
+
+	for i := range b.stripes {
+		assert.Empty(t, b.stripes[i].accesses)
+		b.stripes[i].mu.Unlock()
+	}
+}
+
+func Test_accessBuffer_drain(t *testing.T) {
+	b := newAccessBuffer[string, string](2)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+
+	b.batches <- []access[string, string]{{item: item1, touch: true}, {item: item2}}
+	b.batches <- []access[string, string]{{item: item2, touch: true}}
+	assert.False(t, b.push(item1, false))
+
+	var res []access[string, string]
+	b.drain(func(a access[string, string]) {
+		res = append(res, a)
+	})
+
+	assert.Equal(t, []access[string, string]{
+		{item: item1, touch: true},
+		{item: item2},
+		{item: item2, touch: true},
+		{item: item1},
+	}, res)
+	assert.Empty(t, b.batches)
+
+	for i := range b.stripes {
+		assert.Empty(t, b.stripes[i].accesses)
+	}
+}
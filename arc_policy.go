@@ -0,0 +1,186 @@
+package ttlcache
+
+import "container/list"
+
+// ARCPolicy is an Adaptive Replacement Cache eviction policy.
+// It keeps resident items in two LRU lists: T1 for items that were
+// used only once recently and T2 for items that were used at least
+// twice. The keys of recently evicted items are remembered in two
+// ghost lists (B1 and B2), which are used to continuously adapt the
+// target size of T1, so that the policy self-tunes between recency
+// and frequency as the workload shifts.
+// Ghost keys are not counted against the capacity of the cache.
+// Items that are deleted or expire do not leave ghosts behind.
+type ARCPolicy[K comparable, V any] struct {
+	t1 *list.List
+	t2 *list.List
+	b1 *list.List
+	b2 *list.List
+
+	resident map[K]*arcEntry
+	ghosts   map[K]*arcEntry
+
+	capacity int
+	target   int
+}
+
+// arcEntry holds the position of a single item or ghost key inside
+// of the policy's lists.
+type arcEntry struct {
+	elem *list.Element
+	list *list.List
+}
+
+// NewARCPolicy creates a new instance of ARC eviction policy.
+// The capacity parameter should match the capacity of the cache;
+// it limits the number of remembered ghost keys.
+func NewARCPolicy[K comparable, V any](capacity uint64) *ARCPolicy[K, V] {
+	return &ARCPolicy[K, V]{
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		resident: make(map[K]*arcEntry),
+		ghosts:   make(map[K]*arcEntry),
+		capacity: int(capacity),
+	}
+}
+
+// OnInsert places the item into T1 or, if its key was recently
+// evicted, into T2 and adapts the target size of T1.
+func (p *ARCPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	dst := p.t1
+
+	if ghost := p.ghosts[item.key]; ghost != nil {
+		switch ghost.list {
+		case p.b1:
+			// T1 was too small
+			p.target = minInt(p.capacity, p.target+maxInt(p.b2.Len()/p.b1.Len(), 1))
+		case p.b2:
+			// T2 was too small
+			p.target = maxInt(0, p.target-maxInt(p.b1.Len()/p.b2.Len(), 1))
+		}
+
+		p.removeGhost(item.key, ghost)
+		dst = p.t2
+	}
+
+	p.resident[item.key] = &arcEntry{
+		elem: dst.PushFront(item),
+		list: dst,
+	}
+
+	p.trimGhosts()
+}
+
+// OnAccess moves the item to the front of T2.
+func (p *ARCPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	entry := p.resident[item.key]
+	if entry == nil {
+		return
+	}
+
+	if entry.list == p.t2 {
+		p.t2.MoveToFront(entry.elem)
+		return
+	}
+
+	entry.list.Remove(entry.elem)
+	entry.elem = p.t2.PushFront(item)
+	entry.list = p.t2
+}
+
+// OnUpdate moves the item to the front of T2.
+func (p *ARCPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	p.OnAccess(item)
+}
+
+// OnRemove removes the item from its list. If the item was evicted
+// because the capacity of the cache was reached, its key is
+// remembered in the matching ghost list.
+func (p *ARCPolicy[K, V]) OnRemove(item *Item[K, V], reason EvictionReason) {
+	entry := p.resident[item.key]
+	if entry == nil {
+		return
+	}
+
+	entry.list.Remove(entry.elem)
+	delete(p.resident, item.key)
+
+	if reason != EvictionReasonCapacityReached {
+		return
+	}
+
+	ghosts := p.b1
+	if entry.list == p.t2 {
+		ghosts = p.b2
+	}
+
+	p.ghosts[item.key] = &arcEntry{
+		elem: ghosts.PushFront(item.key),
+		list: ghosts,
+	}
+
+	p.trimGhosts()
+}
+
+// Victim returns the least recently used item of T1 if T1 exceeds
+// its target size, or the least recently used item of T2 otherwise.
+func (p *ARCPolicy[K, V]) Victim() *Item[K, V] {
+	var elem *list.Element
+
+	switch {
+	case p.t1.Len() > 0 && (p.t1.Len() > p.target || p.t2.Len() == 0):
+		elem = p.t1.Back()
+	case p.t2.Len() > 0:
+		elem = p.t2.Back()
+	default:
+		return nil
+	}
+
+	return elem.Value.(*Item[K, V])
+}
+
+// removeGhost removes the provided ghost key.
+func (p *ARCPolicy[K, V]) removeGhost(key K, ghost *arcEntry) {
+	ghost.list.Remove(ghost.elem)
+	delete(p.ghosts, key)
+}
+
+// trimGhosts removes the least recently used ghost keys until
+// T1 and B1 together do not exceed the capacity and all lists
+// together do not exceed twice the capacity.
+func (p *ARCPolicy[K, V]) trimGhosts() {
+	for p.b1.Len() > 0 && p.t1.Len()+p.b1.Len() > p.capacity {
+		key := p.b1.Back().Value.(K)
+		p.removeGhost(key, p.ghosts[key])
+	}
+
+	for p.b1.Len()+p.b2.Len() > 0 && len(p.resident)+len(p.ghosts) > 2*p.capacity {
+		ghosts := p.b2
+		if ghosts.Len() == 0 {
+			ghosts = p.b1
+		}
+
+		key := ghosts.Back().Value.(K)
+		p.removeGhost(key, p.ghosts[key])
+	}
+}
+
+// minInt returns the smaller of the provided values.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the larger of the provided values.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
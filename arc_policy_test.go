@@ -0,0 +1,187 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewARCPolicy(t *testing.T) {
+	p := NewARCPolicy[string, string](10)
+	require.NotNil(t, p)
+	assert.NotNil(t, p.t1)
+	assert.NotNil(t, p.t2)
+	assert.NotNil(t, p.b1)
+	assert.NotNil(t, p.b2)
+	assert.NotNil(t, p.resident)
+	assert.NotNil(t, p.ghosts)
+	assert.Equal(t, 10, p.capacity)
+	assert.Zero(t, p.target)
+}
+
+func Test_ARCPolicy_OnInsert(t *testing.T) {
+	p := NewARCPolicy[string, string](4)
+
+	// new key
+	p.OnInsert(&Item[string, string]{key: "1"})
+	assert.Equal(t, 1, p.t1.Len())
+	assert.Same(t, p.t1, p.resident["1"].list)
+
+	// key in B1
+	p.OnRemove(&Item[string, string]{key: "1"}, EvictionReasonCapacityReached)
+	require.Equal(t, 1, p.b1.Len())
+
+	p.OnInsert(&Item[string, string]{key: "1"})
+	assert.Equal(t, 1, p.target)
+	assert.Same(t, p.t2, p.resident["1"].list)
+	assert.Zero(t, p.b1.Len())
+	assert.Empty(t, p.ghosts)
+
+	// key in B2
+	p.OnRemove(&Item[string, string]{key: "1"}, EvictionReasonCapacityReached)
+	require.Equal(t, 1, p.b2.Len())
+
+	p.OnInsert(&Item[string, string]{key: "1"})
+	assert.Zero(t, p.target)
+	assert.Same(t, p.t2, p.resident["1"].list)
+	assert.Zero(t, p.b2.Len())
+	assert.Empty(t, p.ghosts)
+}
+
+func Test_ARCPolicy_OnAccess(t *testing.T) {
+	p := NewARCPolicy[string, string](4)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+
+	p.OnAccess(item1)
+	assert.Same(t, p.t2, p.resident["1"].list)
+	assert.Equal(t, 1, p.t1.Len())
+
+	p.OnAccess(item2)
+	p.OnAccess(item1)
+	assert.Same(t, item1, p.t2.Front().Value)
+	assert.Zero(t, p.t1.Len())
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "3"})
+	assert.Len(t, p.resident, 2)
+}
+
+func Test_ARCPolicy_OnUpdate(t *testing.T) {
+	p := NewARCPolicy[string, string](4)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	p.OnUpdate(item)
+	assert.Same(t, p.t2, p.resident["1"].list)
+}
+
+func Test_ARCPolicy_OnRemove(t *testing.T) {
+	p := NewARCPolicy[string, string](4)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnInsert(item3)
+	p.OnAccess(item2)
+
+	// evicted items become ghosts
+	p.OnRemove(item1, EvictionReasonCapacityReached)
+	p.OnRemove(item2, EvictionReasonCapacityReached)
+	assert.Empty(t, p.t2.Len())
+	assert.Equal(t, 1, p.t1.Len())
+	assert.Same(t, p.b1, p.ghosts["1"].list)
+	assert.Same(t, p.b2, p.ghosts["2"].list)
+
+	// expired and deleted items do not
+	p.OnRemove(item3, EvictionReasonExpired)
+	assert.Empty(t, p.resident)
+	assert.Len(t, p.ghosts, 2)
+
+	// unknown items are ignored
+	p.OnRemove(item3, EvictionReasonCapacityReached)
+	assert.Len(t, p.ghosts, 2)
+}
+
+func Test_ARCPolicy_Victim(t *testing.T) {
+	p := NewARCPolicy[string, string](4)
+	assert.Nil(t, p.Victim())
+
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnInsert(item3)
+
+	// T1 exceeds its target
+	assert.Same(t, item1, p.Victim())
+
+	// T1 does not exceed its target
+	p.OnAccess(item2)
+	p.target = 2
+	assert.Same(t, item2, p.Victim())
+
+	// T2 is empty
+	p.OnRemove(item2, EvictionReasonDeleted)
+	assert.Same(t, item1, p.Victim())
+}
+
+func Test_ARCPolicy_trimGhosts(t *testing.T) {
+	p := NewARCPolicy[string, string](2)
+
+	for _, key := range []string{"1", "2", "3"} {
+		p.OnInsert(&Item[string, string]{key: key})
+		p.OnRemove(&Item[string, string]{key: key}, EvictionReasonCapacityReached)
+	}
+
+	// T1 and B1 do not exceed the capacity
+	assert.Equal(t, 2, p.b1.Len())
+	assert.NotContains(t, p.ghosts, "1")
+
+	for _, key := range []string{"4", "5", "6", "7"} {
+		item := &Item[string, string]{key: key}
+		p.OnInsert(item)
+		p.OnAccess(item)
+		p.OnRemove(item, EvictionReasonCapacityReached)
+	}
+
+	// all lists do not exceed twice the capacity
+	assert.Len(t, p.ghosts, 4)
+	assert.Equal(t, 1, p.b1.Len())
+	assert.Equal(t, 3, p.b2.Len())
+	assert.NotContains(t, p.ghosts, "4")
+}
+
+func Test_Cache_ARCPolicy(t *testing.T) {
+	p := NewARCPolicy[string, string](3)
+	cache := New[string, string](
+		WithCapacity[string, string](3),
+		WithEvictionPolicy[string, string](p),
+	)
+
+	cache.Set("1", "1", NoTTL)
+	cache.Set("2", "2", NoTTL)
+	cache.Get("1")
+	cache.Get("2")
+	cache.Set("3", "3", NoTTL)
+	cache.Set("4", "4", NoTTL)
+
+	// recently used once item is evicted first
+	assert.ElementsMatch(t, []string{"1", "2", "4"}, cache.Keys())
+	assert.Contains(t, p.ghosts, "3")
+
+	// expired items do not leave ghosts behind
+	cache.Set("5", "5", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cache.DeleteExpired()
+
+	assert.Len(t, cache.Keys(), 2)
+	assert.NotContains(t, p.ghosts, "5")
+	assert.Len(t, p.resident, 2)
+}
@@ -1,10 +1,12 @@
 package ttlcache
 
 import (
-	"container/list"
 	"context"
-	"fmt"
+	"errors"
+	"io"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/singleflight"
@@ -15,24 +17,74 @@ const (
 	EvictionReasonDeleted EvictionReason = iota + 1
 	EvictionReasonCapacityReached
 	EvictionReasonExpired
+	EvictionReasonRejected
+	EvictionReasonMaxCostExceeded
 )
 
 // EvictionReason is used to specify why a certain item was
 // evicted/deleted.
 type EvictionReason int
 
+// ErrKnownAbsent is returned by GetE and GetContext when the loader
+// recently did not find the item and negative caching is enabled
+// (see WithNegativeTTL), so the loader was not called again.
+var ErrKnownAbsent = errors.New("ttlcache: item is known to be absent")
+
+// ErrDeltaUnavailable is returned by SaveSince when the changes since
+// the provided token are not known, e.g. because the token was
+// returned by another cache instance or by an earlier SaveSince call
+// than the latest successful one, all items were deleted since, or
+// version tracking is disabled (see WithVersion).
+// A full snapshot should be saved with the zero token instead.
+var ErrDeltaUnavailable = errors.New("ttlcache: changes since the snapshot token are unknown")
+
+// lastEpoch is the epoch of the most recently created cache.
+var lastEpoch = uint64(time.Now().UnixNano())
+
 // Cache is a synchronised map of items that are automatically removed
 // when they expire or the capacity is reached.
 type Cache[K comparable, V any] struct {
 	items struct {
-		mu     sync.RWMutex
-		values map[K]*list.Element
-
-		// a generic doubly linked list would be more convenient
-		// (and more performant?). It's possible that this
-		// will be introduced with/in go1.19+
-		lru      *list.List
+		mu       sync.RWMutex
+		values   map[K]*Item[K, V]
+		policy   EvictionPolicy[K, V]
 		expQueue expirationQueue[K, V]
+		cost     uint64
+
+		// accessBuf is nil unless buffered access recording is
+		// enabled.
+		accessBuf *accessBuffer[K, V]
+
+		// wheel is nil unless the timing wheel is used instead of
+		// the expiration queue.
+		wheel *timingWheel[K, V]
+
+		// absent is nil unless negative caching is enabled.
+		absent *tombstones[K]
+
+		// wal is nil unless the cache was created with Open.
+		wal *wal[K, V]
+
+		// loads holds the deletions of the keys whose data is being
+		// loaded, so that the loads are able to detect that their
+		// data may be outdated. Keys without loads in-flight are not
+		// tracked.
+		loads map[K]*keyLoads
+
+		// deleted holds the keys of the items that were deleted
+		// since the latest SaveSince call. It is nil until SaveSince
+		// is called or if version tracking is disabled.
+		deleted map[K]struct{}
+
+		// snapshotSeq is the sequence number of the latest snapshot
+		// that was written by SaveSince.
+		snapshotSeq uint64
+
+		// deltaSeq is the sequence number of the latest snapshot that
+		// delta snapshots can be written since, or 0 if deltas are
+		// unavailable (e.g. because the deletions of all items were
+		// not tracked).
+		deltaSeq uint64
 
 		timerCh chan time.Duration
 	}
@@ -51,8 +103,34 @@ type Cache[K comparable, V any] struct {
 			nextID uint64
 			fns    map[uint64]func(EvictionReason, *Item[K, V])
 		}
+		refreshFailure struct {
+			mu     sync.RWMutex
+			nextID uint64
+			fns    map[uint64]func(K, error)
+		}
+	}
+
+	// refreshes holds the keys of items that are being reloaded in
+	// the background.
+	refreshes struct {
+		mu   sync.Mutex
+		keys map[K]struct{}
+	}
+
+	// batches holds the in-flight calls of the batch loader by the
+	// keys that they load.
+	batches struct {
+		mu    sync.Mutex
+		calls map[K]*batchCall[K, V]
 	}
 
+	// epoch identifies the cache instance in snapshot tokens.
+	epoch uint64
+
+	// deltaMu serializes SaveSince calls, so that the items are
+	// marked as saved in the order in which the snapshots are written.
+	deltaMu sync.Mutex
+
 	stopCh  chan struct{}
 	options options[K, V]
 }
@@ -60,45 +138,145 @@ type Cache[K comparable, V any] struct {
 // New creates a new instance of cache.
 func New[K comparable, V any](opts ...Option[K, V]) *Cache[K, V] {
 	c := &Cache[K, V]{
+		epoch:  atomic.AddUint64(&lastEpoch, 1),
 		stopCh: make(chan struct{}),
 	}
-	c.items.values = make(map[K]*list.Element)
-	c.items.lru = list.New()
+	c.items.values = make(map[K]*Item[K, V])
 	c.items.expQueue = newExpirationQueue[K, V]()
 	c.items.timerCh = make(chan time.Duration, 1) // buffer is important
 	c.events.insertion.fns = make(map[uint64]func(*Item[K, V]))
 	c.events.eviction.fns = make(map[uint64]func(EvictionReason, *Item[K, V]))
+	c.events.refreshFailure.fns = make(map[uint64]func(K, error))
+	c.refreshes.keys = make(map[K]struct{})
+	c.batches.calls = make(map[K]*batchCall[K, V])
 
 	applyOptions(&c.options, opts...)
 
+	c.items.policy = c.options.evictionPolicy
+	if c.items.policy == nil && c.options.evictionPolicyFunc != nil {
+		limit := c.options.capacity
+		if limit == 0 {
+			limit = c.options.maxCost
+		}
+
+		c.items.policy = c.options.evictionPolicyFunc(limit)
+	}
+
+	if c.items.policy == nil {
+		c.items.policy = NewLRUPolicy[K, V]()
+	}
+
+	if c.options.accessBufferSize > 0 {
+		c.items.accessBuf = newAccessBuffer[K, V](c.options.accessBufferSize)
+	} else if _, ok := c.items.policy.(ConcurrentAccessPolicy[K, V]); ok {
+		// the policy records accesses by itself, so only the touches
+		// of items need to be buffered
+		c.items.accessBuf = newAccessBuffer[K, V](touchBufferSize)
+	}
+
+	if c.options.wheelTick > 0 {
+		c.items.wheel = newTimingWheel[K, V](c.options.wheelTick)
+	}
+
+	if c.options.negativeTTL > 0 {
+		c.items.absent = newTombstones[K](c.options.negativeTTL, c.options.negativeCapacity)
+	}
+
 	return c
 }
 
-// updateExpirations updates the expiration queue and notifies
-// the cache auto cleaner if needed.
+// Open creates a new instance of cache whose changes are persisted in
+// a write-ahead log, so that they survive restarts and crashes.
+// Every Set, Delete, DeleteAll, eviction and expiration timestamp
+// extension is appended to the log in the configured directory, which
+// is synced to the disk according to the configured sync policy.
+// Before the cache is returned, its items are recovered from the
+// latest snapshot in the directory and the log records that follow
+// it. Once the log grows past the configured threshold, a fresh
+// snapshot is written in the background and the log records that it
+// replaces are removed (see Compact).
+// The snapshots are encoded with the snapshot codec of the cache
+// (see WithSnapshotCodec), while the keys and values of the log
+// records are encoded with its key and value codecs (see
+// WithKeyCodec), or with the encoding/gob package by default.
+// Close must be called when the cache is no longer used.
+func Open[K comparable, V any](cfg WALConfig, opts ...Option[K, V]) (*Cache[K, V], error) {
+	c := New[K, V](opts...)
+
+	codec := newCodecSnapshotCodec(c.options.keyCodec, c.options.valueCodec)
+
+	w, items, err := openWAL(cfg, codec, c.options.snapshotCodecOrDefault())
+	if err != nil {
+		return nil, err
+	}
+
+	c.restore(nil, items, false)
+	c.items.wal = w
+
+	return c, nil
+}
+
+// updateExpirations updates the expiration queue (or the timing
+// wheel) and notifies the cache auto cleaner if needed.
 // Not concurrently safe.
-func (c *Cache[K, V]) updateExpirations(fresh bool, elem *list.Element) {
+func (c *Cache[K, V]) updateExpirations(fresh bool, item *Item[K, V]) {
+	if c.items.wheel != nil {
+		wasEmpty := c.items.wheel.isEmpty()
+		c.items.wheel.schedule(item)
+
+		if wasEmpty && !c.items.wheel.isEmpty() {
+			c.notifyCleaner(c.items.wheel.tick)
+		}
+
+		return
+	}
+
 	var oldExpiresAt time.Time
 
 	if !c.items.expQueue.isEmpty() {
-		oldExpiresAt = c.items.expQueue[0].Value.(*Item[K, V]).expiresAt
+		oldExpiresAt = c.items.expQueue[0].expiresAt
 	}
 
 	if fresh {
-		c.items.expQueue.push(elem)
+		c.items.expQueue.push(item)
 	} else {
-		c.items.expQueue.update(elem)
+		c.items.expQueue.update(item)
 	}
 
-	newExpiresAt := c.items.expQueue[0].Value.(*Item[K, V]).expiresAt
+	newExpiresAt := c.items.expQueue[0].expiresAt
 
 	// check if the closest/soonest expiration timestamp changed
 	if newExpiresAt.IsZero() || (!oldExpiresAt.IsZero() && !newExpiresAt.Before(oldExpiresAt)) {
 		return
 	}
 
-	d := time.Until(newExpiresAt)
+	c.notifyCleaner(time.Until(newExpiresAt.Add(c.options.gracePeriod)))
+}
+
+// markChanged records the change of the provided item, so that its
+// key is no longer written as deleted by the next SaveSince call.
+// The item itself is written because its version or expiration
+// timestamp differs from the saved one.
+// Not concurrently safe.
+func (c *Cache[K, V]) markChanged(item *Item[K, V]) {
+	if c.items.deleted != nil {
+		delete(c.items.deleted, item.key)
+	}
+}
+
+// markDeleted records the deletion of the item with the provided key,
+// so that it is written by the next SaveSince call.
+// Not concurrently safe.
+func (c *Cache[K, V]) markDeleted(key K) {
+	if c.items.deleted != nil {
+		c.items.deleted[key] = struct{}{}
+	}
+}
 
+// notifyCleaner notifies the cache auto cleaner that it should run
+// after the provided duration.
+// Not concurrently safe.
+func (c *Cache[K, V]) notifyCleaner(d time.Duration) {
 	// It's possible that the auto cleaner isn't active or
 	// is busy, so we need to drain the channel before
 	// sending a new value.
@@ -125,37 +303,106 @@ func (c *Cache[K, V]) updateExpirations(fresh bool, elem *list.Element) {
 }
 
 // set creates a new item, adds it to the cache and then returns it.
+// The item's cost is calculated with the cache's cost function.
 // Not concurrently safe.
 func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) *Item[K, V] {
+	var cost uint64 = 1
+	if c.options.costFunc != nil {
+		cost = c.options.costFunc(key, value)
+	}
+
+	return c.setWithCost(key, value, ttl, cost)
+}
+
+// setWithCost creates a new item with the provided cost, adds it
+// to the cache and then returns it.
+// It returns nil if the cost exceeds the maximum cost of the cache.
+// Not concurrently safe.
+func (c *Cache[K, V]) setWithCost(key K, value V, ttl time.Duration, cost uint64) *Item[K, V] {
+	item := c.setItem(key, value, ttl, cost, true)
+	if item != nil {
+		c.logWAL(walOpSet, item)
+	}
+
+	return item
+}
+
+// setItem creates a new item with the provided cost, adds it to the
+// cache and then returns it, just like setWithCost does. The insertion
+// event is fired only if the notify flag is set.
+// Not concurrently safe.
+func (c *Cache[K, V]) setItem(key K, value V, ttl time.Duration, cost uint64, notify bool) *Item[K, V] {
 	if ttl == DefaultTTL {
 		ttl = c.options.ttl
 	}
 
-	elem := c.get(key, false)
-	if elem != nil {
+	if c.options.maxCost != 0 && cost > c.options.maxCost {
+		c.reject(key, value, ttl, cost)
+		return nil
+	}
+
+	if c.items.absent != nil {
+		// the key is no longer absent
+		c.items.absent.remove(key)
+	}
+
+	if item := c.items.values[key]; item != nil && item.isExpiredUnsafe() {
+		// the expired item must be removed before it is replaced
+		c.evict(EvictionReasonExpired, item)
+	}
+
+	item := c.items.values[key]
+	if item != nil {
 		// update/overwrite an existing item
-		item := elem.Value.(*Item[K, V])
+		c.items.cost = c.items.cost - item.cost + cost
+
 		item.update(value, ttl)
-		c.updateExpirations(false, elem)
+		item.updateCost(cost)
+		c.items.policy.OnUpdate(item)
+		c.updateExpirations(false, item)
+		c.markChanged(item)
+
+		for c.options.maxCost != 0 && c.items.cost > c.options.maxCost {
+			if !c.evictVictim() {
+				break
+			}
+
+			if c.items.values[key] != item {
+				// the updated item itself was evicted
+				return nil
+			}
+		}
 
 		return item
 	}
 
 	if c.options.capacity != 0 && uint64(len(c.items.values)) >= c.options.capacity {
-		// delete the oldest item
-		c.evict(EvictionReasonCapacityReached, c.items.lru.Back())
+		c.evictVictim()
+	}
+
+	for c.options.maxCost != 0 && c.items.cost+cost > c.options.maxCost {
+		if !c.evictVictim() {
+			break
+		}
 	}
 
 	// create a new item
-	item := newItem(key, value, ttl, c.options.enableVersionTrack)
-	elem = c.items.lru.PushFront(item)
-	c.items.values[key] = elem
-	c.updateExpirations(true, elem)
+	item = newItem(key, value, ttl, c.options.enableVersionTrack)
+	item.cost = cost
+	c.items.values[key] = item
+	c.items.cost += cost
+	c.items.policy.OnInsert(item)
+	c.updateExpirations(true, item)
+	c.markChanged(item)
 
 	c.metricsMu.Lock()
 	c.metrics.Insertions++
 	c.metricsMu.Unlock()
 
+	if !notify {
+		return item
+	}
+
 	c.events.insertion.mu.RLock()
 	for _, fn := range c.events.insertion.fns {
 		fn(item)
@@ -165,29 +412,159 @@ func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) *Item[K, V] {
 	return item
 }
 
+// evictVictim evicts the item chosen by the eviction policy.
+// It returns false if the policy did not choose any item.
+// Not concurrently safe.
+func (c *Cache[K, V]) evictVictim() bool {
+	// the policy must know about all recent accesses
+	c.drainAccesses()
+
+	victim := c.items.policy.Victim()
+	if victim == nil {
+		return false
+	}
+
+	reason := EvictionReasonCapacityReached
+	if p, ok := c.items.policy.(AdmissionPolicy[K, V]); ok && p.Rejected() {
+		reason = EvictionReasonRejected
+
+		c.metricsMu.Lock()
+		c.metrics.Rejections++
+		c.metricsMu.Unlock()
+	}
+
+	c.evict(reason, victim)
+
+	return true
+}
+
+// reject notifies the eviction subscribers about an item that
+// could not be stored because its cost exceeds the maximum cost of
+// the cache. An existing item with the same key is deleted.
+// Not concurrently safe.
+func (c *Cache[K, V]) reject(key K, value V, ttl time.Duration, cost uint64) {
+	c.delete(key)
+
+	c.metricsMu.Lock()
+	c.metrics.CostRejections++
+	c.metricsMu.Unlock()
+
+	item := newItem(key, value, ttl, c.options.enableVersionTrack)
+	item.cost = cost
+
+	c.events.eviction.mu.RLock()
+	for _, fn := range c.events.eviction.fns {
+		fn(EvictionReasonMaxCostExceeded, item)
+	}
+	c.events.eviction.mu.RUnlock()
+}
+
 // get retrieves an item from the cache and extends its expiration
 // time if 'touch' is set to true.
-// It returns nil if the item is not found or is expired.
+// It returns nil if the item is not found or is expired, unless it
+// expired within the grace period.
 // Not concurrently safe.
-func (c *Cache[K, V]) get(key K, touch bool) *list.Element {
-	elem := c.items.values[key]
-	if elem == nil {
+func (c *Cache[K, V]) get(key K, touch bool) *Item[K, V] {
+	item := c.items.values[key]
+	if item == nil {
 		return nil
 	}
 
-	item := elem.Value.(*Item[K, V])
-	if item.isExpiredUnsafe() {
+	if !c.isUsable(item) {
 		return nil
 	}
 
-	c.items.lru.MoveToFront(elem)
+	c.items.policy.OnAccess(item)
 
-	if touch && item.ttl > 0 {
+	// stale items must not become fresh again
+	if touch && item.ttl > 0 && !item.isExpiredUnsafe() {
 		item.touch()
-		c.updateExpirations(false, elem)
+		c.updateExpirations(false, item)
+		c.markChanged(item)
+		c.logWAL(walOpTouch, item)
+	}
+
+	return item
+}
+
+// lockedGet wraps the get method with the locking of the cache's
+// items. If buffered access recording is enabled, or the eviction
+// policy supports concurrent access recording, the items are only
+// read-locked, while the accesses and the touches are buffered.
+func (c *Cache[K, V]) lockedGet(key K, touch bool) *Item[K, V] {
+	if c.items.accessBuf != nil {
+		c.items.mu.RLock()
+
+		item := c.items.values[key]
+		if item == nil || !c.isUsable(item) {
+			c.items.mu.RUnlock()
+			return nil
+		}
+
+		touch = touch && item.ttl > 0 && !item.isExpiredUnsafe()
+
+		// concurrent policies record accesses immediately, so only
+		// touches are buffered for them
+		p, concurrent := c.items.policy.(ConcurrentAccessPolicy[K, V])
+		if concurrent {
+			p.OnConcurrentAccess(item)
+		}
+
+		c.items.mu.RUnlock()
+
+		if (!concurrent || touch) && c.items.accessBuf.push(item, touch) && c.items.mu.TryLock() {
+			c.drainAccesses()
+			c.items.mu.Unlock()
+		}
+
+		return item
+	}
+
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	return c.get(key, touch)
+}
+
+// isUsable checks whether the item may be returned to callers, i.e.
+// whether it is not expired or expired no longer than the grace period
+// ago.
+// Not concurrently safe.
+func (c *Cache[K, V]) isUsable(item *Item[K, V]) bool {
+	if !item.isExpiredUnsafe() {
+		return true
+	}
+
+	return c.options.gracePeriod > 0 && time.Since(item.expiresAt) <= c.options.gracePeriod
+}
+
+// drainAccesses applies all buffered accesses to the eviction policy
+// (unless it records them concurrently) and to the expiration
+// timestamps of their items. Accesses of items
+// that were removed or expired since are skipped, so that they are
+// not revived.
+// Not concurrently safe.
+func (c *Cache[K, V]) drainAccesses() {
+	if c.items.accessBuf == nil {
+		return
 	}
 
-	return elem
+	c.items.accessBuf.drain(func(a access[K, V]) {
+		if c.items.values[a.item.key] != a.item || a.item.isExpiredUnsafe() {
+			return
+		}
+
+		if _, ok := c.items.policy.(ConcurrentAccessPolicy[K, V]); !ok {
+			c.items.policy.OnAccess(a.item)
+		}
+
+		if a.touch && a.item.ttl > 0 {
+			a.item.touch()
+			c.updateExpirations(false, a.item)
+			c.markChanged(a.item)
+			c.logWAL(walOpTouch, a.item)
+		}
+	})
 }
 
 // getWithOpts wraps the get method applying the given options.
@@ -195,7 +572,9 @@ func (c *Cache[K, V]) get(key K, touch bool) *list.Element {
 // It returns nil if the item is not found or is expired.
 // The useLoader flag signals to use the loader if data not found in cache.
 // Also when the useLoader flag is set, the cache is locked.
-func (c *Cache[K, V]) getWithOpts(key K, useLoader bool, opts ...Option[K, V]) *Item[K, V] {
+// The returned error is the one returned by the loader, which receives
+// the provided context.
+func (c *Cache[K, V]) getWithOpts(ctx context.Context, key K, useLoader bool, opts ...Option[K, V]) (*Item[K, V], error) {
 	getOpts := options[K, V]{
 		loader:            c.options.loader,
 		disableTouchOnHit: c.options.disableTouchOnHit,
@@ -203,135 +582,675 @@ func (c *Cache[K, V]) getWithOpts(key K, useLoader bool, opts ...Option[K, V]) *
 
 	applyOptions(&getOpts, opts...)
 
-	if useLoader {
-		c.items.mu.Lock()
-	}
-
-	elem := c.get(key, !getOpts.disableTouchOnHit)
+	var item *Item[K, V]
 
 	if useLoader {
-		c.items.mu.Unlock()
+		item = c.lockedGet(key, !getOpts.disableTouchOnHit)
+	} else {
+		item = c.get(key, !getOpts.disableTouchOnHit)
 	}
 
-	if elem == nil {
+	if item == nil {
 		c.metricsMu.Lock()
 		c.metrics.Misses++
 		c.metricsMu.Unlock()
 
 		if useLoader && getOpts.loader != nil {
-			return getOpts.loader.Load(c, key)
+			if c.isKnownAbsent(key) {
+				c.metricsMu.Lock()
+				c.metrics.NegativeHits++
+				c.metricsMu.Unlock()
+
+				return nil, ErrKnownAbsent
+			}
+
+			item, err := c.load(ctx, getOpts.loader, key)
+			if item == nil && err == nil {
+				c.markAbsent(key)
+			}
+
+			return item, err
 		}
 
-		return nil
+		return nil, nil
+	}
+
+	if !useLoader {
+		getOpts.loader = nil
 	}
 
+	c.hit(getOpts.loader, item)
+
+	return item, nil
+}
+
+// hit updates the metrics after a successful retrieval of the item.
+// If the loader is not nil, it is used to reload the item in the
+// background when the item is stale or old enough to be refreshed.
+// The cache must not be locked if the loader is not nil.
+func (c *Cache[K, V]) hit(loader Loader[K, V], item *Item[K, V]) {
+	stale := item.IsExpired()
+
 	c.metricsMu.Lock()
 	c.metrics.Hits++
+	if stale {
+		c.metrics.StaleHits++
+	}
 	c.metricsMu.Unlock()
 
-	return elem.Value.(*Item[K, V])
+	if loader != nil && (stale || c.options.refreshAfter > 0 &&
+		time.Since(item.UpdatedAt()) >= c.options.refreshAfter) &&
+		item.canRefresh() {
+		c.refresh(loader, item)
+	}
 }
 
-// evict deletes items from the cache.
-// If no items are provided, all currently present cache items
-// are evicted.
-// Not concurrently safe.
-func (c *Cache[K, V]) evict(reason EvictionReason, elems ...*list.Element) {
-	if len(elems) > 0 {
-		c.metricsMu.Lock()
-		c.metrics.Evictions += uint64(len(elems))
-		c.metricsMu.Unlock()
+// getMany retrieves the items associated with the provided keys,
+// just like getWithOpts does for each of them. If a batch loader is
+// set, all missing items are loaded with it at once.
+// The returned error is the first one returned by the loaders.
+// The cache must not be locked.
+func (c *Cache[K, V]) getMany(ctx context.Context, keys []K, opts ...Option[K, V]) (map[K]*Item[K, V], error) {
+	getOpts := options[K, V]{
+		loader:            c.options.loader,
+		batchLoader:       c.options.batchLoader,
+		disableTouchOnHit: c.options.disableTouchOnHit,
+	}
 
-		c.events.eviction.mu.RLock()
-		for i := range elems {
-			item := elems[i].Value.(*Item[K, V])
-			delete(c.items.values, item.key)
-			c.items.lru.Remove(elems[i])
-			c.items.expQueue.remove(elems[i])
+	applyOptions(&getOpts, opts...)
 
-			for _, fn := range c.events.eviction.fns {
-				fn(reason, item)
+	var (
+		res     = make(map[K]*Item[K, V], len(keys))
+		seen    = make(map[K]struct{}, len(keys))
+		missing []K
+		err     error
+	)
+
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if getOpts.batchLoader == nil {
+			item, loadErr := c.getWithOpts(ctx, key, true, opts...)
+			if item != nil {
+				res[key] = item
+			}
+
+			if loadErr != nil && loadErr != ErrKnownAbsent && err == nil {
+				err = loadErr
 			}
+
+			continue
 		}
-		c.events.eviction.mu.RUnlock()
 
-		return
-	}
+		item := c.lockedGet(key, !getOpts.disableTouchOnHit)
+		if item != nil {
+			c.hit(getOpts.loader, item)
+			res[key] = item
 
-	c.metricsMu.Lock()
-	c.metrics.Evictions += uint64(len(c.items.values))
-	c.metricsMu.Unlock()
+			continue
+		}
 
-	c.events.eviction.mu.RLock()
-	for _, elem := range c.items.values {
-		item := elem.Value.(*Item[K, V])
+		c.metricsMu.Lock()
+		c.metrics.Misses++
+		c.metricsMu.Unlock()
 
-		for _, fn := range c.events.eviction.fns {
-			fn(reason, item)
+		if c.isKnownAbsent(key) {
+			c.metricsMu.Lock()
+			c.metrics.NegativeHits++
+			c.metricsMu.Unlock()
+
+			continue
 		}
+
+		missing = append(missing, key)
 	}
-	c.events.eviction.mu.RUnlock()
 
-	c.items.values = make(map[K]*list.Element)
-	c.items.lru.Init()
-	c.items.expQueue = newExpirationQueue[K, V]()
-}
+	if len(missing) == 0 {
+		return res, err
+	}
 
-// Set creates a new item from the provided key and value, adds
-// it to the cache and then returns it. If an item associated with the
-// provided key already exists, the new item overwrites the existing one.
-func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) *Item[K, V] {
-	c.items.mu.Lock()
-	defer c.items.mu.Unlock()
+	loaded, err := c.loadBatch(ctx, getOpts.batchLoader, missing)
+	for key, item := range loaded {
+		res[key] = item
+	}
 
-	return c.set(key, value, ttl)
+	return res, err
 }
 
-// Get retrieves an item from the cache by the provided key.
-// Unless this is disabled, it also extends/touches an item's
-// expiration timestamp on successful retrieval.
-// If the item is not found, a nil value is returned.
-func (c *Cache[K, V]) Get(key K, opts ...Option[K, V]) *Item[K, V] {
-	return c.getWithOpts(key, true, opts...)
-}
+// loadBatch loads the items associated with the provided keys using
+// the batch loader. Keys that are already being loaded by other
+// callers are not loaded again; the results of their in-flight calls
+// are awaited instead. The remaining keys are split into batches that
+// do not exceed the maximum batch size and are loaded concurrently.
+// The batch loader receives the values of the provided context, but
+// not its cancellation.
+// The returned error is the first one returned by the batch loader or
+// the context's error if it is done before all calls complete.
+// The cache must not be locked.
+func (c *Cache[K, V]) loadBatch(ctx context.Context, loader BatchLoader[K, V], keys []K) (map[K]*Item[K, V], error) {
+	var (
+		own     []K
+		started []*batchCall[K, V]
+		calls   = make(map[*batchCall[K, V]]struct{})
+	)
 
-// Delete deletes an item from the cache. If the item associated with
-// the key is not found, the method is no-op.
-func (c *Cache[K, V]) Delete(key K) {
-	c.items.mu.Lock()
-	defer c.items.mu.Unlock()
+	c.batches.mu.Lock()
 
-	c.delete(key)
-}
+	for _, key := range keys {
+		if call := c.batches.calls[key]; call != nil {
+			calls[call] = struct{}{}
+			continue
+		}
 
-// delete is used for deleting an item without locks.
-func (c *Cache[K, V]) delete(key K) {
-	elem := c.items.values[key]
-	if elem == nil {
-		return
+		own = append(own, key)
 	}
 
-	c.evict(EvictionReasonDeleted, elem)
-}
+	size := c.options.maxBatchSize
+	if size <= 0 {
+		size = len(own)
+	}
 
-// Has checks whether the key exists in the cache.
-func (c *Cache[K, V]) Has(key K) bool {
-	c.items.mu.RLock()
-	defer c.items.mu.RUnlock()
+	for start := 0; start < len(own); start += size {
+		end := start + size
+		if end > len(own) {
+			end = len(own)
+		}
 
-	_, ok := c.items.values[key]
-	return ok
-}
+		call := &batchCall[K, V]{
+			keys: own[start:end],
+			done: make(chan struct{}),
+		}
 
-// GetOrSet returns the existing value for the key if present.
-// Otherwise, it sets and returns the given value. The retrieved
-// result is true if the value was retrieved, false if set.
-// If a loader is defined, the value won't be loaded during get.
+		for _, key := range call.keys {
+			c.batches.calls[key] = call
+		}
+
+		calls[call] = struct{}{}
+		started = append(started, call)
+	}
+
+	c.batches.mu.Unlock()
+
+	// the batches are shared with other callers, so they must not
+	// fail when this caller's context is cancelled
+	for _, call := range started {
+		go c.runBatch(detachedContext{parent: ctx}, loader, call)
+	}
+
+	var (
+		res = make(map[K]*Item[K, V], len(keys))
+		err error
+	)
+
+	for call := range calls {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+
+		if call.err != nil && err == nil {
+			err = call.err
+		}
+
+		for _, key := range keys {
+			if item := call.items[key]; item != nil {
+				res[key] = item
+			}
+		}
+	}
+
+	return res, err
+}
+
+// runBatch executes a single call of the batch loader and stores the
+// loaded items in the cache. Keys that are not found by the loader are
+// marked as absent when negative caching is enabled. If an item is
+// deleted while the call is in-flight, its loaded data is returned
+// without being cached, since it may be outdated.
+// The cache must not be locked.
+func (c *Cache[K, V]) runBatch(ctx context.Context, loader BatchLoader[K, V], call *batchCall[K, V]) {
+	defer func() {
+		c.batches.mu.Lock()
+		for _, key := range call.keys {
+			delete(c.batches.calls, key)
+		}
+		c.batches.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	deletions := make([]uint64, len(call.keys))
+
+	c.items.mu.Lock()
+	for i, key := range call.keys {
+		deletions[i] = c.startLoad(key)
+	}
+	c.items.mu.Unlock()
+
+	values, err := loader.LoadBatch(ctx, c, call.keys)
+
+	var absent []K
+
+	c.items.mu.Lock()
+
+	if err == nil {
+		call.items = make(map[K]*Item[K, V], len(values))
+	}
+
+	for i, key := range call.keys {
+		deleted := c.finishLoad(key, deletions[i])
+		if err != nil {
+			continue
+		}
+
+		value, ok := values[key]
+
+		switch {
+		case deleted:
+			// the item was deleted while loading, so the loaded
+			// data must not be cached
+			if ok {
+				call.items[key] = newItem(key, value, c.options.ttl, c.options.enableVersionTrack)
+			}
+		case !ok:
+			absent = append(absent, key)
+		default:
+			if item := c.set(key, value, DefaultTTL); item != nil {
+				call.items[key] = item
+			}
+		}
+	}
+
+	c.items.mu.Unlock()
+
+	if err != nil {
+		c.metricsMu.Lock()
+		c.metrics.LoadErrors++
+		c.metricsMu.Unlock()
+
+		call.err = err
+
+		return
+	}
+
+	for _, key := range absent {
+		c.markAbsent(key)
+	}
+}
+
+// isKnownAbsent checks whether the key is marked as absent.
+// The cache must not be locked.
+func (c *Cache[K, V]) isKnownAbsent(key K) bool {
+	if c.items.absent == nil {
+		return false
+	}
+
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	return c.items.absent.has(key)
+}
+
+// markAbsent marks the key as absent, unless negative caching is
+// disabled or the item was added in the meantime.
+// The cache must not be locked.
+func (c *Cache[K, V]) markAbsent(key K) {
+	if c.items.absent == nil {
+		return
+	}
+
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	if c.items.values[key] != nil {
+		return
+	}
+
+	c.items.absent.add(key)
+
+	c.metricsMu.Lock()
+	c.metrics.NegativeInsertions++
+	c.metricsMu.Unlock()
+}
+
+// refresh reloads the item in the background using the provided
+// loader, unless the item is already being reloaded. If the reload
+// fails, the existing item is kept, its next refresh is postponed
+// and the failure is reported to the refresh failure subscribers.
+// The cache must not be locked.
+func (c *Cache[K, V]) refresh(loader Loader[K, V], item *Item[K, V]) {
+	key := item.Key()
+
+	c.refreshes.mu.Lock()
+	if _, ok := c.refreshes.keys[key]; ok {
+		c.refreshes.mu.Unlock()
+		return
+	}
+	c.refreshes.keys[key] = struct{}{}
+	c.refreshes.mu.Unlock()
+
+	c.metricsMu.Lock()
+	c.metrics.Refreshes++
+	c.metricsMu.Unlock()
+
+	go func() {
+		_, err := c.load(context.Background(), loader, key)
+
+		c.refreshes.mu.Lock()
+		delete(c.refreshes.keys, key)
+		c.refreshes.mu.Unlock()
+
+		if err == nil {
+			return
+		}
+
+		item.refreshFailed()
+
+		c.metricsMu.Lock()
+		c.metrics.RefreshErrors++
+		c.metricsMu.Unlock()
+
+		c.events.refreshFailure.mu.RLock()
+		for _, fn := range c.events.refreshFailure.fns {
+			fn(key, err)
+		}
+		c.events.refreshFailure.mu.RUnlock()
+	}()
+}
+
+// load retrieves the item associated with the key using the provided
+// loader. Failed loads are tracked by the metrics.
+// The cache must not be locked.
+func (c *Cache[K, V]) load(ctx context.Context, loader Loader[K, V], key K) (*Item[K, V], error) {
+	item, err := loadContext(ctx, loader, c, key)
+	if err != nil {
+		c.metricsMu.Lock()
+		c.metrics.LoadErrors++
+		c.metricsMu.Unlock()
+	}
+
+	return item, err
+}
+
+// keyLoads holds the state of the in-flight loads of a single key.
+type keyLoads struct {
+	// count is the number of the in-flight loads.
+	count int
+
+	// deletions is incremented on each deletion of the key's item.
+	deletions uint64
+}
+
+// startLoad registers a new load of the provided key and returns the
+// number of deletions of the key's item, which must be passed to
+// finishLoad once the load completes.
+// Not concurrently safe.
+func (c *Cache[K, V]) startLoad(key K) uint64 {
+	if c.items.loads == nil {
+		c.items.loads = make(map[K]*keyLoads)
+	}
+
+	loads := c.items.loads[key]
+	if loads == nil {
+		loads = &keyLoads{}
+		c.items.loads[key] = loads
+	}
+
+	loads.count++
+
+	return loads.deletions
+}
+
+// finishLoad unregisters a load of the provided key that was
+// registered by startLoad and reports whether the key's item was
+// deleted since then.
+// Not concurrently safe.
+func (c *Cache[K, V]) finishLoad(key K, deletions uint64) bool {
+	loads := c.items.loads[key]
+
+	loads.count--
+	if loads.count == 0 {
+		delete(c.items.loads, key)
+	}
+
+	return loads.deletions != deletions
+}
+
+// trackDeletions records the deletions of the provided items, whose
+// keys may be loaded at the same time. If no items are provided, the
+// deletions of all items are recorded.
+// Not concurrently safe.
+func (c *Cache[K, V]) trackDeletions(items ...*Item[K, V]) {
+	if len(c.items.loads) == 0 {
+		return
+	}
+
+	if len(items) == 0 {
+		for _, loads := range c.items.loads {
+			loads.deletions++
+		}
+
+		return
+	}
+
+	for _, item := range items {
+		if loads := c.items.loads[item.key]; loads != nil {
+			loads.deletions++
+		}
+	}
+}
+
+// evict deletes items from the cache.
+// If no items are provided, all currently present cache items
+// are evicted.
+// Not concurrently safe.
+func (c *Cache[K, V]) evict(reason EvictionReason, items ...*Item[K, V]) {
+	if reason == EvictionReasonDeleted {
+		c.trackDeletions(items...)
+	}
+
+	if len(items) > 0 {
+		c.metricsMu.Lock()
+		c.metrics.Evictions += uint64(len(items))
+		c.metricsMu.Unlock()
+
+		c.events.eviction.mu.RLock()
+		for _, item := range items {
+			delete(c.items.values, item.key)
+			c.items.cost -= item.cost
+			c.items.policy.OnRemove(item, reason)
+			if c.items.wheel != nil {
+				c.items.wheel.remove(item)
+			} else {
+				c.items.expQueue.remove(item)
+			}
+
+			for _, fn := range c.events.eviction.fns {
+				fn(reason, item)
+			}
+
+			c.markDeleted(item.key)
+			c.logWAL(walOpDelete, item)
+		}
+		c.events.eviction.mu.RUnlock()
+
+		return
+	}
+
+	c.metricsMu.Lock()
+	c.metrics.Evictions += uint64(len(c.items.values))
+	c.metricsMu.Unlock()
+
+	c.events.eviction.mu.RLock()
+	for _, item := range c.items.values {
+		c.items.policy.OnRemove(item, reason)
+
+		for _, fn := range c.events.eviction.fns {
+			fn(reason, item)
+		}
+	}
+	c.events.eviction.mu.RUnlock()
+
+	c.items.values = make(map[K]*Item[K, V])
+	c.items.cost = 0
+	c.items.expQueue = newExpirationQueue[K, V]()
+
+	if c.items.absent != nil {
+		c.items.absent.reset()
+	}
+
+	if c.items.wheel != nil {
+		c.items.wheel = newTimingWheel[K, V](c.items.wheel.tick)
+	}
+
+	// the deletions of all items are not tracked, so deltas
+	// cannot be written since older tokens
+	c.items.deltaSeq = 0
+	if c.items.deleted != nil {
+		c.items.deleted = make(map[K]struct{})
+	}
+
+	c.logWAL(walOpClear, nil)
+}
+
+// Set creates a new item from the provided key and value, adds
+// it to the cache and then returns it. If an item associated with the
+// provided key already exists, the new item overwrites the existing one.
+// If the maximum cost of the cache is set and the item's cost exceeds
+// it, the item is not stored, an existing item associated with the key
+// is deleted and nil is returned.
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) *Item[K, V] {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	return c.set(key, value, ttl)
+}
+
+// SetWithCost creates a new item from the provided key, value and
+// cost, adds it to the cache and then returns it. The provided cost
+// is used instead of the one calculated by the cache's cost function.
+// If an item associated with the provided key already exists, the new
+// item overwrites the existing one.
+// If the cost exceeds the maximum cost of the cache, the item is not
+// stored, an existing item associated with the key is deleted and
+// nil is returned.
+func (c *Cache[K, V]) SetWithCost(key K, value V, ttl time.Duration, cost uint64) *Item[K, V] {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	return c.setWithCost(key, value, ttl, cost)
+}
+
+// Get retrieves an item from the cache by the provided key.
+// Unless this is disabled, it also extends/touches an item's
+// expiration timestamp on successful retrieval.
+// If the item is not found, a nil value is returned.
+// When buffered access recording is enabled (see WithAccessBuffer),
+// the retrieval is applied to the eviction policy and the item's
+// expiration timestamp eventually rather than immediately.
+// When a grace period is set (see WithGracePeriod), an item that
+// expired within the grace period is returned as well. Such stale
+// item reports that it is expired and its expiration timestamp is not
+// extended; instead, the item is reloaded in the background if a
+// loader is set.
+func (c *Cache[K, V]) Get(key K, opts ...Option[K, V]) *Item[K, V] {
+	item, _ := c.getWithOpts(context.Background(), key, true, opts...)
+	return item
+}
+
+// GetE retrieves an item from the cache by the provided key, just like
+// Get does, but it also returns the error that occurred while loading
+// a missing item.
+// A nil item and a nil error are returned if the item is not found
+// and either no loader is set or the loader did not find it either.
+// Errors can only be returned by loaders that implement the
+// LoaderWithError interface.
+// When negative caching is enabled (see WithNegativeTTL),
+// ErrKnownAbsent is returned for keys that the loader recently did
+// not find.
+func (c *Cache[K, V]) GetE(key K, opts ...Option[K, V]) (*Item[K, V], error) {
+	return c.getWithOpts(context.Background(), key, true, opts...)
+}
+
+// GetContext retrieves an item from the cache by the provided key, just
+// like GetE does, but it also passes the provided context to the
+// loader, so that it is able to observe the context's deadline and
+// cancellation or use its values.
+// The context is passed only to loaders that implement the
+// ContextLoader interface.
+func (c *Cache[K, V]) GetContext(ctx context.Context, key K, opts ...Option[K, V]) (*Item[K, V], error) {
+	return c.getWithOpts(ctx, key, true, opts...)
+}
+
+// GetMany retrieves multiple items from the cache by the provided keys
+// and returns the found ones mapped by their keys. Each of the items
+// is retrieved just like Get does.
+// If a batch loader is set (see WithBatchLoader), all items that are
+// not found are loaded with a single call of its LoadBatch method
+// (or a few calls, if the maximum batch size is set), which is shared
+// with other concurrent callers that request the same keys.
+// Otherwise, the missing items are loaded one by one with the regular
+// loader.
+func (c *Cache[K, V]) GetMany(keys []K, opts ...Option[K, V]) map[K]*Item[K, V] {
+	items, _ := c.getMany(context.Background(), keys, opts...)
+	return items
+}
+
+// GetManyContext retrieves multiple items from the cache by the
+// provided keys, just like GetMany does, but it also passes the
+// provided context to the loaders and returns the first error that
+// occurred while loading the missing items. The items that were found
+// or loaded successfully are returned even if an error occurs.
+// If the context is done before all calls of the batch loader
+// complete, the method returns the context's error along with the
+// items that were found or loaded so far. Since the calls of the
+// batch loader are shared with other callers, they receive the
+// context's values, but are not cancelled along with it.
+func (c *Cache[K, V]) GetManyContext(ctx context.Context, keys []K, opts ...Option[K, V]) (map[K]*Item[K, V], error) {
+	return c.getMany(ctx, keys, opts...)
+}
+
+// Delete deletes an item from the cache. If the item associated with
+// the key is not found, the method is no-op.
+func (c *Cache[K, V]) Delete(key K) {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	c.delete(key)
+}
+
+// delete is used for deleting an item without locks.
+func (c *Cache[K, V]) delete(key K) {
+	item := c.items.values[key]
+	if item == nil {
+		return
+	}
+
+	c.evict(EvictionReasonDeleted, item)
+}
+
+// Has checks whether the key exists in the cache.
+func (c *Cache[K, V]) Has(key K) bool {
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	_, ok := c.items.values[key]
+	return ok
+}
+
+// GetOrSet returns the existing value for the key if present.
+// Otherwise, it sets and returns the given value. The retrieved
+// result is true if the value was retrieved, false if set.
+// If a loader is defined, the value won't be loaded during get.
 func (c *Cache[K, V]) GetOrSet(key K, value V, opts ...Option[K, V]) (*Item[K, V], bool) {
 	c.items.mu.Lock()
 
-	elem := c.getWithOpts(key, false, opts...)
-	if elem != nil {
+	elem, _ := c.getWithOpts(context.Background(), key, false, opts...)
+	if elem != nil && !elem.isExpiredUnsafe() {
 		c.items.mu.Unlock()
 		return elem, true
 	}
@@ -353,7 +1272,7 @@ func (c *Cache[K, V]) GetOrSet(key K, value V, opts ...Option[K, V]) (*Item[K, V
 func (c *Cache[K, V]) GetAndDelete(key K, opts ...Option[K, V]) (*Item[K, V], bool) {
 	c.items.mu.Lock()
 
-	elem := c.getWithOpts(key, false, opts...)
+	elem, _ := c.getWithOpts(context.Background(), key, false, opts...)
 	if elem == nil {
 		c.items.mu.Unlock()
 
@@ -363,7 +1282,7 @@ func (c *Cache[K, V]) GetAndDelete(key K, opts ...Option[K, V]) (*Item[K, V], bo
 		applyOptions(&getOpts, opts...)
 
 		if getOpts.loader != nil {
-			item := getOpts.loader.Load(c, key)
+			item, _ := c.load(context.Background(), getOpts.loader, key)
 			return item, item != nil
 		}
 		return nil, false
@@ -386,12 +1305,28 @@ func (c *Cache[K, V]) DeleteExpired() {
 	c.items.mu.Lock()
 	defer c.items.mu.Unlock()
 
+	// recently retrieved items may need to be touched first
+	c.drainAccesses()
+
+	if c.items.absent != nil {
+		c.items.absent.deleteExpired()
+	}
+
+	if c.items.wheel != nil {
+		// items are retained during the grace period
+		if expired := c.items.wheel.advance(time.Now().Add(-c.options.gracePeriod)); len(expired) > 0 {
+			c.evict(EvictionReasonExpired, expired...)
+		}
+
+		return
+	}
+
 	if c.items.expQueue.isEmpty() {
 		return
 	}
 
 	e := c.items.expQueue[0]
-	for e.Value.(*Item[K, V]).isExpiredUnsafe() {
+	for !c.isUsable(e) {
 		c.evict(EvictionReasonExpired, e)
 
 		if c.items.expQueue.isEmpty() {
@@ -420,6 +1355,14 @@ func (c *Cache[K, V]) Len() int {
 	return len(c.items.values)
 }
 
+// Cost returns the total cost of all items in the cache.
+func (c *Cache[K, V]) Cost() uint64 {
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	return c.items.cost
+}
+
 // Keys returns all keys currently present in the cache.
 func (c *Cache[K, V]) Keys() []K {
 	c.items.mu.RLock()
@@ -439,11 +1382,12 @@ func (c *Cache[K, V]) Items() map[K]*Item[K, V] {
 	c.items.mu.RLock()
 	defer c.items.mu.RUnlock()
 
+	// accesses must not be recorded, since the eviction policy may
+	// only be modified while the items are write-locked
 	items := make(map[K]*Item[K, V], len(c.items.values))
-	for k := range c.items.values {
-		item := c.get(k, false)
-		if item != nil {
-			items[k] = item.Value.(*Item[K, V])
+	for k, item := range c.items.values {
+		if c.isUsable(item) {
+			items[k] = item
 		}
 	}
 
@@ -453,9 +1397,14 @@ func (c *Cache[K, V]) Items() map[K]*Item[K, V] {
 // Metrics returns the metrics of the cache.
 func (c *Cache[K, V]) Metrics() Metrics {
 	c.metricsMu.RLock()
-	defer c.metricsMu.RUnlock()
+	m := c.metrics
+	c.metricsMu.RUnlock()
 
-	return c.metrics
+	if c.options.trackMemory {
+		m.EstimatedBytes = c.Cost()
+	}
+
+	return m
 }
 
 // Start starts an automatic cleanup process that
@@ -466,9 +1415,14 @@ func (c *Cache[K, V]) Start() {
 		c.items.mu.RLock()
 		defer c.items.mu.RUnlock()
 
+		if c.items.wheel != nil && !c.items.wheel.isEmpty() {
+			// expired items are deleted in batches on each tick
+			return c.items.wheel.tick
+		}
+
 		if !c.items.expQueue.isEmpty() &&
-			!c.items.expQueue[0].Value.(*Item[K, V]).expiresAt.IsZero() {
-			d := time.Until(c.items.expQueue[0].Value.(*Item[K, V]).expiresAt)
+			!c.items.expQueue[0].expiresAt.IsZero() {
+			d := time.Until(c.items.expQueue[0].expiresAt.Add(c.options.gracePeriod))
 			if d <= 0 {
 				// execute immediately
 				return time.Microsecond
@@ -594,21 +1548,490 @@ func (c *Cache[K, V]) OnEviction(fn func(context.Context, EvictionReason, *Item[
 	}
 }
 
+// OnRefreshFailure adds the provided function to be executed when
+// a background reload of an item (see WithRefreshAfter) fails. The
+// function receives the item's key and the error returned by the
+// loader. It is executed on a separate goroutine and does not block
+// the flow of the cache manager.
+// The returned function may be called to delete the subscription function
+// from the list of refresh failure subscribers.
+// When the returned function is called, it blocks until all instances of
+// the same subscription function return. A context is used to notify the
+// subscription function when the returned/deletion function is called.
+func (c *Cache[K, V]) OnRefreshFailure(fn func(context.Context, K, error)) func() {
+	var (
+		wg          sync.WaitGroup
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	c.events.refreshFailure.mu.Lock()
+	id := c.events.refreshFailure.nextID
+	c.events.refreshFailure.fns[id] = func(key K, err error) {
+		wg.Add(1)
+		go func() {
+			fn(ctx, key, err)
+			wg.Done()
+		}()
+	}
+	c.events.refreshFailure.nextID++
+	c.events.refreshFailure.mu.Unlock()
+
+	return func() {
+		cancel()
+
+		c.events.refreshFailure.mu.Lock()
+		delete(c.events.refreshFailure.fns, id)
+		c.events.refreshFailure.mu.Unlock()
+
+		wg.Wait()
+	}
+}
+
 // Range iterate over all items and calls fn function. It calls fn function
 // until it returns false.
+// Items are visited in the order defined by the eviction policy
+// (from the most to the least recently used one for the default LRU
+// policy) or in an unspecified order if the policy does not define one.
 func (c *Cache[K, V]) Range(fn func(item *Item[K, V]) bool) {
 	c.items.mu.RLock()
-	for item := c.items.lru.Front(); item != c.items.lru.Back().Next(); item = item.Next() {
-		i := item.Value.(*Item[K, V])
-		c.items.mu.RUnlock()
-		if !fn(i) {
+	items := make([]*Item[K, V], 0, len(c.items.values))
+	if p, ok := c.items.policy.(orderedPolicy[K, V]); ok {
+		p.each(func(item *Item[K, V]) bool {
+			items = append(items, item)
+			return true
+		})
+	} else {
+		for _, item := range c.items.values {
+			items = append(items, item)
+		}
+	}
+	c.items.mu.RUnlock()
+
+	for _, item := range items {
+		if !fn(item) {
 			return
 		}
+	}
+}
+
+// Save writes all items that are not expired to the provided writer,
+// so that they can be restored later with Load. The items are encoded
+// with the snapshot codec of the cache (see WithSnapshotCodec) or its
+// key and value codecs (see WithKeyCodec), and with the encoding/gob
+// package by default.
+// It does not update any expiration timestamps.
+func (c *Cache[K, V]) Save(w io.Writer, opts ...Option[K, V]) error {
+	saveOpts := options[K, V]{
+		snapshotCodec: c.options.snapshotCodec,
+		keyCodec:      c.options.keyCodec,
+		valueCodec:    c.options.valueCodec,
+	}
+
+	applyOptions(&saveOpts, opts...)
+
+	return encodeSnapshot(w, saveOpts.snapshotCodecOrDefault(), c.lockedSnapshot())
+}
+
+// SaveSince writes the items that were changed, touched or deleted
+// since the provided token was returned to the provided writer and
+// returns the token of the written delta snapshot, which should be
+// passed into the next call. The zero token writes all items that are
+// not expired, just like Save does.
+// An item is written if its version (see WithVersion) or expiration
+// timestamp differs from the one that was written by the previous
+// call. A base snapshot and the deltas that were written since it can
+// be restored with LoadChain. Version tracking must be enabled;
+// otherwise, only the zero token can be used.
+// Only the token that was returned by the latest successful call can
+// be used, since the items are marked as saved when a snapshot is
+// written. ErrDeltaUnavailable is returned for any other token, or if
+// the deletions of some items were not tracked (e.g. because
+// DeleteAll was called), in which case a full snapshot should be
+// saved with the zero token instead. If the snapshot cannot be
+// written, the provided token remains valid.
+// It does not update any expiration timestamps.
+func (c *Cache[K, V]) SaveSince(w io.Writer, token SnapshotToken, opts ...Option[K, V]) (SnapshotToken, error) {
+	saveOpts := options[K, V]{
+		snapshotCodec: c.options.snapshotCodec,
+		keyCodec:      c.options.keyCodec,
+		valueCodec:    c.options.valueCodec,
+	}
+
+	applyOptions(&saveOpts, opts...)
+
+	c.deltaMu.Lock()
+	defer c.deltaMu.Unlock()
+
+	if token != (SnapshotToken{}) && (token.Epoch != c.epoch || token.Seq != c.lockedDeltaSeq()) {
+		return token, ErrDeltaUnavailable
+	}
+
+	d, ok := c.delta(token == SnapshotToken{})
+	if !ok {
+		return token, ErrDeltaUnavailable
+	}
+
+	if err := encodeSnapshot(w, saveOpts.snapshotCodecOrDefault(), d.items); err != nil {
+		c.abortDelta(d)
+		return token, err
+	}
+
+	return SnapshotToken{Epoch: c.epoch, Seq: c.commitDelta(d)}, nil
+}
+
+// SnapshotToken identifies the state of a cache at the time a
+// snapshot was written by SaveSince.
+type SnapshotToken struct {
+	// Epoch identifies the cache instance that returned the token.
+	Epoch uint64
+
+	// Seq is the sequence number of the snapshot.
+	Seq uint64
+}
+
+// deltaSnapshot holds the snapshot items of a delta snapshot that is
+// being written, along with the state that is needed to either mark
+// its items as saved or to track its deletions again.
+type deltaSnapshot[K comparable, V any] struct {
+	items   []SnapshotItem[K, V]
+	saved   []savedItem[K, V]
+	deleted map[K]struct{}
+}
+
+// savedItem holds the version and the expiration timestamp of an
+// item at the time it was added to a delta snapshot.
+type savedItem[K comparable, V any] struct {
+	item      *Item[K, V]
+	version   int64
+	expiresAt time.Time
+}
+
+// lockedDeltaSeq returns the sequence number of the latest snapshot
+// that delta snapshots can be written since, or 0 if deltas are
+// unavailable.
+func (c *Cache[K, V]) lockedDeltaSeq() uint64 {
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	return c.items.deltaSeq
+}
+
+// delta returns the snapshot items of the items that were changed,
+// touched or deleted since the latest snapshot that was written by
+// SaveSince, or of all items that are not expired if the full flag is
+// set. It returns false if the delta is unavailable.
+// The deletions are no longer tracked until abortDelta is called, so
+// either commitDelta or abortDelta must be called once the snapshot
+// items are written.
+func (c *Cache[K, V]) delta(full bool) (*deltaSnapshot[K, V], bool) {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	if !full && (!c.options.enableVersionTrack || c.items.deltaSeq == 0) {
+		return nil, false
+	}
+
+	d := &deltaSnapshot[K, V]{deleted: c.items.deleted}
+	if c.options.enableVersionTrack {
+		c.items.deleted = make(map[K]struct{})
+	}
+
+	if !full {
+		for key := range d.deleted {
+			d.items = append(d.items, SnapshotItem[K, V]{Key: key, Deleted: true})
+		}
+	}
+
+	d.items = append(d.items, c.snapshot(func(item *Item[K, V]) bool {
+		if !full && !item.changedSinceSave() {
+			return false
+		}
+
+		if c.options.enableVersionTrack {
+			d.saved = append(d.saved, savedItem[K, V]{
+				item:      item,
+				version:   item.version,
+				expiresAt: item.expiresAt,
+			})
+		}
+
+		return true
+	})...)
+
+	return d, true
+}
+
+// commitDelta marks the items of the provided delta snapshot as saved
+// and returns the sequence number of the snapshot.
+func (c *Cache[K, V]) commitDelta(d *deltaSnapshot[K, V]) uint64 {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	for _, s := range d.saved {
+		s.item.saved = true
+		s.item.savedVersion = s.version
+		s.item.savedExpiresAt = s.expiresAt
+	}
+
+	c.items.snapshotSeq++
+	if c.options.enableVersionTrack {
+		c.items.deltaSeq = c.items.snapshotSeq
+	}
+
+	return c.items.snapshotSeq
+}
+
+// abortDelta tracks the deletions of the provided delta snapshot
+// again, so that they are written by the next SaveSince call.
+func (c *Cache[K, V]) abortDelta(d *deltaSnapshot[K, V]) {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	if c.items.deleted == nil {
+		return
+	}
+
+	for key := range d.deleted {
+		if _, ok := c.items.values[key]; !ok {
+			c.items.deleted[key] = struct{}{}
+		}
+	}
+}
+
+// Load reads the items that were written by Save or SaveSince from
+// the provided reader and adds them to the cache. Items that have
+// expired since they were saved are skipped, while the rest retain
+// their TTLs, expiration timestamps and versions, as well as their
+// order in the eviction policy (e.g. their recency when LRU is used).
+// Their costs are recalculated if a cost function is set (see
+// WithCostFunc).
+// The items are added just like Set adds them, so they overwrite
+// the existing items with the same keys, may evict other items when
+// the capacity is reached and trigger insertion events (unless this
+// is disabled, see WithDisableLoadEvents). The existing items whose
+// keys belong to deleted or expired items are deleted.
+// If the items cannot be read, none of them are added.
+func (c *Cache[K, V]) Load(r io.Reader, opts ...Option[K, V]) error {
+	return c.LoadChain([]io.Reader{r}, opts...)
+}
+
+// LoadChain reads the items that were written by Save or SaveSince
+// from the provided readers and adds them to the cache, just like Load
+// does. The readers are applied in order, so a base snapshot followed
+// by the deltas that were written since it restores the state of the
+// cache at the time the last delta was written.
+// If the items of any of the readers cannot be read, none of them are
+// added.
+func (c *Cache[K, V]) LoadChain(rs []io.Reader, opts ...Option[K, V]) error {
+	loadOpts := options[K, V]{
+		snapshotCodec:     c.options.snapshotCodec,
+		keyCodec:          c.options.keyCodec,
+		valueCodec:        c.options.valueCodec,
+		disableLoadEvents: c.options.disableLoadEvents,
+	}
 
-		if item.Next() != nil {
-			c.items.mu.RLock()
+	applyOptions(&loadOpts, opts...)
+
+	state := newSnapshotState[K, V]()
+	for _, r := range rs {
+		if err := decodeSnapshot(r, loadOpts.snapshotCodecOrDefault(), state); err != nil {
+			return err
+		}
+	}
+
+	c.restore(state.deletedKeys(), state.items(), !loadOpts.disableLoadEvents)
+
+	return nil
+}
+
+// snapshot returns the snapshot items of all items that are not
+// expired and are accepted by the provided filter (or of all such
+// items, if it is nil), in the reverse order of the eviction policy
+// (e.g. from the least recently used item to the most recently used
+// one), so that restoring them one by one recreates that order.
+// Not concurrently safe.
+func (c *Cache[K, V]) snapshot(filter func(*Item[K, V]) bool) []SnapshotItem[K, V] {
+	items := make([]SnapshotItem[K, V], 0, len(c.items.values))
+	add := func(item *Item[K, V]) bool {
+		if !item.isExpiredUnsafe() && (filter == nil || filter(item)) {
+			items = append(items, newSnapshotItem(item))
+		}
+
+		return true
+	}
+
+	if p, ok := c.items.policy.(orderedPolicy[K, V]); ok {
+		p.each(add)
+	} else {
+		for _, item := range c.items.values {
+			add(item)
 		}
 	}
+
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	return items
+}
+
+// lockedSnapshot wraps the snapshot method with the read-locking of
+// the cache's items.
+func (c *Cache[K, V]) lockedSnapshot() []SnapshotItem[K, V] {
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	return c.snapshot(nil)
+}
+
+// restore deletes the items with the provided keys and then adds the
+// provided snapshot items to the cache in the order in which they are
+// provided. The insertion events are fired only if the notify flag is
+// set.
+func (c *Cache[K, V]) restore(deleted []K, items []SnapshotItem[K, V], notify bool) {
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	for _, key := range deleted {
+		c.delete(key)
+	}
+
+	for _, si := range items {
+		ttl := si.TTL
+		if ttl == DefaultTTL {
+			// the item did not expire, so the cache's default TTL
+			// must not be applied
+			ttl = NoTTL
+		}
+
+		// the stored cost is not trusted, since it may have been
+		// calculated differently (or not at all) by the cache that
+		// wrote it
+		cost := si.Cost
+		if c.options.costFunc != nil {
+			cost = c.options.costFunc(si.Key, si.Value)
+		}
+
+		item := c.setItem(si.Key, si.Value, ttl, cost, notify)
+		if item == nil {
+			continue
+		}
+
+		item.mu.Lock()
+		if ttl > 0 {
+			item.expiresAt = si.ExpiresAt
+		}
+
+		if item.enableVersionTrack {
+			item.version = si.Version
+		}
+		item.mu.Unlock()
+
+		c.updateExpirations(false, item)
+		c.logWAL(walOpSet, item)
+	}
+}
+
+// logWAL appends a record of the provided operation on the provided
+// item to the write-ahead log, if the cache has one, and starts the
+// compaction of the log in the background when it grows past its
+// threshold.
+// Not concurrently safe.
+func (c *Cache[K, V]) logWAL(op walOp, item *Item[K, V]) {
+	w := c.items.wal
+	if w == nil || !w.append(op, item) {
+		return
+	}
+
+	// the log is already being compacted
+	if !w.compactMu.TryLock() {
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.compactMu.Unlock()
+
+		err := c.compactWAL(w)
+
+		w.mu.Lock()
+		w.compactErr = err
+		w.mu.Unlock()
+	}()
+}
+
+// compactWAL writes a snapshot of all items to the provided
+// write-ahead log, which replaces all of its existing segments.
+// The compaction mutex of the log must be held.
+func (c *Cache[K, V]) compactWAL(w *wal[K, V]) error {
+	c.items.mu.RLock()
+	items := c.snapshot(nil)
+	seq, err := w.rotate()
+	c.items.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return w.writeSnapshot(seq, items)
+}
+
+// Sync writes all changes that were appended to the write-ahead log
+// of the cache to the disk, regardless of its sync policy.
+// It returns the first error that occurred while writing the log, or
+// ErrWALClosed if the cache was not created with Open or was closed.
+func (c *Cache[K, V]) Sync() error {
+	c.items.mu.RLock()
+	w := c.items.wal
+	c.items.mu.RUnlock()
+
+	if w == nil {
+		return ErrWALClosed
+	}
+
+	return w.sync()
+}
+
+// Compact writes a fresh snapshot of all items that are not expired
+// to the directory of the write-ahead log of the cache and removes
+// the log segments that it replaces. It blocks until the snapshot is
+// written.
+// It returns ErrWALClosed if the cache was not created with Open or
+// was closed.
+func (c *Cache[K, V]) Compact() error {
+	c.items.mu.RLock()
+	w := c.items.wal
+	c.items.mu.RUnlock()
+
+	if w == nil {
+		return ErrWALClosed
+	}
+
+	w.compactMu.Lock()
+	defer w.compactMu.Unlock()
+
+	return c.compactWAL(w)
+}
+
+// Close waits for the background compaction of the write-ahead log of
+// the cache to finish, syncs the log and closes it. It returns the
+// first error that occurred while writing the log or compacting it
+// in the background.
+// The cache remains usable, but its changes are no longer logged.
+// If the cache was not created with Open or was already closed, the
+// method is no-op.
+func (c *Cache[K, V]) Close() error {
+	c.items.mu.Lock()
+	w := c.items.wal
+	c.items.wal = nil
+	c.items.mu.Unlock()
+
+	if w == nil {
+		return nil
+	}
+
+	return w.close()
 }
 
 // Loader is an interface that handles missing data loading.
@@ -621,6 +2044,202 @@ type Loader[K comparable, V any] interface {
 	Load(c *Cache[K, V], key K) *Item[K, V]
 }
 
+// LoaderWithError is a Loader that is able to report the errors that
+// occur while loading missing data, so that they can be distinguished
+// from missing items.
+// When a loader implements this interface, the cache calls its
+// LoadWithError method instead of Load.
+type LoaderWithError[K comparable, V any] interface {
+	Loader[K, V]
+
+	// LoadWithError should execute a custom item retrieval logic and
+	// return the item that is associated with the key.
+	// It should return a nil item and a nil error if the item is not
+	// found/valid, and a non-nil error if the item could not be
+	// retrieved.
+	// The method is allowed to fetch data from the cache instance
+	// or update it for future use.
+	LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error)
+}
+
+// loadWithError retrieves the item associated with the key using the
+// provided loader. The error is always nil if the loader does not
+// implement the LoaderWithError interface.
+func loadWithError[K comparable, V any](l Loader[K, V], c *Cache[K, V], key K) (*Item[K, V], error) {
+	if le, ok := l.(LoaderWithError[K, V]); ok {
+		return le.LoadWithError(c, key)
+	}
+
+	return l.Load(c, key), nil
+}
+
+// ContextLoader is a Loader that accepts the context of the
+// retrieval, so that it is able to observe the context's deadline and
+// cancellation or use its values (e.g. trace spans).
+// When a loader implements this interface, the cache calls its
+// LoadContext method instead of Load or LoadWithError.
+type ContextLoader[K comparable, V any] interface {
+	Loader[K, V]
+
+	// LoadContext should execute a custom item retrieval logic and
+	// return the item that is associated with the key.
+	// It should return a nil item and a nil error if the item is not
+	// found/valid, and a non-nil error if the item could not be
+	// retrieved (e.g. because the context is cancelled).
+	// The method is allowed to fetch data from the cache instance
+	// or update it for future use.
+	LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error)
+}
+
+// loadContext retrieves the item associated with the key using the
+// provided loader. The context is passed only to loaders that
+// implement the ContextLoader or ResultLoader interfaces.
+func loadContext[K comparable, V any](ctx context.Context, l Loader[K, V], c *Cache[K, V], key K) (*Item[K, V], error) {
+	if rl, ok := l.(ResultLoader[K, V]); ok {
+		return c.loadResult(ctx, rl, key)
+	}
+
+	if cl, ok := l.(ContextLoader[K, V]); ok {
+		return cl.LoadContext(ctx, c, key)
+	}
+
+	return loadWithError(l, c, key)
+}
+
+// LoadResult holds the data that is loaded by a ResultLoader along
+// with the instructions on how it should be stored in the cache.
+type LoadResult[V any] struct {
+	// Value is the loaded value.
+	Value V
+
+	// TTL is the TTL of the item (e.g. derived from the upstream
+	// Cache-Control header or the expiry of a token). If it is
+	// DefaultTTL, the cache's default TTL is used.
+	TTL time.Duration
+
+	// Cost is the cost of the item. If it is 0, the cost is
+	// calculated with the cache's cost function.
+	Cost uint64
+
+	// NoCache prevents the value from being stored in the cache.
+	// The loaded item is still returned to the caller.
+	NoCache bool
+}
+
+// ResultLoader is a Loader that returns the loaded data instead of
+// storing it in the cache itself. The cache stores the data
+// atomically after the load completes, unless the item was
+// overwritten or deleted in the meantime.
+// When a loader implements this interface, the cache calls its
+// LoadResult method instead of any other load method.
+type ResultLoader[K comparable, V any] interface {
+	Loader[K, V]
+
+	// LoadResult should execute a custom retrieval logic and return
+	// the data that is associated with the key.
+	// It should return a nil result and a nil error if the data is
+	// not found/valid, and a non-nil error if the data could not be
+	// retrieved.
+	// The method should not update the cache instance itself.
+	LoadResult(ctx context.Context, c *Cache[K, V], key K) (*LoadResult[V], error)
+}
+
+// loadResult retrieves the data associated with the key using the
+// provided loader and stores it in the cache. If the item was
+// overwritten while the data was being loaded, the loaded data is
+// discarded and the current item is returned instead. If the item was
+// deleted meanwhile, the loaded data is returned without being cached,
+// since it may be outdated.
+// The cache must not be locked.
+func (c *Cache[K, V]) loadResult(ctx context.Context, l ResultLoader[K, V], key K) (*Item[K, V], error) {
+	var prevUpdatedAt time.Time
+
+	c.items.mu.Lock()
+	prev := c.items.values[key]
+	if prev != nil {
+		prevUpdatedAt = prev.updatedAt
+	}
+	deletions := c.startLoad(key)
+	c.items.mu.Unlock()
+
+	res, err := l.LoadResult(ctx, c, key)
+
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	deleted := c.finishLoad(key, deletions)
+
+	if res == nil || err != nil {
+		return nil, err
+	}
+
+	ttl := res.TTL
+	if ttl == DefaultTTL {
+		ttl = c.options.ttl
+	}
+
+	if res.NoCache {
+		return newItem(key, res.Value, ttl, c.options.enableVersionTrack), nil
+	}
+
+	cur := c.items.values[key]
+	if cur != nil && (cur != prev || !cur.updatedAt.Equal(prevUpdatedAt)) {
+		// the item was overwritten while loading, so the loaded
+		// data is outdated
+		return cur, nil
+	}
+
+	if deleted {
+		// the item was deleted while loading, so the loaded data
+		// must not be cached
+		return newItem(key, res.Value, ttl, c.options.enableVersionTrack), nil
+	}
+
+	if res.Cost == 0 {
+		return c.set(key, res.Value, ttl), nil
+	}
+
+	return c.setWithCost(key, res.Value, ttl, res.Cost), nil
+}
+
+// ResultLoaderFunc type is an adapter that allows the use of ordinary
+// functions as data loaders that return the loaded data.
+type ResultLoaderFunc[K comparable, V any] func(context.Context, *Cache[K, V], K) (*LoadResult[V], error)
+
+// Load executes a custom retrieval logic with a background context,
+// stores the loaded data in the cache and returns the item that is
+// associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved.
+func (l ResultLoaderFunc[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := c.loadResult(context.Background(), l, key)
+	return item
+}
+
+// LoadWithError executes a custom retrieval logic with a background
+// context, stores the loaded data in the cache and returns the item
+// that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+func (l ResultLoaderFunc[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return c.loadResult(context.Background(), l, key)
+}
+
+// LoadContext executes a custom retrieval logic, stores the loaded
+// data in the cache and returns the item that is associated with the
+// key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+func (l ResultLoaderFunc[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	return c.loadResult(ctx, l, key)
+}
+
+// LoadResult executes a custom retrieval logic and returns the data
+// that is associated with the key.
+func (l ResultLoaderFunc[K, V]) LoadResult(ctx context.Context, c *Cache[K, V], key K) (*LoadResult[V], error) {
+	return l(ctx, c, key)
+}
+
 // LoaderFunc type is an adapter that allows the use of ordinary
 // functions as data loaders.
 type LoaderFunc[K comparable, V any] func(*Cache[K, V], K) *Item[K, V]
@@ -632,53 +2251,311 @@ func (l LoaderFunc[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
 	return l(c, key)
 }
 
+// LoaderWithErrorFunc type is an adapter that allows the use of
+// ordinary functions as data loaders that are able to report errors.
+type LoaderWithErrorFunc[K comparable, V any] func(*Cache[K, V], K) (*Item[K, V], error)
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved.
+func (l LoaderWithErrorFunc[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+func (l LoaderWithErrorFunc[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l(c, key)
+}
+
+// ContextLoaderFunc type is an adapter that allows the use of
+// ordinary functions as context-aware data loaders.
+type ContextLoaderFunc[K comparable, V any] func(context.Context, *Cache[K, V], K) (*Item[K, V], error)
+
+// Load executes a custom item retrieval logic with a background
+// context and returns the item that is associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved.
+func (l ContextLoaderFunc[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l(context.Background(), c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic with a
+// background context and returns the item that is associated with
+// the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+func (l ContextLoaderFunc[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+func (l ContextLoaderFunc[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l(ctx, c, key)
+}
+
+// BatchLoader is an interface that handles loading of multiple
+// missing items at once (e.g. with a single database query).
+type BatchLoader[K comparable, V any] interface {
+	// LoadBatch should execute a custom retrieval logic for all of
+	// the provided keys and return the found values mapped by their
+	// keys. Keys that are not found should be omitted, and a non-nil
+	// error should be returned if the values could not be retrieved.
+	// The returned values are added to the cache with the default
+	// TTL, so the method should not update the cache itself.
+	LoadBatch(ctx context.Context, c *Cache[K, V], keys []K) (map[K]V, error)
+}
+
+// BatchLoaderFunc type is an adapter that allows the use of ordinary
+// functions as batch data loaders.
+type BatchLoaderFunc[K comparable, V any] func(context.Context, *Cache[K, V], []K) (map[K]V, error)
+
+// LoadBatch executes a custom retrieval logic and returns the values
+// that are associated with the keys.
+func (l BatchLoaderFunc[K, V]) LoadBatch(ctx context.Context, c *Cache[K, V], keys []K) (map[K]V, error) {
+	return l(ctx, c, keys)
+}
+
+// batchCall holds the keys and the results of a single in-flight call
+// of a batch loader that is shared by multiple callers.
+type batchCall[K comparable, V any] struct {
+	keys  []K
+	done  chan struct{}
+	items map[K]*Item[K, V]
+	err   error
+}
+
 // SuppressedLoader wraps another Loader and suppresses duplicate
 // calls to its Load method.
 type SuppressedLoader[K comparable, V any] struct {
 	loader Loader[K, V]
-	group  *singleflight.Group
+	calls  *suppressedCalls[K, V]
+}
+
+// suppressedCalls holds the in-flight loads of one or more suppressed
+// loaders.
+type suppressedCalls[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*suppressedCall[K, V]
+}
+
+// suppressedCall holds the context and the result of a single
+// in-flight load that is shared by multiple callers.
+type suppressedCall[K comparable, V any] struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+
+	// done is closed when the load completes.
+	done     chan struct{}
+	item     *Item[K, V]
+	err      error
+	panicked bool
+	panicVal interface{}
 }
 
+// suppressedGroupKey identifies the in-flight loads that are shared by
+// the suppressed loaders created with the same group.
+type suppressedGroupKey struct {
+	group *singleflight.Group
+	calls reflect.Type
+}
+
+// suppressedGroups maps suppressedGroupKey values to the
+// *suppressedCalls values that are shared by the suppressed loaders
+// created with the same group.
+var suppressedGroups sync.Map
+
 // NewSuppressedLoader creates a new instance of suppressed loader.
-// If the group parameter is nil, a newly created instance of
-// *singleflight.Group is used.
+// Duplicate calls are detected by comparing the keys themselves.
+// Suppressed loaders that are created with the same non-nil group
+// share their in-flight loads, so that duplicate calls made through
+// any of them are suppressed as well. If the group is nil, only the
+// duplicate calls made through the returned loader are suppressed.
 func NewSuppressedLoader[K comparable, V any](loader Loader[K, V], group *singleflight.Group) *SuppressedLoader[K, V] {
-	if group == nil {
-		group = &singleflight.Group{}
+	calls := &suppressedCalls[K, V]{}
+
+	if group != nil {
+		shared, _ := suppressedGroups.LoadOrStore(suppressedGroupKey{
+			group: group,
+			calls: reflect.TypeOf(calls),
+		}, calls)
+		calls = shared.(*suppressedCalls[K, V])
 	}
 
 	return &SuppressedLoader[K, V]{
 		loader: loader,
-		group:  group,
+		calls:  calls,
 	}
 }
 
 // Load executes a custom item retrieval logic and returns the item that
 // is associated with the key.
-// It returns nil if the item is not found/valid.
+// It returns nil if the item is not found/valid or could not be
+// retrieved.
 // It also ensures that only one execution of the wrapped Loader's Load
 // method is in-flight for a given key at a time.
 func (l *SuppressedLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
-	// there should be a better/generic way to create a
-	// singleflight Group's key. It's possible that a generic
-	// singleflight.Group will be introduced with/in go1.19+
-	strKey := fmt.Sprint(key)
-
-	// the error can be discarded since the singleflight.Group
-	// itself does not return any of its errors, it returns
-	// the error that we return ourselves in the func below, which
-	// is also nil
-	res, _, _ := l.group.Do(strKey, func() (interface{}, error) {
-		item := l.loader.Load(c, key)
-		if item == nil {
-			return nil, nil
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+// It also ensures that only one execution of the wrapped Loader's
+// load method is in-flight for a given key at a time. All callers
+// that wait for the same execution receive its item and error.
+func (l *SuppressedLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+// It also ensures that only one execution of the wrapped Loader's
+// load method is in-flight for a given key at a time. All callers
+// that wait for the same execution receive its item and error; if the
+// execution panics, the panic is propagated to all of them.
+// A caller returns the error of its own context as soon as the context
+// is cancelled, while the shared execution continues. The context that
+// is passed to the wrapped Loader carries the values of the context
+// of the caller that started the execution, and it is cancelled only
+// when all waiting callers have given up.
+func (l *SuppressedLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	call := l.calls.join(ctx, l.loader, c, key)
+
+	select {
+	case <-call.done:
+		l.calls.leave(key, call)
+
+		if call.panicked {
+			panic(call.panicVal)
 		}
 
-		return item, nil
-	})
-	if res == nil {
-		return nil
+		return call.item, call.err
+	case <-ctx.Done():
+		l.calls.leave(key, call)
+
+		return nil, ctx.Err()
+	}
+}
+
+// Forget makes the suppressed loader forget about the in-flight load
+// of the provided key, so that subsequent calls start a new execution
+// of the wrapped Loader instead of waiting for the existing one.
+// Callers that already wait for the existing execution still receive
+// its result.
+func (l *SuppressedLoader[K, V]) Forget(key K) {
+	l.calls.mu.Lock()
+	defer l.calls.mu.Unlock()
+
+	delete(l.calls.calls, key)
+}
+
+// join registers a new caller that waits for the load of the provided
+// key and returns the load's shared call. If no load of the key is
+// in-flight, a new one is started with the provided loader.
+func (s *suppressedCalls[K, V]) join(ctx context.Context, loader Loader[K, V], c *Cache[K, V], key K) *suppressedCall[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.calls == nil {
+		s.calls = make(map[K]*suppressedCall[K, V])
+	}
+
+	call := s.calls[key]
+	if call == nil {
+		callCtx, cancel := context.WithCancel(detachedContext{parent: ctx})
+		call = &suppressedCall[K, V]{
+			ctx:    callCtx,
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		s.calls[key] = call
+
+		go s.run(loader, c, key, call)
+	}
+
+	call.waiters++
+
+	return call
+}
+
+// run executes the provided Loader and stores its result (or the value
+// of its panic) in the provided call.
+func (s *suppressedCalls[K, V]) run(loader Loader[K, V], c *Cache[K, V], key K, call *suppressedCall[K, V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.panicked = true
+			call.panicVal = r
+		}
+
+		// subsequent callers should start a new load
+		s.mu.Lock()
+		if s.calls[key] == call {
+			delete(s.calls, key)
+		}
+		s.mu.Unlock()
+
+		call.cancel()
+		close(call.done)
+	}()
+
+	call.item, call.err = loadContext(call.ctx, loader, c, key)
+}
+
+// leave unregisters a caller that waited for the load of the provided
+// key. When the last caller leaves, the load's context is cancelled
+// and any subsequent caller starts a new load.
+func (s *suppressedCalls[K, V]) leave(key K, call *suppressedCall[K, V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	call.cancel()
+
+	if s.calls[key] == call {
+		delete(s.calls, key)
 	}
+}
+
+// detachedContext carries the values of its parent context, but is
+// never cancelled along with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns a nil channel, since the context is never cancelled.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err always returns nil.
+func (detachedContext) Err() error {
+	return nil
+}
 
-	return res.(*Item[K, V])
+// Value returns the parent context's value associated with the key.
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
 }
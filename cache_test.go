@@ -1,9 +1,13 @@
 package ttlcache
 
 import (
-	"container/list"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -26,13 +30,126 @@ func Test_New(t *testing.T) {
 	require.NotNil(t, c)
 	assert.NotNil(t, c.stopCh)
 	assert.NotNil(t, c.items.values)
-	assert.NotNil(t, c.items.lru)
+	assert.IsType(t, &LRUPolicy[string, string]{}, c.items.policy)
 	assert.NotNil(t, c.items.expQueue)
 	assert.NotNil(t, c.items.timerCh)
 	assert.NotNil(t, c.events.insertion.fns)
 	assert.NotNil(t, c.events.eviction.fns)
+	assert.NotNil(t, c.events.refreshFailure.fns)
+	assert.NotNil(t, c.refreshes.keys)
+	assert.NotNil(t, c.batches.calls)
 	assert.Equal(t, time.Hour, c.options.ttl)
 	assert.Equal(t, uint64(1), c.options.capacity)
+
+	c = New[string, string](
+		WithCapacity[string, string](3),
+		WithEvictionPolicyFunc[string, string](func(capacity uint64) EvictionPolicy[string, string] {
+			return NewARCPolicy[string, string](capacity)
+		}),
+	)
+	require.NotNil(t, c)
+	require.IsType(t, &ARCPolicy[string, string]{}, c.items.policy)
+	assert.Equal(t, 3, c.items.policy.(*ARCPolicy[string, string]).capacity)
+
+	// the maximum cost is used when the capacity is not set
+	c = New[string, string](
+		WithMaxCost[string, string](500),
+		WithEvictionPolicyFunc[string, string](func(maxCost uint64) EvictionPolicy[string, string] {
+			return NewWeightedTinyLFUPolicy[string, string](maxCost)
+		}),
+	)
+	require.NotNil(t, c)
+	require.IsType(t, &TinyLFUPolicy[string, string]{}, c.items.policy)
+	assert.Equal(t, uint64(5), c.items.policy.(*TinyLFUPolicy[string, string]).windowCap)
+
+	c = New[string, string](
+		WithAccessBuffer[string, string](8),
+	)
+	require.NotNil(t, c)
+	require.NotNil(t, c.items.accessBuf)
+	assert.Equal(t, 8, c.items.accessBuf.size)
+
+	c = New[string, string](
+		WithTimingWheel[string, string](time.Millisecond),
+	)
+	require.NotNil(t, c)
+	require.NotNil(t, c.items.wheel)
+	assert.Equal(t, time.Millisecond, c.items.wheel.tick)
+
+	c = New[string, string](
+		WithNegativeTTL[string, string](time.Minute),
+		WithNegativeCapacity[string, string](5),
+		WithCapacity[string, string](3),
+	)
+	require.NotNil(t, c)
+	require.NotNil(t, c.items.absent)
+	assert.Equal(t, time.Minute, c.items.absent.ttl)
+	assert.Equal(t, uint64(5), c.items.absent.capacity)
+
+	c = New[string, string](
+		WithNegativeTTL[string, string](time.Minute),
+	)
+	require.NotNil(t, c)
+	require.NotNil(t, c.items.absent)
+	assert.Equal(t, uint64(defaultTombstoneCapacity), c.items.absent.capacity)
+}
+
+func Test_Open(t *testing.T) {
+	dir := t.TempDir()
+	opts := []Option[string, string]{
+		WithTTL[string, string](time.Hour),
+		WithVersion[string, string](true),
+		WithCapacity[string, string](3),
+		WithKeyCodec[string, string](BinaryCodec[string]{}),
+	}
+
+	c, err := Open[string, string](WALConfig{Dir: dir}, opts...)
+	require.NoError(t, err)
+	require.NotNil(t, c.items.wal)
+
+	c.Set("1", "value1", DefaultTTL)
+	c.Set("2", "value2", NoTTL)
+	c.Set("3", "value3", time.Minute)
+	c.Set("1", "value1", DefaultTTL)
+	c.Delete("2")
+	c.Touch("3")
+	c.SetWithCost("4", "value4", time.Minute, 5)
+	c.Set("5", "value5", DefaultTTL) // evicts "1"
+
+	items := c.Items()
+	require.NoError(t, c.Close())
+
+	// all changes are recovered
+	c, err = Open[string, string](WALConfig{Dir: dir}, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5", "4", "3"}, lruKeys(c))
+
+	for key, item := range items {
+		res := c.Get(key, WithDisableTouchOnHit[string, string]())
+		require.NotNil(t, res)
+		assert.Equal(t, item.Value(), res.Value())
+		assert.Equal(t, item.TTL(), res.TTL())
+		assert.True(t, item.ExpiresAt().Equal(res.ExpiresAt()))
+		assert.Equal(t, item.Version(), res.Version())
+		assert.Equal(t, item.Cost(), res.Cost())
+	}
+
+	c.DeleteAll()
+	c.Set("6", "value6", DefaultTTL)
+	require.NoError(t, c.Close())
+
+	c, err = Open[string, string](WALConfig{Dir: dir}, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"6"}, c.Keys())
+	require.NoError(t, c.Close())
+
+	// invalid directory
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	c, err = Open[string, string](WALConfig{Dir: file})
+	assert.Error(t, err)
+	assert.Nil(t, c)
 }
 
 func Test_Cache_updateExpirations(t *testing.T) {
@@ -128,32 +245,26 @@ func Test_Cache_updateExpirations(t *testing.T) {
 				cache.items.timerCh <- c.TimerChValue
 			}
 
-			elem := &list.Element{
-				Value: &Item[string, string]{
-					expiresAt: c.NewExpiresAt,
-				},
+			item := &Item[string, string]{
+				expiresAt: c.NewExpiresAt,
 			}
 
 			if !c.EmptyQueue {
-				cache.items.expQueue.push(&list.Element{
-					Value: &Item[string, string]{
-						expiresAt: c.OldExpiresAt,
-					},
+				cache.items.expQueue.push(&Item[string, string]{
+					expiresAt: c.OldExpiresAt,
 				})
 
 				if !c.Fresh {
-					elem = &list.Element{
-						Value: &Item[string, string]{
-							expiresAt: c.OldExpiresAt,
-						},
+					item = &Item[string, string]{
+						expiresAt: c.OldExpiresAt,
 					}
-					cache.items.expQueue.push(elem)
+					cache.items.expQueue.push(item)
 
-					elem.Value.(*Item[string, string]).expiresAt = c.NewExpiresAt
+					item.expiresAt = c.NewExpiresAt
 				}
 			}
 
-			cache.updateExpirations(c.Fresh, elem)
+			cache.updateExpirations(c.Fresh, item)
 
 			var res time.Duration
 
@@ -165,6 +276,55 @@ func Test_Cache_updateExpirations(t *testing.T) {
 			assert.InDelta(t, c.Result, res, float64(time.Second))
 		})
 	}
+
+	// timing wheel
+	cache := prepCache(time.Hour)
+	cache.items.wheel = newTimingWheel[string, string](time.Millisecond)
+
+	item := newItem("test", "value", time.Hour, false)
+	cache.updateExpirations(true, item)
+	assert.Empty(t, cache.items.expQueue)
+	assert.NotNil(t, item.wheelSlot)
+	require.Len(t, cache.items.timerCh, 1)
+	assert.Equal(t, time.Millisecond, <-cache.items.timerCh)
+
+	// cleaner is notified only when the wheel stops being empty
+	item2 := newItem("test2", "value", time.Hour, false)
+	cache.updateExpirations(true, item2)
+	assert.Empty(t, cache.items.timerCh)
+	assert.Equal(t, 2, cache.items.wheel.len)
+
+	item2.ttl = NoTTL
+	item2.expiresAt = time.Time{}
+	cache.updateExpirations(false, item2)
+	assert.Nil(t, item2.wheelSlot)
+	assert.Equal(t, 1, cache.items.wheel.len)
+}
+
+func Test_Cache_markChanged(t *testing.T) {
+	cache := prepCache(time.Hour, "1")
+	item := cache.items.values["1"]
+
+	// deletions are not tracked
+	cache.markChanged(item)
+	assert.Nil(t, cache.items.deleted)
+
+	// deletion records are removed
+	cache.items.deleted = map[string]struct{}{"1": {}}
+	cache.markChanged(item)
+	assert.Empty(t, cache.items.deleted)
+}
+
+func Test_Cache_markDeleted(t *testing.T) {
+	cache := prepCache(time.Hour)
+
+	// deletions are not tracked
+	cache.markDeleted("1")
+	assert.Nil(t, cache.items.deleted)
+
+	cache.items.deleted = make(map[string]struct{})
+	cache.markDeleted("1")
+	assert.Equal(t, map[string]struct{}{"1": {}}, cache.items.deleted)
 }
 
 func Test_Cache_set(t *testing.T) {
@@ -275,35 +435,123 @@ func Test_Cache_set(t *testing.T) {
 				}
 			}
 
-			assert.Same(t, cache.items.values[c.Key].Value.(*Item[string, string]), item)
+			assert.Same(t, cache.items.values[c.Key], item)
 			assert.Len(t, cache.items.values, total)
 			assert.Equal(t, c.Key, item.key)
 			assert.Equal(t, "value123", item.value)
-			assert.Equal(t, c.Key, cache.items.lru.Front().Value.(*Item[string, string]).key)
+			assert.Equal(t, c.Key, lruFront(cache).key)
 			assert.Equal(t, c.Metrics, cache.metrics)
 
 			if c.Capacity > 0 && c.Capacity < 4 {
-				assert.NotEqual(t, evictedKey, cache.items.lru.Back().Value.(*Item[string, string]).key)
+				assert.NotEqual(t, evictedKey, lruBack(cache).key)
 			}
 
 			switch {
 			case c.TTL == DefaultTTL:
 				assert.Equal(t, cache.options.ttl, item.ttl)
 				assert.WithinDuration(t, time.Now(), item.expiresAt, cache.options.ttl)
-				assert.Equal(t, c.Key, cache.items.expQueue[0].Value.(*Item[string, string]).key)
+				assert.Equal(t, c.Key, cache.items.expQueue[0].key)
 			case c.TTL > DefaultTTL:
 				assert.Equal(t, c.TTL, item.ttl)
 				assert.WithinDuration(t, time.Now(), item.expiresAt, c.TTL)
-				assert.Equal(t, c.Key, cache.items.expQueue[0].Value.(*Item[string, string]).key)
+				assert.Equal(t, c.Key, cache.items.expQueue[0].key)
 			default:
 				assert.Equal(t, c.TTL, item.ttl)
 				assert.Zero(t, item.expiresAt)
-				assert.NotEqual(t, c.Key, cache.items.expQueue[0].Value.(*Item[string, string]).key)
+				assert.NotEqual(t, c.Key, cache.items.expQueue[0].key)
 			}
 		})
 	}
 }
 
+func Test_Cache_setWithCost(t *testing.T) {
+	var (
+		reasons = make(map[string]EvictionReason)
+		fnsKeys []string
+	)
+
+	cache := prepCache(time.Hour)
+	cache.options.maxCost = 10
+	cache.events.eviction.fns[1] = func(r EvictionReason, item *Item[string, string]) {
+		reasons[item.key] = r
+		fnsKeys = append(fnsKeys, item.key)
+	}
+
+	cache.setWithCost("1", "1", NoTTL, 3)
+	cache.setWithCost("2", "2", NoTTL, 3)
+	cache.setWithCost("3", "3", NoTTL, 3)
+	assert.Equal(t, uint64(9), cache.items.cost)
+
+	// as many items as needed are evicted
+	item := cache.setWithCost("4", "4", NoTTL, 6)
+	require.NotNil(t, item)
+	assert.Equal(t, uint64(6), item.cost)
+	assert.Equal(t, []string{"1", "2"}, fnsKeys)
+	assert.Equal(t, EvictionReasonCapacityReached, reasons["1"])
+	assert.Equal(t, uint64(9), cache.items.cost)
+
+	// updated item's cost is taken into account
+	fnsKeys = nil
+
+	item = cache.setWithCost("4", "4", NoTTL, 8)
+	require.NotNil(t, item)
+	assert.Equal(t, uint64(8), item.cost)
+	assert.Equal(t, []string{"3"}, fnsKeys)
+	assert.Equal(t, uint64(8), cache.items.cost)
+
+	// other items are evicted to make room for the updated item
+	fnsKeys = nil
+	cache.options.maxCost = 0
+	cache.setWithCost("5", "5", NoTTL, 2)
+	cache.options.maxCost = 10
+	cache.items.policy.OnAccess(cache.items.values["5"])
+
+	item = cache.setWithCost("4", "4", NoTTL, 9)
+	require.NotNil(t, item)
+	assert.Same(t, item, cache.items.values["4"])
+	assert.NotContains(t, cache.items.values, "5")
+	assert.Equal(t, []string{"5"}, fnsKeys)
+	assert.Equal(t, uint64(9), cache.items.cost)
+
+	// item with a cost that exceeds the maximum cost is rejected
+	fnsKeys = nil
+
+	item = cache.setWithCost("4", "4", NoTTL, 11)
+	assert.Nil(t, item)
+	assert.Empty(t, cache.items.values)
+	assert.Equal(t, []string{"4", "4"}, fnsKeys)
+	assert.Equal(t, EvictionReasonMaxCostExceeded, reasons["4"])
+	assert.Zero(t, cache.items.cost)
+	assert.Equal(t, uint64(1), cache.metrics.CostRejections)
+	assert.Zero(t, cache.metrics.Rejections)
+
+	// expired item is replaced
+	fnsKeys = nil
+
+	cache.setWithCost("6", "6", time.Nanosecond, 1)
+	time.Sleep(time.Millisecond)
+
+	item = cache.setWithCost("6", "6", NoTTL, 2)
+	require.NotNil(t, item)
+	assert.Equal(t, []string{"6"}, fnsKeys)
+	assert.Equal(t, EvictionReasonExpired, reasons["6"])
+	assert.Equal(t, uint64(2), cache.items.cost)
+	assert.Equal(t, 1, cache.items.expQueue.Len())
+
+	// updated item itself may be evicted
+	fnsKeys = nil
+	cache.items.policy = &fifoPolicy{}
+	cache.items.policy.OnInsert(cache.items.values["6"])
+	cache.setWithCost("7", "7", NoTTL, 1)
+
+	item = cache.setWithCost("6", "6", NoTTL, 10)
+	assert.Nil(t, item)
+	assert.NotContains(t, cache.items.values, "6")
+	assert.Equal(t, []string{"6"}, fnsKeys)
+	assert.Equal(t, EvictionReasonCapacityReached, reasons["6"])
+	assert.Equal(t, uint64(1), cache.items.cost)
+}
+
 func Test_Cache_get(t *testing.T) {
 	const existingKey, notFoundKey, expiredKey = "existing", "notfound", "expired"
 
@@ -342,7 +590,7 @@ func Test_Cache_get(t *testing.T) {
 			addToCache(cache, time.Nanosecond, expiredKey)
 			time.Sleep(time.Millisecond) // force expiration
 
-			oldItem := cache.items.values[existingKey].Value.(*Item[string, string])
+			oldItem := cache.items.values[existingKey]
 			oldQueueIndex := oldItem.queueIndex
 			oldExpiresAt := oldItem.expiresAt
 
@@ -352,21 +600,20 @@ func Test_Cache_get(t *testing.T) {
 				oldItem.ttl = 0
 			}
 
-			elem := cache.get(c.Key, c.Touch)
+			item := cache.get(c.Key, c.Touch)
 
 			if c.Key == notFoundKey {
-				assert.Nil(t, elem)
+				assert.Nil(t, item)
 				return
 			}
 
 			if c.Key == expiredKey {
-				assert.True(t, time.Now().After(cache.items.values[expiredKey].Value.(*Item[string, string]).expiresAt))
-				assert.Nil(t, elem)
+				assert.True(t, time.Now().After(cache.items.values[expiredKey].expiresAt))
+				assert.Nil(t, item)
 				return
 			}
 
-			require.NotNil(t, elem)
-			item := elem.Value.(*Item[string, string])
+			require.NotNil(t, item)
 
 			if c.Touch && c.WithTTL {
 				assert.True(t, item.expiresAt.After(oldExpiresAt))
@@ -376,9 +623,292 @@ func Test_Cache_get(t *testing.T) {
 				assert.Equal(t, oldQueueIndex, item.queueIndex)
 			}
 
-			assert.Equal(t, c.Key, cache.items.lru.Front().Value.(*Item[string, string]).key)
+			assert.Equal(t, c.Key, lruFront(cache).key)
+		})
+	}
+
+	// stale items are returned during the grace period, but are not
+	// touched
+	cache := prepCache(time.Hour)
+	cache.options.gracePeriod = time.Hour
+	addToCache(cache, time.Nanosecond, expiredKey)
+	time.Sleep(time.Millisecond) // force expiration
+
+	oldExpiresAt := cache.items.values[expiredKey].expiresAt
+	item := cache.get(expiredKey, true)
+	require.NotNil(t, item)
+	assert.True(t, item.isExpiredUnsafe())
+	assert.Equal(t, oldExpiresAt, item.expiresAt)
+}
+
+func Test_Cache_lockedGet(t *testing.T) {
+	const existingKey, notFoundKey, expiredKey = "existing", "notfound", "expired"
+
+	cc := map[string]struct {
+		Key              string
+		Touch            bool
+		TTL              time.Duration
+		ConcurrentPolicy bool
+		ConcurrentAccess bool
+	}{
+		"Retrieval of non-existent item": {
+			Key:              notFoundKey,
+			ConcurrentPolicy: true,
+		},
+		"Retrieval of expired item": {
+			Key:              expiredKey,
+			ConcurrentPolicy: true,
+		},
+		"Retrieval of existing item with regular policy": {
+			Key: existingKey,
+		},
+		"Retrieval of existing item with concurrent policy and without touch": {
+			Key:              existingKey,
+			TTL:              time.Hour,
+			ConcurrentPolicy: true,
+			ConcurrentAccess: true,
+		},
+		"Retrieval of existing item with concurrent policy, touch and zero TTL": {
+			Key:              existingKey,
+			Touch:            true,
+			TTL:              NoTTL,
+			ConcurrentPolicy: true,
+			ConcurrentAccess: true,
+		},
+		"Retrieval of existing item with concurrent policy, touch and non zero TTL": {
+			Key:              existingKey,
+			Touch:            true,
+			TTL:              time.Hour,
+			ConcurrentPolicy: true,
+			ConcurrentAccess: true,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			p := &accessRecordingPolicy{SIEVEPolicy: NewSIEVEPolicy[string, string]()}
+
+			cache := prepCache(time.Hour)
+			cache.items.policy = p
+			cache.items.accessBuf = newAccessBuffer[string, string](touchBufferSize)
+			if !c.ConcurrentPolicy {
+				cache.items.policy = struct{ EvictionPolicy[string, string] }{p}
+				cache.items.accessBuf = nil
+			}
+
+			addToCache(cache, c.TTL, existingKey)
+			addToCache(cache, time.Nanosecond, expiredKey)
+			time.Sleep(time.Millisecond) // force expiration
+
+			item := cache.lockedGet(c.Key, c.Touch)
+
+			if c.Key != existingKey {
+				assert.Nil(t, item)
+				assert.Zero(t, p.accesses)
+				assert.Zero(t, p.concurrentAccesses)
+				return
+			}
+
+			require.NotNil(t, item)
+			assert.Equal(t, c.Key, item.key)
+
+			if c.ConcurrentAccess {
+				assert.Zero(t, p.accesses)
+				assert.Equal(t, 1, p.concurrentAccesses)
+
+				// only touches are buffered
+				var touches int
+				cache.items.accessBuf.drain(func(a access[string, string]) {
+					assert.True(t, a.touch)
+					touches++
+				})
+
+				if c.Touch && c.TTL > 0 {
+					assert.Equal(t, 1, touches)
+				} else {
+					assert.Zero(t, touches)
+				}
+			} else {
+				assert.Equal(t, 1, p.accesses)
+				assert.Zero(t, p.concurrentAccesses)
+			}
 		})
 	}
+
+	// buffered accesses
+	p := &accessRecordingPolicy{SIEVEPolicy: NewSIEVEPolicy[string, string]()}
+
+	cache := prepCache(time.Hour)
+	cache.items.policy = struct{ EvictionPolicy[string, string] }{p}
+	cache.items.accessBuf = newAccessBuffer[string, string](1)
+
+	addToCache(cache, time.Hour, existingKey)
+	addToCache(cache, time.Nanosecond, expiredKey)
+	time.Sleep(time.Millisecond) // force expiration
+
+	assert.Nil(t, cache.lockedGet(notFoundKey, true))
+	assert.Nil(t, cache.lockedGet(expiredKey, true))
+	assert.Zero(t, p.accesses)
+
+	item := cache.items.values[existingKey]
+	oldExp := item.expiresAt
+
+	assert.Same(t, item, cache.lockedGet(existingKey, true))
+	assert.Equal(t, 1, p.accesses)
+	assert.Zero(t, p.concurrentAccesses)
+	assert.True(t, item.expiresAt.After(oldExp))
+	assert.Empty(t, cache.items.accessBuf.batches)
+
+	// buffered touches with a concurrent policy
+	p = &accessRecordingPolicy{SIEVEPolicy: NewSIEVEPolicy[string, string]()}
+
+	cache = New[string, string](
+		WithTTL[string, string](time.Hour),
+		WithEvictionPolicy[string, string](p),
+	)
+	require.NotNil(t, cache.items.accessBuf)
+
+	cache.Set(existingKey, "value", DefaultTTL)
+	item = cache.items.values[existingKey]
+	oldExp = item.expiresAt
+
+	for i := 0; i < touchBufferSize*len(cache.items.accessBuf.stripes); i++ {
+		assert.Same(t, item, cache.lockedGet(existingKey, true))
+	}
+
+	cache.items.mu.Lock()
+	cache.drainAccesses()
+	cache.items.mu.Unlock()
+
+	assert.Zero(t, p.accesses)
+	assert.Equal(t, touchBufferSize*len(cache.items.accessBuf.stripes), p.concurrentAccesses)
+	assert.True(t, item.expiresAt.After(oldExp))
+}
+
+func Test_Cache_isUsable(t *testing.T) {
+	cache := prepCache(time.Hour)
+
+	assert.True(t, cache.isUsable(&Item[string, string]{ttl: NoTTL}))
+	assert.True(t, cache.isUsable(&Item[string, string]{ttl: time.Hour, expiresAt: time.Now().Add(time.Hour)}))
+
+	expired := &Item[string, string]{ttl: time.Hour, expiresAt: time.Now().Add(-time.Minute)}
+	assert.False(t, cache.isUsable(expired))
+
+	cache.options.gracePeriod = time.Hour
+	assert.True(t, cache.isUsable(expired))
+
+	cache.options.gracePeriod = time.Second
+	assert.False(t, cache.isUsable(expired))
+}
+
+func Test_Cache_drainAccesses(t *testing.T) {
+	p := &accessRecordingPolicy{SIEVEPolicy: NewSIEVEPolicy[string, string]()}
+
+	cache := prepCache(time.Hour)
+	cache.items.policy = struct{ EvictionPolicy[string, string] }{p}
+
+	// no buffer
+	cache.drainAccesses()
+
+	cache.items.accessBuf = newAccessBuffer[string, string](4)
+
+	addToCache(cache, time.Hour, "touched", "untouched", "removed", "replaced")
+	addToCache(cache, time.Millisecond, "expired")
+	time.Sleep(time.Millisecond * 2) // force expiration
+
+	touched := cache.items.values["touched"]
+	untouched := cache.items.values["untouched"]
+	expired := cache.items.values["expired"]
+	touchedExp, untouchedExp := touched.expiresAt, untouched.expiresAt
+
+	cache.items.accessBuf.batches <- []access[string, string]{
+		{item: touched, touch: true},
+		{item: untouched},
+		{item: cache.items.values["removed"], touch: true},
+		{item: cache.items.values["replaced"], touch: true},
+		{item: expired, touch: true},
+	}
+
+	cache.evict(EvictionReasonDeleted, cache.items.values["removed"], cache.items.values["replaced"])
+	cache.set("replaced", "value", time.Hour)
+	p.accesses = 0
+
+	cache.drainAccesses()
+	assert.Equal(t, 2, p.accesses)
+	assert.True(t, touched.expiresAt.After(touchedExp))
+	assert.Equal(t, untouchedExp, untouched.expiresAt)
+	assert.True(t, expired.isExpiredUnsafe())
+	assert.Len(t, cache.items.accessBuf.batches, 0)
+
+	// partially filled stripes are drained as well
+	p.accesses = 0
+	cache.items.accessBuf.push(touched, false)
+
+	cache.drainAccesses()
+	assert.Equal(t, 1, p.accesses)
+
+	// concurrent policies have recorded the accesses already
+	cache.items.policy = p
+	p.accesses = 0
+	cache.items.accessBuf.push(touched, true)
+	touchedExp = touched.expiresAt
+
+	cache.drainAccesses()
+	assert.Zero(t, p.accesses)
+	assert.False(t, touched.expiresAt.Before(touchedExp))
+}
+
+func Test_Cache_startLoad(t *testing.T) {
+	cache := prepCache(time.Hour, "1")
+
+	assert.Equal(t, uint64(0), cache.startLoad("1"))
+	assert.Equal(t, uint64(0), cache.startLoad("1"))
+	assert.Equal(t, &keyLoads{count: 2}, cache.items.loads["1"])
+
+	cache.items.loads["1"].deletions = 3
+	assert.Equal(t, uint64(3), cache.startLoad("1"))
+	assert.Equal(t, &keyLoads{count: 3, deletions: 3}, cache.items.loads["1"])
+}
+
+func Test_Cache_finishLoad(t *testing.T) {
+	cache := prepCache(time.Hour, "1")
+
+	deletions := cache.startLoad("1")
+	cache.startLoad("1")
+	cache.items.loads["1"].deletions++
+
+	assert.True(t, cache.finishLoad("1", deletions))
+	assert.Equal(t, &keyLoads{count: 1, deletions: 1}, cache.items.loads["1"])
+	assert.False(t, cache.finishLoad("1", deletions+1))
+	assert.Empty(t, cache.items.loads)
+}
+
+func Test_Cache_trackDeletions(t *testing.T) {
+	cache := prepCache(time.Hour, "1", "2", "3")
+
+	// no loads
+	cache.trackDeletions(cache.items.values["1"])
+	assert.Nil(t, cache.items.loads)
+
+	// some items
+	cache.startLoad("1")
+	cache.startLoad("2")
+	cache.trackDeletions(cache.items.values["1"], cache.items.values["3"])
+	assert.Equal(t, map[string]*keyLoads{
+		"1": {count: 1, deletions: 1},
+		"2": {count: 1},
+	}, cache.items.loads)
+
+	// all items
+	cache.trackDeletions()
+	assert.Equal(t, map[string]*keyLoads{
+		"1": {count: 1, deletions: 2},
+		"2": {count: 1, deletions: 1},
+	}, cache.items.loads)
 }
 
 func Test_Cache_evict(t *testing.T) {
@@ -406,7 +936,8 @@ func Test_Cache_evict(t *testing.T) {
 	cache.events.eviction.fns[2] = cache.events.eviction.fns[1]
 
 	// delete only specified
-	cache.evict(EvictionReasonDeleted, cache.items.lru.Back(), cache.items.lru.Back().Prev())
+	back := cache.items.policy.(*LRUPolicy[string, string]).list.Back()
+	cache.evict(EvictionReasonDeleted, back.Value.(*Item[string, string]), back.Prev().Value.(*Item[string, string]))
 
 	assert.Equal(t, 2, key1FnsCalls)
 	assert.Equal(t, 2, key2FnsCalls)
@@ -431,17 +962,50 @@ func Test_Cache_evict(t *testing.T) {
 	assert.NotContains(t, cache.items.values, "3")
 	assert.NotContains(t, cache.items.values, "4")
 	assert.Equal(t, uint64(2), cache.metrics.Evictions)
+
+	// timing wheel
+	cache = prepCache(time.Hour)
+	cache.items.wheel = newTimingWheel[string, string](time.Millisecond)
+	addToCache(cache, time.Hour, "1", "2")
+	require.Equal(t, 2, cache.items.wheel.len)
+
+	item := cache.items.values["1"]
+	cache.evict(EvictionReasonDeleted, item)
+	assert.Nil(t, item.wheelSlot)
+	assert.Equal(t, 1, cache.items.wheel.len)
+
+	cache.evict(EvictionReasonDeleted)
+	assert.True(t, cache.items.wheel.isEmpty())
+	assert.Equal(t, time.Millisecond, cache.items.wheel.tick)
 }
 
 func Test_Cache_Set(t *testing.T) {
 	cache := prepCache(time.Hour, "test1", "test2", "test3")
 	item := cache.Set("hello", "value123", time.Minute)
 	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["hello"].Value)
+	assert.Same(t, item, cache.items.values["hello"])
 
 	item = cache.Set("test1", "value123", time.Minute)
 	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["test1"].Value)
+	assert.Same(t, item, cache.items.values["test1"])
+}
+
+func Test_Cache_SetWithCost(t *testing.T) {
+	cache := prepCache(time.Hour)
+	cache.options.costFunc = func(_ string, _ string) uint64 {
+		return 100
+	}
+
+	item := cache.SetWithCost("test", "value123", time.Minute, 5)
+	require.NotNil(t, item)
+	assert.Same(t, item, cache.items.values["test"])
+	assert.Equal(t, uint64(5), item.cost)
+	assert.Equal(t, uint64(5), cache.items.cost)
+
+	item = cache.Set("test2", "value123", time.Minute)
+	require.NotNil(t, item)
+	assert.Equal(t, uint64(100), item.cost)
+	assert.Equal(t, uint64(105), cache.items.cost)
 }
 
 func Test_Cache_Get(t *testing.T) {
@@ -482,6 +1046,18 @@ func Test_Cache_Get(t *testing.T) {
 				Misses: 1,
 			},
 		},
+		"Get with default loader that returns an error when item is not found": {
+			Key: notFoundKey,
+			DefaultOptions: options[string, string]{
+				loader: LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+					return nil, errors.New("error")
+				}),
+			},
+			Metrics: Metrics{
+				Misses:     1,
+				LoadErrors: 1,
+			},
+		},
 		"Get with call loader that returns non nil value when item is not found": {
 			Key: notFoundKey,
 			DefaultOptions: options[string, string]{
@@ -548,14 +1124,14 @@ func Test_Cache_Get(t *testing.T) {
 			t.Parallel()
 
 			cache := prepCache(time.Minute, foundKey, "test2", "test3")
-			oldExpiresAt := cache.items.values[foundKey].Value.(*Item[string, string]).expiresAt
+			oldExpiresAt := cache.items.values[foundKey].expiresAt
 			cache.options = c.DefaultOptions
 
 			res := cache.Get(c.Key, c.CallOptions...)
 
 			if c.Key == foundKey {
-				c.Result = cache.items.values[foundKey].Value.(*Item[string, string])
-				assert.Equal(t, foundKey, cache.items.lru.Front().Value.(*Item[string, string]).key)
+				c.Result = cache.items.values[foundKey]
+				assert.Equal(t, foundKey, lruFront(cache).key)
 			}
 
 			assert.Equal(t, c.Metrics, cache.metrics)
@@ -577,87 +1153,741 @@ func Test_Cache_Get(t *testing.T) {
 	}
 }
 
-func Test_Cache_Delete(t *testing.T) {
-	var fnsCalls int
+func Test_Cache_GetE(t *testing.T) {
+	errLoad := errors.New("error")
+	loader := LoaderWithErrorFunc[string, string](func(c *Cache[string, string], key string) (*Item[string, string], error) {
+		switch key {
+		case "error":
+			return nil, errLoad
+		case "loaded":
+			return c.Set(key, "loaded", DefaultTTL), nil
+		default:
+			return nil, nil
+		}
+	})
 
-	cache := prepCache(time.Hour, "1", "2", "3", "4")
-	cache.events.eviction.fns[1] = func(r EvictionReason, item *Item[string, string]) {
-		assert.Equal(t, EvictionReasonDeleted, r)
-		fnsCalls++
-	}
-	cache.events.eviction.fns[2] = cache.events.eviction.fns[1]
+	cache := prepCache(time.Hour, "test")
+	cache.options.loader = loader
+
+	// found
+	item, err := cache.GetE("test")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+
+	// loaded
+	item, err = cache.GetE("loaded")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded", item.value)
 
 	// not found
-	cache.Delete("1234")
-	assert.Zero(t, fnsCalls)
-	assert.Len(t, cache.items.values, 4)
+	item, err = cache.GetE("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
 
-	// success
-	cache.Delete("1")
-	assert.Equal(t, 2, fnsCalls)
-	assert.Len(t, cache.items.values, 3)
-	assert.NotContains(t, cache.items.values, "1")
-}
+	// failed load
+	item, err = cache.GetE("error")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
 
-func Test_Cache_Has(t *testing.T) {
-	cc := map[string]struct {
-		keys      []string
-		searchKey string
-		has       bool
-	}{
-		"Empty cache": {
-			keys:      []string{},
-			searchKey: "key1",
-			has:       false,
-		},
-		"Key exists": {
-			keys:      []string{"key1", "key2", "key3"},
-			searchKey: "key2",
-			has:       true,
-		},
-		"Key doesn't exist": {
-			keys:      []string{"key1", "key2", "key3"},
-			searchKey: "key4",
-			has:       false,
-		},
-	}
+	// regular loader
+	item, err = cache.GetE("error", WithLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	})))
+	assert.NoError(t, err)
+	assert.Nil(t, item)
 
-	for name, tc := range cc {
-		t.Run(name, func(t *testing.T) {
-			c := prepCache(NoTTL, tc.keys...)
-			has := c.Has(tc.searchKey)
-			assert.Equal(t, tc.has, has)
-		})
-	}
+	// no loader
+	cache.options.loader = nil
+	item, err = cache.GetE("error")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	assert.Equal(t, Metrics{Insertions: 1, Hits: 1, Misses: 5, LoadErrors: 1}, cache.metrics)
 }
 
-func Test_Cache_GetOrSet(t *testing.T) {
-	cache := prepCache(time.Hour)
-	item, retrieved := cache.GetOrSet("test", "1", WithTTL[string, string](time.Minute))
-	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["test"].Value)
-	assert.False(t, retrieved)
+func Test_Cache_GetMany(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
 
-	item, retrieved = cache.GetOrSet("test", "1", WithTTL[string, string](time.Minute))
-	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["test"].Value)
-	assert.True(t, retrieved)
+	cache := prepCache(time.Hour, "1", "2")
+	cache.options.maxBatchSize = 2
+	cache.options.batchLoader = BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+
+		res := make(map[string]string)
+		for _, key := range keys {
+			if key != "missing" {
+				res[key] = "loaded " + key
+			}
+		}
 
-	item, retrieved = cache.GetOrSet("test2", "1", WithTTL[string, string](time.Microsecond))
-	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["test2"].Value)
-	assert.False(t, retrieved)
+		return res, nil
+	})
 
-	time.Sleep(time.Millisecond)
-	item, retrieved = cache.GetOrSet("test2", "2", WithTTL[string, string](time.Minute))
+	items := cache.GetMany([]string{"1", "2", "3", "3", "4", "missing"})
+	require.Len(t, items, 4)
+	assert.Equal(t, "value of1", items["1"].Value())
+	assert.Equal(t, "value of2", items["2"].Value())
+	assert.Equal(t, "loaded 3", items["3"].Value())
+	assert.Equal(t, "loaded 4", items["4"].Value())
+	assert.ElementsMatch(t, [][]string{{"3", "4"}, {"missing"}}, batches)
+	assert.Equal(t, cache.items.values["3"], items["3"])
+	assert.Empty(t, cache.batches.calls)
+	assert.Equal(t, Metrics{Insertions: 2, Hits: 2, Misses: 3}, cache.metrics)
+
+	// loaded items are hits afterwards
+	batches = nil
+	items = cache.GetMany([]string{"3", "4"})
+	assert.Len(t, items, 2)
+	assert.Empty(t, batches)
+
+	// ephemeral batch loader
+	items = cache.GetMany([]string{"5"}, WithBatchLoader[string, string](BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], _ []string) (map[string]string, error) {
+		return map[string]string{"5": "ephemeral"}, nil
+	})))
+	require.Len(t, items, 1)
+	assert.Equal(t, "ephemeral", items["5"].Value())
+
+	// regular loader
+	cache.options.batchLoader = nil
+	cache.options.loader = LoaderFunc[string, string](func(c *Cache[string, string], key string) *Item[string, string] {
+		if key == "missing" {
+			return nil
+		}
+
+		return c.Set(key, "single "+key, DefaultTTL)
+	})
+
+	items = cache.GetMany([]string{"1", "6", "missing"})
+	require.Len(t, items, 2)
+	assert.Equal(t, "value of1", items["1"].Value())
+	assert.Equal(t, "single 6", items["6"].Value())
+
+	// no loader
+	cache.options.loader = nil
+	items = cache.GetMany([]string{"1", "7"})
+	require.Len(t, items, 1)
+	assert.Contains(t, items, "1")
+
+	// known absent keys are not loaded
+	cache.items.absent = newTombstones[string](time.Hour, 0)
+	cache.items.absent.add("missing")
+	cache.options.batchLoader = BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		batches = append(batches, keys)
+		return nil, nil
+	})
+
+	batches = nil
+	items = cache.GetMany([]string{"missing", "8"})
+	assert.Empty(t, items)
+	assert.Equal(t, [][]string{{"8"}}, batches)
+	assert.True(t, cache.items.absent.has("8"))
+	assert.Equal(t, uint64(1), cache.metrics.NegativeHits)
+	assert.Equal(t, uint64(1), cache.metrics.NegativeInsertions)
+}
+
+func Test_Cache_GetManyContext(t *testing.T) {
+	type ctxKey struct{}
+
+	errLoad := errors.New("error")
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	cache := prepCache(time.Hour, "1")
+	cache.options.batchLoader = BatchLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ []string) (map[string]string, error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return nil, errLoad
+	})
+
+	// batch loader
+	items, err := cache.GetManyContext(ctx, []string{"1", "2"})
+	assert.Equal(t, errLoad, err)
+	require.Len(t, items, 1)
+	assert.Contains(t, items, "1")
+	assert.Equal(t, uint64(1), cache.metrics.LoadErrors)
+
+	// regular loader
+	cache.options.batchLoader = nil
+	cache.options.loader = ContextLoaderFunc[string, string](func(ctx context.Context, c *Cache[string, string], key string) (*Item[string, string], error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+		if key == "error" {
+			return nil, errLoad
+		}
+
+		return c.Set(key, "loaded", DefaultTTL), nil
+	})
+
+	items, err = cache.GetManyContext(ctx, []string{"error", "2"})
+	assert.Equal(t, errLoad, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "loaded", items["2"].Value())
+}
+
+func Test_Cache_loadBatch(t *testing.T) {
+	var loaded []string
+
+	loader := BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		loaded = append(loaded, keys...)
+
+		res := make(map[string]string)
+		for _, key := range keys {
+			res[key] = "loaded"
+		}
+
+		return res, nil
+	})
+
+	cache := prepCache(time.Hour)
+
+	// keys that are in-flight are awaited instead of loaded
+	inFlight := &batchCall[string, string]{
+		keys: []string{"1", "other"},
+		done: make(chan struct{}),
+	}
+	cache.batches.calls["1"] = inFlight
+	cache.batches.calls["other"] = inFlight
+
+	type result struct {
+		items map[string]*Item[string, string]
+		err   error
+	}
+
+	resCh := make(chan result)
+
+	go func() {
+		items, err := cache.loadBatch(context.Background(), loader, []string{"1", "2"})
+		resCh <- result{items: items, err: err}
+	}()
+
+	assert.Eventually(t, func() bool {
+		cache.batches.mu.Lock()
+		defer cache.batches.mu.Unlock()
+
+		return len(cache.batches.calls) == 2
+	}, time.Second, time.Millisecond)
+
+	inFlight.items = map[string]*Item[string, string]{
+		"1":     {key: "1", value: "shared"},
+		"other": {key: "other", value: "other"},
+	}
+	close(inFlight.done)
+
+	res := <-resCh
+	require.NoError(t, res.err)
+	require.Len(t, res.items, 2)
+	assert.Equal(t, "shared", res.items["1"].value)
+	assert.Equal(t, "loaded", res.items["2"].value)
+	assert.Equal(t, []string{"2"}, loaded)
+
+	// waiting is stopped when the context is done
+	cache.batches.calls["1"] = &batchCall[string, string]{
+		keys: []string{"1"},
+		done: make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items, err := cache.loadBatch(ctx, loader, []string{"1"})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, items)
+
+	// own batches are awaited until the context is done, but are
+	// not cancelled along with it
+	type ctxKey struct{}
+
+	var (
+		releaseCh = make(chan struct{})
+		errCh     = make(chan error, 1)
+	)
+
+	ctx, cancel = context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+
+	go func() {
+		_, err := cache.loadBatch(ctx, BatchLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+			<-releaseCh
+
+			assert.NoError(t, ctx.Err())
+			assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+			return map[string]string{"3": "loaded"}, nil
+		}), []string{"3"})
+		errCh <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		cache.batches.mu.Lock()
+		defer cache.batches.mu.Unlock()
+
+		return cache.batches.calls["3"] != nil
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errCh)
+
+	close(releaseCh)
+
+	assert.Eventually(t, func() bool {
+		return cache.Get("3") != nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "loaded", cache.Get("3").Value())
+
+	// own batches are loaded concurrently
+	cache.options.maxBatchSize = 1
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	items, err = cache.loadBatch(context.Background(), BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		// each batch waits for the other one to start
+		wg.Done()
+		wg.Wait()
+
+		return map[string]string{keys[0]: "loaded"}, nil
+	}), []string{"4", "5"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "loaded", items["4"].value)
+	assert.Equal(t, "loaded", items["5"].value)
+}
+
+func Test_Cache_runBatch(t *testing.T) {
+	cache := prepCache(time.Hour)
+	cache.options.maxCost = 1
+	cache.options.costFunc = func(_ string, value string) uint64 {
+		return uint64(len(value))
+	}
+
+	call := &batchCall[string, string]{
+		keys: []string{"1", "2", "3"},
+		done: make(chan struct{}),
+	}
+	for _, key := range call.keys {
+		cache.batches.calls[key] = call
+	}
+
+	cache.runBatch(context.Background(), BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		assert.Equal(t, []string{"1", "2", "3"}, keys)
+		return map[string]string{"1": "a", "2": "too costly"}, nil
+	}), call)
+
+	assert.NoError(t, call.err)
+	require.Len(t, call.items, 1)
+	assert.Equal(t, "a", call.items["1"].value)
+	assert.Empty(t, cache.batches.calls)
+	assert.Len(t, cache.items.values, 1)
+
+	select {
+	case <-call.done:
+	default:
+		t.Fatal("call is not done")
+	}
+
+	// failed batch
+	errLoad := errors.New("error")
+	call = &batchCall[string, string]{
+		keys: []string{"1"},
+		done: make(chan struct{}),
+	}
+
+	cache.runBatch(context.Background(), BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], _ []string) (map[string]string, error) {
+		return nil, errLoad
+	}), call)
+
+	assert.Equal(t, errLoad, call.err)
+	assert.Nil(t, call.items)
+	assert.Equal(t, uint64(1), cache.metrics.LoadErrors)
+	assert.Empty(t, cache.items.loads)
+
+	// deleted while loading
+	cache.options.maxCost = 0
+	cache.Set("4", "old", DefaultTTL)
+	call = &batchCall[string, string]{
+		keys: []string{"4", "5"},
+		done: make(chan struct{}),
+	}
+
+	cache.runBatch(context.Background(), BatchLoaderFunc[string, string](func(_ context.Context, c *Cache[string, string], _ []string) (map[string]string, error) {
+		c.Delete("4")
+		return map[string]string{"4": "loaded", "5": "loaded"}, nil
+	}), call)
+
+	assert.NoError(t, call.err)
+	require.Len(t, call.items, 2)
+	assert.Equal(t, "loaded", call.items["4"].value)
+	assert.NotContains(t, cache.items.values, "4")
+	assert.Same(t, cache.items.values["5"], call.items["5"])
+	assert.Empty(t, cache.items.loads)
+}
+
+func Test_Cache_GetContext(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	defer cancel()
+
+	cache := prepCache(time.Hour, "test")
+	cache.options.loader = ContextLoaderFunc[string, string](func(ctx context.Context, c *Cache[string, string], key string) (*Item[string, string], error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+		if key == "cancelled" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		return c.Set(key, "loaded", DefaultTTL), nil
+	})
+
+	// found
+	item, err := cache.GetContext(ctx, "test")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+
+	// loaded
+	item, err = cache.GetContext(ctx, "loaded")
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded", item.value)
+
+	// cancelled
+	cancel()
+	item, err = cache.GetContext(ctx, "cancelled")
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, item)
+
+	assert.Equal(t, Metrics{Insertions: 1, Hits: 1, Misses: 2, LoadErrors: 1}, cache.metrics)
+}
+
+func Test_Cache_refresh(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		releaseCh = make(chan bool)
+		errLoad   = errors.New("error")
+		failed    = make(chan string, 1)
+	)
+
+	cache := prepCache(time.Hour, "test")
+	cache.events.refreshFailure.fns[1] = func(key string, err error) {
+		assert.Equal(t, errLoad, err)
+		failed <- key
+	}
+
+	loader := LoaderWithErrorFunc[string, string](func(c *Cache[string, string], key string) (*Item[string, string], error) {
+		mu.Lock()
+		loadCalls++
+		mu.Unlock()
+
+		if !<-releaseCh {
+			return nil, errLoad
+		}
+
+		return c.Set(key, "refreshed", DefaultTTL), nil
+	})
+
+	// duplicate refreshes are suppressed
+	cache.refresh(loader, cache.items.values["test"])
+	cache.refresh(loader, cache.items.values["test"])
+	releaseCh <- true
+
+	assert.Eventually(t, func() bool {
+		cache.refreshes.mu.Lock()
+		defer cache.refreshes.mu.Unlock()
+
+		return len(cache.refreshes.keys) == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, loadCalls)
+	assert.Equal(t, "refreshed", cache.Get("test").Value())
+
+	// failed refresh keeps the old value
+	cache.refresh(loader, cache.items.values["test"])
+	releaseCh <- false
+
+	assert.Equal(t, "test", <-failed)
+	assert.Equal(t, "refreshed", cache.Get("test").Value())
+	assert.Equal(t, 2, loadCalls)
+	assert.Equal(t, 1, cache.items.values["test"].refreshFailures)
+	assert.False(t, cache.items.values["test"].canRefresh())
+
+	cache.metricsMu.RLock()
+	assert.Equal(t, uint64(2), cache.metrics.Refreshes)
+	assert.Equal(t, uint64(1), cache.metrics.RefreshErrors)
+	assert.Equal(t, uint64(1), cache.metrics.LoadErrors)
+	cache.metricsMu.RUnlock()
+
+	// failed items are not refreshed by Get until their backoff ends
+	cache.options.refreshAfter = time.Millisecond
+	cache.options.loader = loader
+	time.Sleep(time.Millisecond * 2)
+
+	assert.NotNil(t, cache.Get("test"))
+	assert.Never(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls > 2
+	}, time.Millisecond*20, time.Millisecond)
+
+	// old items are refreshed by Get
+	cache.items.values["test"].refreshRetryAt = time.Now()
+
+	item := cache.Get("test")
+	require.NotNil(t, item)
+	assert.Equal(t, "refreshed", item.Value())
+	releaseCh <- true
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls == 3
+	}, time.Second, time.Millisecond)
+
+	// fresh items are not refreshed
+	cache.options.refreshAfter = time.Hour
+	assert.NotNil(t, cache.Get("test"))
+	assert.Never(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls > 3
+	}, time.Millisecond*20, time.Millisecond)
+}
+
+func Test_Cache_Get_stale(t *testing.T) {
+	var (
+		loadCh  = make(chan error)
+		errLoad = errors.New("error")
+	)
+
+	cache := New[string, string](
+		WithGracePeriod[string, string](time.Hour),
+		WithLoader[string, string](LoaderWithErrorFunc[string, string](func(c *Cache[string, string], key string) (*Item[string, string], error) {
+			if err := <-loadCh; err != nil {
+				return nil, err
+			}
+
+			return c.Set(key, "fresh", time.Hour), nil
+		})),
+	)
+
+	cache.Set("test", "stale", time.Millisecond)
+	time.Sleep(time.Millisecond * 2) // force expiration
+
+	// failed revalidation keeps the stale item
+	item := cache.Get("test")
+	require.NotNil(t, item)
+	assert.Equal(t, "stale", item.Value())
+	assert.True(t, item.IsExpired())
+	loadCh <- errLoad
+
+	assert.Eventually(t, func() bool {
+		cache.refreshes.mu.Lock()
+		defer cache.refreshes.mu.Unlock()
+
+		return len(cache.refreshes.keys) == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(1), cache.Metrics().RefreshErrors)
+
+	// failed revalidation is not retried right away
+	item = cache.Get("test")
+	require.NotNil(t, item)
+	assert.Equal(t, "stale", item.Value())
+	assert.Equal(t, uint64(1), cache.Metrics().Refreshes)
+
+	// successful revalidation replaces the stale item
+	item.mu.Lock()
+	item.refreshRetryAt = time.Time{}
+	item.mu.Unlock()
+
+	assert.NotNil(t, cache.Get("test"))
+	loadCh <- nil
+
+	assert.Eventually(t, func() bool {
+		cache.refreshes.mu.Lock()
+		defer cache.refreshes.mu.Unlock()
+
+		return len(cache.refreshes.keys) == 0
+	}, time.Second, time.Millisecond)
+
+	item = cache.Get("test")
+	require.NotNil(t, item)
+	assert.Equal(t, "fresh", item.Value())
+	assert.False(t, item.IsExpired())
+
+	m := cache.Metrics()
+	assert.Equal(t, uint64(3), m.StaleHits)
+	assert.Equal(t, uint64(2), m.Refreshes)
+
+	// stale items are replaced by GetOrSet
+	cache.Set("test2", "stale", time.Millisecond)
+	time.Sleep(time.Millisecond * 2) // force expiration
+
+	item, retrieved := cache.GetOrSet("test2", "new")
+	assert.False(t, retrieved)
+	assert.Equal(t, "new", item.Value())
+}
+
+func Test_Cache_Get_negative(t *testing.T) {
+	var calls int
+
+	cache := New[string, string](
+		WithNegativeTTL[string, string](time.Hour),
+		WithLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+			calls++
+			return nil
+		})),
+	)
+
+	// the first miss calls the loader
+	item, err := cache.GetE("test")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Equal(t, 1, calls)
+
+	// subsequent misses do not
+	item, err = cache.GetE("test")
+	assert.Equal(t, ErrKnownAbsent, err)
+	assert.Nil(t, item)
+	assert.Nil(t, cache.Get("test"))
+	assert.Equal(t, 1, calls)
+
+	// absent keys are not items
+	assert.Zero(t, cache.Len())
+	assert.Empty(t, cache.Keys())
+	assert.False(t, cache.Has("test"))
+
+	m := cache.Metrics()
+	assert.Equal(t, uint64(1), m.NegativeInsertions)
+	assert.Equal(t, uint64(2), m.NegativeHits)
+	assert.Equal(t, uint64(3), m.Misses)
+
+	// setting an item removes its absence mark
+	cache.Set("test", "value", DefaultTTL)
+	assert.Zero(t, cache.items.absent.len())
+	cache.Delete("test")
+
+	item, err = cache.GetE("test")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Equal(t, 2, calls)
+
+	// all absence marks are removed with the items
+	cache.DeleteAll()
+	assert.Zero(t, cache.items.absent.len())
+
+	// expired absence marks do not prevent loading
+	cache.items.absent.ttl = time.Millisecond
+	cache.Get("test")
+	time.Sleep(time.Millisecond * 2) // force expiration
+	cache.Get("test")
+	assert.Equal(t, 4, calls)
+
+	cache.DeleteExpired()
+	time.Sleep(time.Millisecond * 2) // force expiration
+	cache.DeleteExpired()
+	assert.Zero(t, cache.items.absent.len())
+
+	// failed loads are not remembered
+	cache.options.loader = LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errors.New("error")
+	})
+	cache.items.absent.ttl = time.Hour
+
+	_, err = cache.GetE("error")
+	assert.Error(t, err)
+	assert.Zero(t, cache.items.absent.len())
+}
+
+func Test_Cache_Delete(t *testing.T) {
+	var fnsCalls int
+
+	cache := prepCache(time.Hour, "1", "2", "3", "4")
+	cache.events.eviction.fns[1] = func(r EvictionReason, item *Item[string, string]) {
+		assert.Equal(t, EvictionReasonDeleted, r)
+		fnsCalls++
+	}
+	cache.events.eviction.fns[2] = cache.events.eviction.fns[1]
+
+	// not found
+	cache.Delete("1234")
+	assert.Zero(t, fnsCalls)
+	assert.Len(t, cache.items.values, 4)
+
+	// success
+	cache.Delete("1")
+	assert.Equal(t, 2, fnsCalls)
+	assert.Len(t, cache.items.values, 3)
+	assert.NotContains(t, cache.items.values, "1")
+}
+
+func Test_Cache_Has(t *testing.T) {
+	cc := map[string]struct {
+		keys      []string
+		searchKey string
+		has       bool
+	}{
+		"Empty cache": {
+			keys:      []string{},
+			searchKey: "key1",
+			has:       false,
+		},
+		"Key exists": {
+			keys:      []string{"key1", "key2", "key3"},
+			searchKey: "key2",
+			has:       true,
+		},
+		"Key doesn't exist": {
+			keys:      []string{"key1", "key2", "key3"},
+			searchKey: "key4",
+			has:       false,
+		},
+	}
+
+	for name, tc := range cc {
+		t.Run(name, func(t *testing.T) {
+			c := prepCache(NoTTL, tc.keys...)
+			has := c.Has(tc.searchKey)
+			assert.Equal(t, tc.has, has)
+		})
+	}
+}
+
+func Test_Cache_GetOrSet(t *testing.T) {
+	cache := prepCache(time.Hour)
+	item, retrieved := cache.GetOrSet("test", "1", WithTTL[string, string](time.Minute))
+	require.NotNil(t, item)
+	assert.Same(t, item, cache.items.values["test"])
+	assert.False(t, retrieved)
+
+	item, retrieved = cache.GetOrSet("test", "1", WithTTL[string, string](time.Minute))
+	require.NotNil(t, item)
+	assert.Same(t, item, cache.items.values["test"])
+	assert.True(t, retrieved)
+
+	item, retrieved = cache.GetOrSet("test2", "1", WithTTL[string, string](time.Microsecond))
+	require.NotNil(t, item)
+	assert.Same(t, item, cache.items.values["test2"])
+	assert.False(t, retrieved)
+
+	time.Sleep(time.Millisecond)
+	item, retrieved = cache.GetOrSet("test2", "2", WithTTL[string, string](time.Minute))
 	require.NotNil(t, item)
-	assert.Same(t, item, cache.items.values["test2"].Value)
+	assert.Same(t, item, cache.items.values["test2"])
 	assert.False(t, retrieved)
 }
 
 func Test_Cache_GetAndDelete(t *testing.T) {
 	cache := prepCache(time.Hour, "test1", "test2", "test3")
-	listItem := cache.items.lru.Front()
+	listItem := lruFront(cache)
 	require.NotNil(t, listItem)
 	assert.Same(t, listItem, cache.items.values["test3"])
 
@@ -760,25 +1990,94 @@ func Test_Cache_DeleteExpired(t *testing.T) {
 	assert.NotContains(t, cache.items.values, "6")
 	assert.Equal(t, 2, key1FnsCalls)
 	assert.Equal(t, 2, key2FnsCalls)
-}
 
-func Test_Cache_Touch(t *testing.T) {
-	cache := prepCache(time.Hour, "1", "2")
-	oldExpiresAt := cache.items.values["1"].Value.(*Item[string, string]).expiresAt
+	// buffered accesses are applied first
+	cache.items.accessBuf = newAccessBuffer[string, string](1)
+	addToCache(cache, time.Millisecond*20, "7")
+	addToCache(cache, time.Millisecond*5, "8")
+	cache.items.accessBuf.batches <- []access[string, string]{
+		{item: cache.items.values["7"], touch: true},
+		{item: cache.items.values["8"], touch: true},
+	}
+	time.Sleep(time.Millisecond * 10)
 
-	cache.Touch("1")
+	cache.DeleteExpired()
+	assert.Contains(t, cache.items.values, "7")
+	assert.NotContains(t, cache.items.values, "8")
+
+	// timing wheel
+	cache = prepCache(time.Hour)
+	cache.items.wheel = newTimingWheel[string, string](time.Millisecond)
+	addToCache(cache, time.Hour, "1", "2")
+	addToCache(cache, time.Millisecond, "3")
+	addToCache(cache, time.Millisecond, "4")
+	addToCache(cache, NoTTL, "5")
+	time.Sleep(time.Millisecond * 5) // force expiration
 
-	newExpiresAt := cache.items.values["1"].Value.(*Item[string, string]).expiresAt
-	assert.True(t, newExpiresAt.After(oldExpiresAt))
-	assert.Equal(t, "1", cache.items.lru.Front().Value.(*Item[string, string]).key)
-}
+	cache.DeleteExpired()
+	assert.Len(t, cache.items.values, 3)
+	assert.NotContains(t, cache.items.values, "3")
+	assert.NotContains(t, cache.items.values, "4")
+	assert.Equal(t, 2, cache.items.wheel.len)
 
-func Test_Cache_Len(t *testing.T) {
-	cache := prepCache(time.Hour, "1", "2")
-	assert.Equal(t, 2, cache.Len())
-}
+	// grace period
+	cache = prepCache(time.Hour)
+	cache.options.gracePeriod = time.Millisecond * 20
+	addToCache(cache, time.Millisecond, "1")
+	addToCache(cache, time.Hour, "2")
+	time.Sleep(time.Millisecond * 5) // force expiration
 
-func Test_Cache_Keys(t *testing.T) {
+	cache.DeleteExpired()
+	assert.Contains(t, cache.items.values, "1")
+
+	time.Sleep(time.Millisecond * 20)
+
+	cache.DeleteExpired()
+	assert.NotContains(t, cache.items.values, "1")
+	assert.Contains(t, cache.items.values, "2")
+
+	// grace period with timing wheel
+	cache = prepCache(time.Hour)
+	cache.options.gracePeriod = time.Millisecond * 20
+	cache.items.wheel = newTimingWheel[string, string](time.Millisecond)
+	addToCache(cache, time.Millisecond, "1")
+	addToCache(cache, time.Hour, "2")
+	time.Sleep(time.Millisecond * 5) // force expiration
+
+	cache.DeleteExpired()
+	assert.Contains(t, cache.items.values, "1")
+
+	time.Sleep(time.Millisecond * 20)
+
+	cache.DeleteExpired()
+	assert.NotContains(t, cache.items.values, "1")
+	assert.Contains(t, cache.items.values, "2")
+}
+
+func Test_Cache_Touch(t *testing.T) {
+	cache := prepCache(time.Hour, "1", "2")
+	oldExpiresAt := cache.items.values["1"].expiresAt
+
+	cache.Touch("1")
+
+	newExpiresAt := cache.items.values["1"].expiresAt
+	assert.True(t, newExpiresAt.After(oldExpiresAt))
+	assert.Equal(t, "1", lruFront(cache).key)
+}
+
+func Test_Cache_Len(t *testing.T) {
+	cache := prepCache(time.Hour, "1", "2")
+	assert.Equal(t, 2, cache.Len())
+}
+
+func Test_Cache_Cost(t *testing.T) {
+	cache := prepCache(time.Hour)
+	cache.Set("1", "1", NoTTL)
+	cache.SetWithCost("2", "2", NoTTL, 4)
+	assert.Equal(t, uint64(5), cache.Cost())
+}
+
+func Test_Cache_Keys(t *testing.T) {
 	cache := prepCache(time.Hour, "1", "2", "3")
 	assert.ElementsMatch(t, []string{"1", "2", "3"}, cache.Keys())
 }
@@ -794,6 +2093,20 @@ func Test_Cache_Items(t *testing.T) {
 	assert.Equal(t, "2", items["2"].key)
 	require.Contains(t, items, "3")
 	assert.Equal(t, "3", items["3"].key)
+
+	// accesses are not recorded and expired items are skipped
+	p := &accessRecordingPolicy{SIEVEPolicy: NewSIEVEPolicy[string, string]()}
+	cache = prepCache(time.Hour)
+	cache.items.policy = p
+	addToCache(cache, time.Hour, "1")
+	addToCache(cache, time.Nanosecond, "2")
+	time.Sleep(time.Millisecond) // force expiration
+
+	items = cache.Items()
+	assert.Len(t, items, 1)
+	assert.Contains(t, items, "1")
+	assert.Zero(t, p.accesses)
+	assert.Zero(t, p.concurrentAccesses)
 }
 
 func Test_Cache_Metrics(t *testing.T) {
@@ -840,6 +2153,26 @@ func Test_Cache_Start(t *testing.T) {
 	cache.events.eviction.fns[1] = fn
 
 	cache.Start()
+	// timing wheel
+	cache = New[string, string](
+		WithTimingWheel[string, string](time.Millisecond),
+	)
+	cache.Set("1", "value", time.Millisecond*5)
+	cache.Set("2", "value", NoTTL)
+
+	done := make(chan struct{})
+	go func() {
+		cache.Start()
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return !cache.Has("1")
+	}, time.Second, time.Millisecond)
+	assert.True(t, cache.Has("2"))
+
+	cache.Stop()
+	<-done
 }
 
 func Test_Cache_Stop(t *testing.T) {
@@ -1018,168 +2351,1589 @@ func Test_Cache_OnEviction(t *testing.T) {
 	assert.NotContains(t, cache.events.eviction.fns, uint64(1))
 }
 
-func Test_Cache_Range(t *testing.T) {
-	c := prepCache(DefaultTTL, "1", "2", "3", "4", "5")
-	var results []string
-
-	c.Range(func(item *Item[string, string]) bool {
-		results = append(results, item.Key())
-		return item.Key() != "4"
+func Test_Cache_OnRefreshFailure(t *testing.T) {
+	checkCh := make(chan struct{})
+	resCh := make(chan struct{})
+	cache := prepCache(time.Hour)
+	del1 := cache.OnRefreshFailure(func(_ context.Context, _ string, _ error) {
+		checkCh <- struct{}{}
+	})
+	del2 := cache.OnRefreshFailure(func(_ context.Context, _ string, _ error) {
+		checkCh <- struct{}{}
 	})
 
-	assert.Equal(t, []string{"5", "4"}, results)
-}
+	require.Len(t, cache.events.refreshFailure.fns, 2)
+	assert.Equal(t, uint64(2), cache.events.refreshFailure.nextID)
 
-func Test_LoaderFunc_Load(t *testing.T) {
-	var called bool
+	cache.events.refreshFailure.fns[0]("", nil)
 
-	fn := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
-		called = true
-		return nil
-	})
+	go func() {
+		del1()
+		resCh <- struct{}{}
+	}()
+	assert.Never(t, func() bool {
+		select {
+		case <-resCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*200, time.Millisecond*100)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-checkCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*500, time.Millisecond*250)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-resCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*500, time.Millisecond*250)
 
-	assert.Nil(t, fn(nil, ""))
-	assert.True(t, called)
+	require.Len(t, cache.events.refreshFailure.fns, 1)
+	assert.NotContains(t, cache.events.refreshFailure.fns, uint64(0))
+	assert.Contains(t, cache.events.refreshFailure.fns, uint64(1))
+
+	cache.events.refreshFailure.fns[1]("", nil)
+
+	go func() {
+		del2()
+		resCh <- struct{}{}
+	}()
+	assert.Never(t, func() bool {
+		select {
+		case <-resCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*200, time.Millisecond*100)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-checkCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*500, time.Millisecond*250)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-resCh:
+			return true
+		default:
+			return false
+		}
+	}, time.Millisecond*500, time.Millisecond*250)
+
+	assert.Empty(t, cache.events.refreshFailure.fns)
+	assert.NotContains(t, cache.events.refreshFailure.fns, uint64(1))
 }
 
-func Test_NewSuppressedLoader(t *testing.T) {
-	var called bool
+func Test_Cache_Save(t *testing.T) {
+	var buf bytes.Buffer
 
-	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
-		called = true
-		return nil
-	})
+	cache := prepCache(time.Hour, "1", "2", "3")
+	cache.items.values["2"].expiresAt = time.Now().Add(-time.Minute)
+
+	require.NoError(t, cache.Save(&buf))
+
+	res, err := decodeSnapshotItems(&buf, GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "1", res[0].Key)
+	assert.Equal(t, "3", res[1].Key)
+
+	// custom codec
+	codec := &recordingSnapshotCodec{}
+
+	require.NoError(t, cache.Save(&buf, WithSnapshotCodec[string, string](codec)))
+	assert.Len(t, codec.items, 2)
+
+	cache.options.snapshotCodec = codec
+	require.NoError(t, cache.Save(&buf))
+	assert.Len(t, codec.items, 4)
+	// key and value codecs
+	buf.Reset()
+	cache.options.snapshotCodec = nil
+
+	require.NoError(t, cache.Save(&buf,
+		WithKeyCodec[string, string](BinaryCodec[string]{}),
+		WithValueCodec[string, string](BinaryCodec[string]{}),
+	))
+
+	res, err = decodeSnapshotItems(&buf, NewSnapshotCodec[string, string](BinaryCodec[string]{}, BinaryCodec[string]{}))
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "1", res[0].Key)
+	assert.Equal(t, "3", res[1].Key)
+}
 
-	// uses the provided loader and group parameters
-	group := &singleflight.Group{}
+func Test_Cache_SaveSince(t *testing.T) {
+	var base, delta bytes.Buffer
 
-	sl := NewSuppressedLoader[string, string](loader, group)
-	require.NotNil(t, sl)
-	require.NotNil(t, sl.loader)
+	cache := New[string, string](
+		WithTTL[string, string](time.Hour),
+		WithVersion[string, string](true),
+	)
+	cache.Set("1", "value1", DefaultTTL)
+	cache.Set("2", "value2", DefaultTTL)
+	cache.Set("3", "value3", DefaultTTL)
+
+	// the zero token writes all items
+	token0, err := cache.SaveSince(&base, SnapshotToken{})
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotToken{Epoch: cache.epoch, Seq: cache.items.deltaSeq}, token0)
+
+	res, err := decodeSnapshotItems(bytes.NewReader(base.Bytes()), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	assert.Len(t, res, 3)
+
+	// only the changes are written
+	cache.Set("2", "value2b", DefaultTTL)
+	cache.Delete("3")
+	cache.Set("4", "value4", DefaultTTL)
+
+	token1, err := cache.SaveSince(&delta, token0)
+	require.NoError(t, err)
+	assert.Greater(t, token1.Seq, token0.Seq)
+
+	var keys []string
+
+	dec := GobSnapshotCodec[string, string]{}.NewDecoder(bytes.NewReader(delta.Bytes()))
+	for {
+		item, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
 
-	sl.loader.Load(nil, "")
+		require.NoError(t, err)
+		keys = append(keys, fmt.Sprint(item.Key, item.Deleted))
+	}
 
-	assert.True(t, called)
-	assert.Equal(t, group, sl.group)
+	assert.Equal(t, []string{"3true", "2false", "4false"}, keys)
 
-	// uses the provided loader and automatically creates a new instance
-	// of *singleflight.Group as nil parameter is passed
-	called = false
+	// the chain restores the current state
+	dst := New[string, string](WithVersion[string, string](true))
+	require.NoError(t, dst.LoadChain([]io.Reader{&base, &delta}))
+	assert.ElementsMatch(t, []string{"1", "2", "4"}, dst.Keys())
+	assert.Equal(t, "value2b", dst.Get("2").Value())
+	assert.Equal(t, int64(1), dst.Get("2").Version())
 
-	sl = NewSuppressedLoader[string, string](loader, nil)
-	require.NotNil(t, sl)
-	require.NotNil(t, sl.loader)
+	// tokens older than the one of the latest call are unavailable
+	token, err := cache.SaveSince(io.Discard, token0)
+	assert.Equal(t, ErrDeltaUnavailable, err)
+	assert.Equal(t, token0, token)
 
-	sl.loader.Load(nil, "")
+	// unchanged items are not written again, while touched ones are
+	codec := &recordingSnapshotCodec{}
+	cache.Touch("1")
 
-	assert.True(t, called)
-	assert.NotNil(t, group, sl.group)
-}
+	token2, err := cache.SaveSince(io.Discard, token1, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	require.Len(t, codec.items, 1)
+	assert.Equal(t, "1", codec.items[0].Key)
 
-func Test_SuppressedLoader_Load(t *testing.T) {
-	var (
-		mu        sync.Mutex
-		loadCalls int
-		releaseCh = make(chan struct{})
-		res       *Item[string, string]
-	)
+	// failed writes keep the token valid and the changes unsaved
+	errWrite := errors.New("error")
+	cache.Set("5", "value5", DefaultTTL)
+	cache.Delete("4")
 
-	l := SuppressedLoader[string, string]{
-		loader: LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
-			mu.Lock()
-			loadCalls++
-			mu.Unlock()
+	token, err = cache.SaveSince(failingWriter{err: errWrite}, token2)
+	assert.Equal(t, errWrite, err)
+	assert.Equal(t, token2, token)
 
-			<-releaseCh
+	codec = &recordingSnapshotCodec{}
 
-			if res == nil {
-				return nil
-			}
+	_, err = cache.SaveSince(io.Discard, token2, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	require.Len(t, codec.items, 2)
+	assert.Equal(t, SnapshotItem[string, string]{Key: "4", Deleted: true}, codec.items[0])
+	assert.Equal(t, "5", codec.items[1].Key)
 
-			res1 := *res
+	// custom codec
+	codec = &recordingSnapshotCodec{}
 
-			return &res1
-		}),
-		group: &singleflight.Group{},
+	_, err = cache.SaveSince(io.Discard, SnapshotToken{}, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	assert.Len(t, codec.items, 3)
+}
+
+func Test_Cache_delta(t *testing.T) {
+	cache := New[string, string](WithVersion[string, string](true))
+	cache.Set("1", "value1", NoTTL)
+	cache.Set("2", "value2", NoTTL)
+
+	// deltas are unavailable before the first snapshot
+	_, ok := cache.delta(false)
+	assert.False(t, ok)
+
+	d, ok := cache.delta(true)
+	require.True(t, ok)
+	assert.Len(t, d.items, 2)
+	assert.Len(t, d.saved, 2)
+	assert.NotNil(t, cache.items.deleted)
+	assert.Equal(t, uint64(1), cache.commitDelta(d))
+	assert.Equal(t, uint64(1), cache.items.deltaSeq)
+	assert.False(t, cache.items.values["1"].changedSinceSave())
+
+	// deletions and changed items are selected
+	cache.Delete("1")
+	cache.Delete("2")
+	cache.Set("2", "value2", NoTTL)
+	cache.Set("3", "value3", NoTTL)
+	assert.Len(t, cache.items.deleted, 1)
+
+	d, ok = cache.delta(false)
+	require.True(t, ok)
+	require.Len(t, d.items, 3)
+	assert.Equal(t, SnapshotItem[string, string]{Key: "1", Deleted: true}, d.items[0])
+	assert.Equal(t, "2", d.items[1].Key)
+	assert.Equal(t, "3", d.items[2].Key)
+	assert.Empty(t, cache.items.deleted)
+
+	// aborted deltas track their deletions again, unless the keys
+	// were set since
+	cache.Set("1", "value1", NoTTL)
+	cache.Delete("1")
+	cache.abortDelta(d)
+	assert.Equal(t, map[string]struct{}{"1": {}}, cache.items.deleted)
+
+	cache.Set("1", "value1", NoTTL)
+	cache.abortDelta(d)
+	assert.Empty(t, cache.items.deleted)
+
+	// committed deltas mark their items as saved
+	d, ok = cache.delta(false)
+	require.True(t, ok)
+	require.Len(t, d.items, 3)
+	assert.Equal(t, uint64(2), cache.commitDelta(d))
+	assert.Equal(t, uint64(2), cache.items.deltaSeq)
+
+	for _, item := range cache.items.values {
+		assert.False(t, item.changedSinceSave())
 	}
 
+	// items that were changed during the write are selected again
+	cache.Set("1", "value1b", NoTTL)
+
+	d, ok = cache.delta(false)
+	require.True(t, ok)
+	require.Len(t, d.items, 1)
+	cache.Set("1", "value1c", NoTTL)
+	cache.commitDelta(d)
+	assert.True(t, cache.items.values["1"].changedSinceSave())
+
+	// DeleteAll makes deltas unavailable
+	cache.DeleteAll()
+	assert.Zero(t, cache.items.deltaSeq)
+
+	_, ok = cache.delta(false)
+	assert.False(t, ok)
+
+	// version tracking disabled
+	cache = New[string, string]()
+	cache.Set("1", "value1", NoTTL)
+
+	d, ok = cache.delta(true)
+	require.True(t, ok)
+	assert.Len(t, d.items, 1)
+	assert.Empty(t, d.saved)
+	assert.Nil(t, cache.items.deleted)
+	cache.commitDelta(d)
+	assert.Zero(t, cache.items.deltaSeq)
+
+	_, ok = cache.delta(false)
+	assert.False(t, ok)
+}
+
+func Test_Cache_LoadChain(t *testing.T) {
+	var base, delta bytes.Buffer
+
+	require.NoError(t, encodeSnapshot[string, string](&base, GobSnapshotCodec[string, string]{}, []SnapshotItem[string, string]{
+		{Key: "1", Value: "value1", TTL: NoTTL},
+		{Key: "2", Value: "value2", TTL: NoTTL},
+		{Key: "3", Value: "value3", TTL: NoTTL},
+	}))
+
+	require.NoError(t, encodeSnapshot[string, string](&delta, GobSnapshotCodec[string, string]{}, []SnapshotItem[string, string]{
+		{Key: "1", Deleted: true},
+		{Key: "2", Value: "value2", TTL: time.Hour, ExpiresAt: time.Now().Add(-time.Minute)},
+		{Key: "4", Value: "value4", TTL: NoTTL},
+	}))
+
+	// deleted and expired items of the chain remove the existing ones
+	cache := prepCache(time.Hour, "1", "2", "5")
+
+	require.NoError(t, cache.LoadChain([]io.Reader{bytes.NewReader(base.Bytes()), bytes.NewReader(delta.Bytes())}))
+	assert.ElementsMatch(t, []string{"3", "4", "5"}, cache.Keys())
+
+	// invalid link
+	cache = New[string, string]()
+
+	err := cache.LoadChain([]io.Reader{bytes.NewReader(base.Bytes()), bytes.NewReader(delta.Bytes()[:delta.Len()-1])})
+	assert.Error(t, err)
+	assert.Zero(t, cache.Len())
+}
+
+func Test_Cache_Load(t *testing.T) {
 	var (
-		wg           sync.WaitGroup
-		item1, item2 *Item[string, string]
+		buf        bytes.Buffer
+		mu         sync.Mutex
+		insertions int
 	)
 
-	cache := prepCache(time.Hour)
-
-	// nil result
-	wg.Add(2)
+	onInsertion := func(_ context.Context, _ *Item[string, string]) {
+		mu.Lock()
+		insertions++
+		mu.Unlock()
+	}
 
-	go func() {
-		item1 = l.Load(cache, "test")
-		wg.Done()
-	}()
+	src := New[string, string](
+		WithTTL[string, string](time.Hour),
+		WithVersion[string, string](true),
+	)
+	src.Set("1", "value1", DefaultTTL)
+	src.Set("2", "value2", NoTTL)
+	src.Set("3", "value3", time.Minute)
+	src.Set("3", "value3", time.Minute)
+	src.Get("1")
+	src.SetWithCost("4", "value4", DefaultTTL, 5)
+
+	require.NoError(t, src.Save(&buf))
+	snapshot := buf.Bytes()
+
+	// all items are restored with their data and recency
+	dst := New[string, string](
+		WithTTL[string, string](time.Minute),
+		WithVersion[string, string](true),
+	)
+	del := dst.OnInsertion(onInsertion)
+
+	require.NoError(t, dst.Load(bytes.NewReader(snapshot)))
+	del()
+	require.Equal(t, 4, dst.Len())
+	assert.Equal(t, 4, insertions)
+	assert.Equal(t, []string{"4", "1", "3", "2"}, lruKeys(dst))
+
+	for key, srcItem := range src.Items() {
+		item := dst.Get(key, WithDisableTouchOnHit[string, string]())
+		require.NotNil(t, item)
+		assert.Equal(t, srcItem.Value(), item.Value())
+		assert.Equal(t, srcItem.Version(), item.Version())
+		assert.Equal(t, srcItem.Cost(), item.Cost())
+		assert.True(t, srcItem.ExpiresAt().Equal(item.ExpiresAt()))
+
+		if srcItem.TTL() > 0 {
+			assert.Equal(t, srcItem.TTL(), item.TTL())
+		} else {
+			assert.Equal(t, NoTTL, item.TTL())
+		}
+	}
 
-	go func() {
-		item2 = l.Load(cache, "test")
-		wg.Done()
-	}()
+	assert.Equal(t, int64(1), dst.Get("3").Version())
+	assert.Equal(t, "3", dst.items.expQueue[0].key)
 
-	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
-	releaseCh <- struct{}{}
+	// capacity is respected and the least recently used items are
+	// evicted
+	dst = New[string, string](WithCapacity[string, string](2))
+	del = dst.OnInsertion(onInsertion)
 
-	wg.Wait()
-	require.Nil(t, item1)
-	require.Nil(t, item2)
-	assert.Equal(t, 1, loadCalls)
+	insertions = 0
 
-	// non nil result
-	res = &Item[string, string]{key: "test"}
-	loadCalls = 0
-	wg.Add(2)
+	require.NoError(t, dst.Load(bytes.NewReader(snapshot), WithDisableLoadEvents[string, string]()))
+	del()
+	assert.Equal(t, []string{"4", "1"}, lruKeys(dst))
+	assert.Zero(t, insertions)
 
-	go func() {
-		item1 = l.Load(cache, "test")
-		wg.Done()
-	}()
+	// events are disabled by the cache options
+	dst = New[string, string](WithDisableLoadEvents[string, string]())
+	del = dst.OnInsertion(onInsertion)
 
-	go func() {
-		item2 = l.Load(cache, "test")
-		wg.Done()
-	}()
+	require.NoError(t, dst.Load(bytes.NewReader(snapshot)))
+	del()
+	assert.Equal(t, 4, dst.Len())
+	assert.Zero(t, insertions)
 
-	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
-	releaseCh <- struct{}{}
+	// invalid snapshot
+	dst = New[string, string]()
 
-	wg.Wait()
-	require.Same(t, item1, item2)
-	assert.Equal(t, "test", item1.key)
-	assert.Equal(t, 1, loadCalls)
+	err := dst.Load(bytes.NewReader(snapshot[:len(snapshot)-1]))
+	assert.Error(t, err)
+	assert.Zero(t, dst.Len())
 }
 
-func prepCache(ttl time.Duration, keys ...string) *Cache[string, string] {
-	c := &Cache[string, string]{}
-	c.options.ttl = ttl
-	c.items.values = make(map[string]*list.Element)
-	c.items.lru = list.New()
-	c.items.expQueue = newExpirationQueue[string, string]()
-	c.items.timerCh = make(chan time.Duration, 1)
-	c.events.eviction.fns = make(map[uint64]func(EvictionReason, *Item[string, string]))
-	c.events.insertion.fns = make(map[uint64]func(*Item[string, string]))
+func Test_Cache_snapshot(t *testing.T) {
+	cache := prepCache(time.Hour, "1", "2", "3")
+	cache.items.values["2"].expiresAt = time.Now().Add(-time.Minute)
 
-	addToCache(c, ttl, keys...)
+	items := cache.snapshot(nil)
+	require.Len(t, items, 2)
+	assert.Equal(t, "1", items[0].Key)
+	assert.Equal(t, "value of1", items[0].Value)
+	assert.Equal(t, "3", items[1].Key)
 
-	return c
+	// unordered policy
+	cache.items.policy = NewSIEVEPolicy[string, string]()
+	cache.items.values["2"].expiresAt = time.Now().Add(time.Minute)
+
+	items = cache.snapshot(nil)
+	assert.Len(t, items, 3)
 }
 
-func addToCache(c *Cache[string, string], ttl time.Duration, keys ...string) {
-	for i, key := range keys {
-		item := newItem(
-			key,
-			fmt.Sprint("value of", key),
-			ttl+time.Duration(i)*time.Minute,
-			false,
-		)
-		elem := c.items.lru.PushFront(item)
-		c.items.values[key] = elem
-		c.items.expQueue.push(elem)
+func Test_Cache_restore(t *testing.T) {
+	var insertions []string
+
+	cache := prepCache(time.Hour, "1")
+	cache.options.maxCost = 10
+	cache.events.insertion.fns[0] = func(item *Item[string, string]) {
+		insertions = append(insertions, item.key)
 	}
+
+	expiresAt := time.Now().Add(time.Minute)
+
+	cache.restore(nil, []SnapshotItem[string, string]{
+		{Key: "1", Value: "restored", TTL: time.Hour, ExpiresAt: expiresAt, Version: 5, Cost: 1},
+		{Key: "2", Value: "value", Cost: 2},
+		{Key: "3", Value: "costly", Cost: 20},
+	}, true)
+
+	require.Len(t, cache.items.values, 2)
+	assert.Equal(t, []string{"2"}, insertions)
+
+	item := cache.items.values["1"]
+	assert.Equal(t, "restored", item.value)
+	assert.Equal(t, expiresAt, item.expiresAt)
+	assert.Equal(t, int64(-1), item.version)
+	assert.Same(t, item, cache.items.expQueue[0])
+
+	item = cache.items.values["2"]
+	assert.Equal(t, NoTTL, item.ttl)
+	assert.Zero(t, item.expiresAt)
+	assert.Equal(t, uint64(2), item.cost)
+
+	// events are not fired
+	cache.restore(nil, []SnapshotItem[string, string]{{Key: "4"}}, false)
+	assert.Len(t, cache.items.values, 3)
+	assert.Equal(t, []string{"2"}, insertions)
+
+	// costs are recalculated with the cost function
+	cache.options.costFunc = func(_ string, value string) uint64 {
+		return uint64(len(value))
+	}
+
+	cache.restore(nil, []SnapshotItem[string, string]{
+		{Key: "5", Value: "cheap", Cost: 20},
+		{Key: "6", Value: "far too costly", Cost: 1},
+	}, false)
+	assert.Equal(t, uint64(5), cache.items.values["5"].cost)
+	assert.NotContains(t, cache.items.values, "6")
+}
+
+func Test_Cache_logWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open[string, string](WALConfig{
+		Dir:                 dir,
+		SyncPolicy:          WALSyncNever,
+		CompactionThreshold: 100,
+	})
+	require.NoError(t, err)
+
+	// no log
+	New[string, string]().logWAL(walOpClear, nil)
+
+	// background compaction
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprint(i), "value", NoTTL)
+	}
+
+	require.NoError(t, c.Close())
+
+	snapshots, err := filepath.Glob(filepath.Join(dir, "*"+walSnapshotExt))
+	require.NoError(t, err)
+	assert.NotEmpty(t, snapshots)
+
+	// all items are recovered from the snapshot and the log
+	c, err = Open[string, string](WALConfig{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 10, c.Len())
+	require.NoError(t, c.Close())
+}
+
+func Test_Cache_compactWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open[string, string](WALConfig{Dir: dir})
+	require.NoError(t, err)
+
+	c.Set("1", "value1", NoTTL)
+	c.Set("2", "value2", NoTTL)
+
+	w := c.items.wal
+	require.NoError(t, c.compactWAL(w))
+	assert.FileExists(t, w.path(2, walSnapshotExt))
+	assert.NoFileExists(t, w.path(1, walSegmentExt))
+
+	c.Delete("1")
+	require.NoError(t, c.Close())
+
+	c, err = Open[string, string](WALConfig{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, c.Keys())
+	require.NoError(t, c.Close())
+
+	// closed log
+	assert.Equal(t, ErrWALClosed, c.compactWAL(w))
+}
+
+func Test_Cache_Sync(t *testing.T) {
+	c, err := Open[string, string](WALConfig{Dir: t.TempDir(), SyncPolicy: WALSyncNever})
+	require.NoError(t, err)
+
+	c.Set("1", "value1", NoTTL)
+	require.NoError(t, c.Sync())
+	assert.False(t, c.items.wal.dirty)
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, ErrWALClosed, c.Sync())
+	assert.Equal(t, ErrWALClosed, New[string, string]().Sync())
+}
+
+func Test_Cache_Compact(t *testing.T) {
+	c, err := Open[string, string](WALConfig{Dir: t.TempDir(), CompactionThreshold: -1})
+	require.NoError(t, err)
+
+	c.Set("1", "value1", NoTTL)
+	require.NoError(t, c.Compact())
+	assert.FileExists(t, c.items.wal.path(2, walSnapshotExt))
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, ErrWALClosed, c.Compact())
+	assert.Equal(t, ErrWALClosed, New[string, string]().Compact())
+}
+
+func Test_Cache_Close(t *testing.T) {
+	c, err := Open[string, string](WALConfig{Dir: t.TempDir(), SyncPolicy: WALSyncInterval})
+	require.NoError(t, err)
+
+	w := c.items.wal
+	c.Set("1", "value1", NoTTL)
+	require.NoError(t, c.Close())
+	assert.Nil(t, c.items.wal)
+	assert.True(t, w.closed)
+
+	// changes are no longer logged
+	c.Set("2", "value2", NoTTL)
+	assert.NoError(t, c.Close())
+
+	// no log
+	assert.NoError(t, New[string, string]().Close())
+
+	// background compaction error
+	c, err = Open[string, string](WALConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	errCompact := errors.New("error")
+	c.items.wal.compactErr = errCompact
+	assert.Equal(t, errCompact, c.Close())
+}
+
+func Test_Cache_Range(t *testing.T) {
+	c := prepCache(DefaultTTL, "1", "2", "3", "4", "5")
+	var results []string
+
+	c.Range(func(item *Item[string, string]) bool {
+		results = append(results, item.Key())
+		return item.Key() != "4"
+	})
+
+	assert.Equal(t, []string{"5", "4"}, results)
+}
+
+func Test_LoaderFunc_Load(t *testing.T) {
+	var called bool
+
+	fn := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		called = true
+		return nil
+	})
+
+	assert.Nil(t, fn(nil, ""))
+	assert.True(t, called)
+}
+
+func Test_loadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	item, err := loadWithError[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+
+	item, err = loadWithError[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}), nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+}
+
+func Test_loadContext(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	item, err := loadContext[string, string](ctx, ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		return &Item[string, string]{key: ctx.Value(ctxKey{}).(string)}, nil
+	}), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "value", item.key)
+
+	errLoad := errors.New("error")
+
+	item, err = loadContext[string, string](ctx, LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}), nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+
+	cache := prepCache(time.Hour)
+
+	item, err = loadContext[string, string](ctx, ResultLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*LoadResult[string], error) {
+		return &LoadResult[string]{Value: ctx.Value(ctxKey{}).(string)}, nil
+	}), cache, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "value", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+}
+
+func Test_Cache_loadResult(t *testing.T) {
+	var (
+		res     *LoadResult[string]
+		errLoad error
+		onLoad  func(c *Cache[string, string])
+	)
+
+	loader := ResultLoaderFunc[string, string](func(_ context.Context, c *Cache[string, string], _ string) (*LoadResult[string], error) {
+		if onLoad != nil {
+			onLoad(c)
+		}
+
+		return res, errLoad
+	})
+
+	cache := prepCache(time.Hour)
+	cache.options.maxCost = 10
+
+	// not found
+	item, err := cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	// failed load
+	errLoad = errors.New("error")
+	res = &LoadResult[string]{Value: "value"}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+	assert.Empty(t, cache.items.values)
+
+	// default TTL and cost
+	errLoad = nil
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Same(t, cache.items.values["test"], item)
+	assert.Equal(t, "value", item.value)
+	assert.Equal(t, time.Hour, item.ttl)
+	assert.Equal(t, uint64(1), item.cost)
+
+	// custom TTL and cost
+	res = &LoadResult[string]{Value: "value2", TTL: time.Minute, Cost: 5}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Same(t, cache.items.values["test"], item)
+	assert.Equal(t, "value2", item.value)
+	assert.Equal(t, time.Minute, item.ttl)
+	assert.Equal(t, uint64(5), item.cost)
+	assert.Equal(t, uint64(5), cache.items.cost)
+
+	// no cache
+	res = &LoadResult[string]{Value: "uncached", NoCache: true}
+
+	item, err = cache.loadResult(context.Background(), loader, "test2")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "uncached", item.value)
+	assert.Equal(t, time.Hour, item.ttl)
+	assert.NotContains(t, cache.items.values, "test2")
+
+	// overwritten while loading
+	res = &LoadResult[string]{Value: "loaded"}
+	onLoad = func(c *Cache[string, string]) {
+		c.Set("test", "overwritten", DefaultTTL)
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "overwritten", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+
+	// added while loading
+	onLoad = func(c *Cache[string, string]) {
+		c.Set("test3", "added", DefaultTTL)
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test3")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "added", item.value)
+
+	// deleted while loading
+	onLoad = func(c *Cache[string, string]) {
+		c.Delete("test")
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded", item.value)
+	assert.NotContains(t, cache.items.values, "test")
+
+	// added and deleted while loading
+	onLoad = func(c *Cache[string, string]) {
+		c.Set("test", "added", DefaultTTL)
+		c.Delete("test")
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded", item.value)
+	assert.NotContains(t, cache.items.values, "test")
+
+	// deleted and added again while loading
+	cache.Set("test", "old", DefaultTTL)
+	onLoad = func(c *Cache[string, string]) {
+		c.Delete("test")
+		c.Set("test", "added", DefaultTTL)
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "added", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+
+	// not deleted while loading
+	onLoad = nil
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+
+	// another item deleted while loading
+	cache.Set("test5", "other", DefaultTTL)
+	res = &LoadResult[string]{Value: "loaded2"}
+	onLoad = func(c *Cache[string, string]) {
+		c.Delete("test5")
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded2", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+
+	// all items deleted while loading
+	res = &LoadResult[string]{Value: "loaded3"}
+	onLoad = func(c *Cache[string, string]) {
+		c.DeleteAll()
+	}
+
+	item, err = cache.loadResult(context.Background(), loader, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "loaded3", item.value)
+	assert.NotContains(t, cache.items.values, "test")
+	assert.Empty(t, cache.items.loads)
+
+	// rejected because of its cost
+	onLoad = nil
+	res = &LoadResult[string]{Value: "costly", Cost: 20}
+
+	item, err = cache.loadResult(context.Background(), loader, "test4")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.NotContains(t, cache.items.values, "test4")
+}
+
+func Test_ResultLoaderFunc_Load(t *testing.T) {
+	cache := prepCache(time.Hour)
+
+	fn := ResultLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*LoadResult[string], error) {
+		assert.Equal(t, context.Background(), ctx)
+		return &LoadResult[string]{Value: "value"}, nil
+	})
+
+	item := fn.Load(cache, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "value", item.value)
+	assert.Same(t, cache.items.values["test"], item)
+}
+
+func Test_ResultLoaderFunc_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+	cache := prepCache(time.Hour)
+
+	fn := ResultLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*LoadResult[string], error) {
+		assert.Equal(t, context.Background(), ctx)
+		return nil, errLoad
+	})
+
+	item, err := fn.LoadWithError(cache, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+}
+
+func Test_ResultLoaderFunc_LoadContext(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	cache := prepCache(time.Hour)
+
+	fn := ResultLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*LoadResult[string], error) {
+		return &LoadResult[string]{Value: ctx.Value(ctxKey{}).(string), TTL: time.Minute}, nil
+	})
+
+	item, err := fn.LoadContext(ctx, cache, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "value", item.value)
+	assert.Equal(t, time.Minute, item.ttl)
+}
+
+func Test_ResultLoaderFunc_LoadResult(t *testing.T) {
+	fn := ResultLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], key string) (*LoadResult[string], error) {
+		return &LoadResult[string]{Value: key}, nil
+	})
+
+	res, err := fn.LoadResult(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, res)
+	assert.Equal(t, "test", res.Value)
+}
+
+func Test_LoaderWithErrorFunc_Load(t *testing.T) {
+	var called bool
+
+	fn := LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		called = true
+		return nil, errors.New("error")
+	})
+
+	assert.Nil(t, fn.Load(nil, ""))
+	assert.True(t, called)
+}
+
+func Test_LoaderWithErrorFunc_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	fn := LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], key string) (*Item[string, string], error) {
+		return &Item[string, string]{key: key}, errLoad
+	})
+
+	item, err := fn.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_ContextLoaderFunc_Load(t *testing.T) {
+	var called bool
+
+	fn := ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*Item[string, string], error) {
+		called = true
+		assert.NotNil(t, ctx)
+		return nil, errors.New("error")
+	})
+
+	assert.Nil(t, fn.Load(nil, ""))
+	assert.True(t, called)
+}
+
+func Test_ContextLoaderFunc_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	fn := ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		assert.Equal(t, context.Background(), ctx)
+		return &Item[string, string]{key: key}, errLoad
+	})
+
+	item, err := fn.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_ContextLoaderFunc_LoadContext(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	fn := ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return &Item[string, string]{key: key}, nil
+	})
+
+	item, err := fn.LoadContext(ctx, nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_BatchLoaderFunc_LoadBatch(t *testing.T) {
+	var called bool
+
+	fn := BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+		called = true
+		return map[string]string{keys[0]: "value"}, nil
+	})
+
+	res, err := fn.LoadBatch(context.Background(), nil, []string{"test"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"test": "value"}, res)
+	assert.True(t, called)
+}
+
+func Test_NewSuppressedLoader(t *testing.T) {
+	var called bool
+
+	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		called = true
+		return nil
+	})
+
+	// uses the provided loader and shares the in-flight loads of
+	// the group
+	group := &singleflight.Group{}
+
+	sl := NewSuppressedLoader[string, string](loader, group)
+	require.NotNil(t, sl)
+	require.NotNil(t, sl.loader)
+	require.NotNil(t, sl.calls)
+	assert.Same(t, sl.calls, NewSuppressedLoader[string, string](loader, group).calls)
+	assert.NotSame(t, sl.calls, NewSuppressedLoader[string, string](loader, &singleflight.Group{}).calls)
+
+	sl.loader.Load(nil, "")
+
+	assert.True(t, called)
+
+	// loaders of other types do not share the in-flight loads of
+	// the group
+	assert.NotNil(t, NewSuppressedLoader[string, int](nil, group).calls)
+
+	// uses the provided loader when nil group parameter is passed
+	called = false
+
+	sl = NewSuppressedLoader[string, string](loader, nil)
+	require.NotNil(t, sl)
+	require.NotNil(t, sl.loader)
+	require.NotNil(t, sl.calls)
+	assert.NotSame(t, sl.calls, NewSuppressedLoader[string, string](loader, nil).calls)
+
+	sl.loader.Load(nil, "")
+
+	assert.True(t, called)
+}
+
+func Test_SuppressedLoader_Load(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		releaseCh = make(chan struct{})
+		res       *Item[string, string]
+	)
+
+	l := SuppressedLoader[string, string]{
+		loader: LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+			mu.Lock()
+			loadCalls++
+			mu.Unlock()
+
+			<-releaseCh
+
+			if res == nil {
+				return nil
+			}
+
+			return &Item[string, string]{key: res.key}
+		}),
+		calls: &suppressedCalls[string, string]{},
+	}
+
+	var (
+		wg           sync.WaitGroup
+		item1, item2 *Item[string, string]
+	)
+
+	cache := prepCache(time.Hour)
+
+	// nil result
+	wg.Add(2)
+
+	go func() {
+		item1 = l.Load(cache, "test")
+		wg.Done()
+	}()
+
+	go func() {
+		item2 = l.Load(cache, "test")
+		wg.Done()
+	}()
+
+	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
+	releaseCh <- struct{}{}
+
+	wg.Wait()
+	require.Nil(t, item1)
+	require.Nil(t, item2)
+	assert.Equal(t, 1, loadCalls)
+
+	// non nil result
+	res = &Item[string, string]{key: "test"}
+	loadCalls = 0
+	wg.Add(2)
+
+	go func() {
+		item1 = l.Load(cache, "test")
+		wg.Done()
+	}()
+
+	go func() {
+		item2 = l.Load(cache, "test")
+		wg.Done()
+	}()
+
+	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
+	releaseCh <- struct{}{}
+
+	wg.Wait()
+	require.Same(t, item1, item2)
+	assert.Equal(t, "test", item1.key)
+	assert.Equal(t, 1, loadCalls)
+
+	// loaders that share their in-flight loads
+	l2 := SuppressedLoader[string, string]{
+		loader: l.loader,
+		calls:  l.calls,
+	}
+
+	loadCalls = 0
+	wg.Add(2)
+
+	go func() {
+		item1 = l.Load(cache, "test")
+		wg.Done()
+	}()
+
+	go func() {
+		item2 = l2.Load(cache, "test")
+		wg.Done()
+	}()
+
+	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
+	releaseCh <- struct{}{}
+
+	wg.Wait()
+	require.Same(t, item1, item2)
+	assert.Equal(t, 1, loadCalls)
+}
+
+func prepCache(ttl time.Duration, keys ...string) *Cache[string, string] {
+	c := &Cache[string, string]{}
+	c.options.ttl = ttl
+	c.items.values = make(map[string]*Item[string, string])
+	c.items.policy = NewLRUPolicy[string, string]()
+	c.items.expQueue = newExpirationQueue[string, string]()
+	c.items.timerCh = make(chan time.Duration, 1)
+	c.events.eviction.fns = make(map[uint64]func(EvictionReason, *Item[string, string]))
+	c.events.insertion.fns = make(map[uint64]func(*Item[string, string]))
+	c.events.refreshFailure.fns = make(map[uint64]func(string, error))
+	c.refreshes.keys = make(map[string]struct{})
+	c.batches.calls = make(map[string]*batchCall[string, string])
+
+	addToCache(c, ttl, keys...)
+
+	return c
+}
+
+func addToCache(c *Cache[string, string], ttl time.Duration, keys ...string) {
+	for i, key := range keys {
+		item := newItem(
+			key,
+			fmt.Sprint("value of", key),
+			ttl+time.Duration(i)*time.Minute,
+			false,
+		)
+		c.items.values[key] = item
+		c.items.policy.OnInsert(item)
+
+		if c.items.wheel != nil {
+			c.items.wheel.schedule(item)
+		} else {
+			c.items.expQueue.push(item)
+		}
+	}
+}
+
+// accessRecordingPolicy wraps SIEVEPolicy and counts the calls of
+// its access methods.
+type accessRecordingPolicy struct {
+	*SIEVEPolicy[string, string]
+	accesses           int
+	concurrentAccesses int
+}
+
+func (p *accessRecordingPolicy) OnAccess(item *Item[string, string]) {
+	p.accesses++
+	p.SIEVEPolicy.OnAccess(item)
+}
+
+func (p *accessRecordingPolicy) OnConcurrentAccess(item *Item[string, string]) {
+	p.concurrentAccesses++
+	p.SIEVEPolicy.OnConcurrentAccess(item)
+}
+
+// lruKeys returns the keys of the cache's items from the most
+// recently used one to the least recently used one.
+func lruKeys(c *Cache[string, string]) []string {
+	var keys []string
+	c.items.policy.(*LRUPolicy[string, string]).each(func(item *Item[string, string]) bool {
+		keys = append(keys, item.key)
+		return true
+	})
+
+	return keys
+}
+
+// recordingSnapshotCodec is a SnapshotCodec that records the encoded
+// items instead of writing them.
+type recordingSnapshotCodec struct {
+	items []SnapshotItem[string, string]
+}
+
+func (c *recordingSnapshotCodec) NewEncoder(_ io.Writer) SnapshotEncoder[string, string] {
+	return c
+}
+
+func (c *recordingSnapshotCodec) NewDecoder(_ io.Reader) SnapshotDecoder[string, string] {
+	return c
+}
+
+func (c *recordingSnapshotCodec) Encode(item SnapshotItem[string, string]) error {
+	c.items = append(c.items, item)
+	return nil
+}
+
+func (c *recordingSnapshotCodec) Decode() (SnapshotItem[string, string], error) {
+	if len(c.items) == 0 {
+		return SnapshotItem[string, string]{}, io.EOF
+	}
+
+	item := c.items[0]
+	c.items = c.items[1:]
+
+	return item, nil
+}
+
+func lruFront(c *Cache[string, string]) *Item[string, string] {
+	return c.items.policy.(*LRUPolicy[string, string]).list.Front().Value.(*Item[string, string])
+}
+
+func lruBack(c *Cache[string, string]) *Item[string, string] {
+	return c.items.policy.(*LRUPolicy[string, string]).list.Back().Value.(*Item[string, string])
+}
+
+func Test_SuppressedLoader_LoadWithError(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		releaseCh = make(chan struct{})
+		errLoad   = errors.New("error")
+	)
+
+	l := SuppressedLoader[string, string]{
+		loader: LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+			mu.Lock()
+			loadCalls++
+			mu.Unlock()
+
+			<-releaseCh
+
+			return nil, errLoad
+		}),
+		calls: &suppressedCalls[string, string]{},
+	}
+
+	var (
+		wg           sync.WaitGroup
+		item1, item2 *Item[string, string]
+		err1, err2   error
+	)
+
+	cache := prepCache(time.Hour)
+
+	wg.Add(2)
+
+	go func() {
+		item1, err1 = l.LoadWithError(cache, "test")
+		wg.Done()
+	}()
+
+	go func() {
+		item2, err2 = l.LoadWithError(cache, "test")
+		wg.Done()
+	}()
+
+	time.Sleep(time.Millisecond * 100) // wait for goroutines to halt
+	releaseCh <- struct{}{}
+
+	wg.Wait()
+	assert.Nil(t, item1)
+	assert.Nil(t, item2)
+	assert.Equal(t, errLoad, err1)
+	assert.Equal(t, errLoad, err2)
+	assert.Equal(t, 1, loadCalls)
+
+	// errors are discarded by Load
+	go func() {
+		releaseCh <- struct{}{}
+	}()
+
+	assert.Nil(t, l.Load(cache, "test"))
+}
+
+func Test_SuppressedLoader_LoadContext(t *testing.T) {
+	type ctxKey struct{}
+
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		startedCh = make(chan struct{}, 1)
+		releaseCh = make(chan struct{})
+		loadCtxCh = make(chan context.Context, 1)
+	)
+
+	l := SuppressedLoader[string, string]{
+		loader: ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+			mu.Lock()
+			loadCalls++
+			mu.Unlock()
+
+			loadCtxCh <- ctx
+			startedCh <- struct{}{}
+
+			select {
+			case <-releaseCh:
+				return &Item[string, string]{key: key}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}),
+		calls: &suppressedCalls[string, string]{},
+	}
+
+	cache := prepCache(time.Hour)
+
+	// a single caller gives up, the shared load continues
+	ctx1, cancel1 := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	var (
+		wg    sync.WaitGroup
+		item2 *Item[string, string]
+		err2  error
+	)
+
+	wg.Add(1)
+	go func() {
+		item2, err2 = l.LoadContext(ctx2, cache, "test")
+		wg.Done()
+	}()
+
+	<-startedCh
+	loadCtx := <-loadCtxCh
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := l.LoadContext(ctx1, cache, "test")
+		errCh <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		l.calls.mu.Lock()
+		defer l.calls.mu.Unlock()
+
+		return l.calls.calls["test"] != nil && l.calls.calls["test"].waiters == 2
+	}, time.Second, time.Millisecond)
+
+	cancel1()
+	assert.Equal(t, context.Canceled, <-errCh)
+	assert.NoError(t, loadCtx.Err())
+
+	releaseCh <- struct{}{}
+	wg.Wait()
+	assert.NoError(t, err2)
+	require.NotNil(t, item2)
+	assert.Equal(t, "test", item2.key)
+	assert.Equal(t, 1, loadCalls)
+	assert.Empty(t, l.calls.calls)
+
+	// all callers give up, the shared load is cancelled
+	ctx3, cancel3 := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+
+	go func() {
+		_, err := l.LoadContext(ctx3, cache, "test")
+		errCh <- err
+	}()
+
+	<-startedCh
+	loadCtx = <-loadCtxCh
+	assert.Equal(t, "value", loadCtx.Value(ctxKey{}))
+
+	cancel3()
+	assert.Equal(t, context.Canceled, <-errCh)
+	<-loadCtx.Done()
+	assert.Equal(t, context.Canceled, loadCtx.Err())
+	assert.Empty(t, l.calls.calls)
+
+	// a new caller starts a new load
+	go func() {
+		<-startedCh
+		<-loadCtxCh
+		releaseCh <- struct{}{}
+	}()
+
+	item, err := l.LoadContext(context.Background(), cache, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, 3, loadCalls)
+}
+
+// stringerKey is a key type whose distinct values may have equal
+// string forms.
+type stringerKey struct {
+	id   int
+	name string
+}
+
+func (k stringerKey) String() string {
+	return k.name
+}
+
+func Test_SuppressedLoader_LoadContext_keys(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		releaseCh = make(chan struct{})
+	)
+
+	l := SuppressedLoader[stringerKey, string]{
+		loader: LoaderFunc[stringerKey, string](func(_ *Cache[stringerKey, string], key stringerKey) *Item[stringerKey, string] {
+			mu.Lock()
+			loadCalls++
+			mu.Unlock()
+
+			<-releaseCh
+
+			return &Item[stringerKey, string]{key: key}
+		}),
+		calls: &suppressedCalls[stringerKey, string]{},
+	}
+
+	var (
+		wg           sync.WaitGroup
+		item1, item2 *Item[stringerKey, string]
+		key1         = stringerKey{id: 1, name: "same"}
+		key2         = stringerKey{id: 2, name: "same"}
+	)
+
+	wg.Add(2)
+
+	go func() {
+		item1, _ = l.LoadContext(context.Background(), nil, key1)
+		wg.Done()
+	}()
+
+	go func() {
+		item2, _ = l.LoadContext(context.Background(), nil, key2)
+		wg.Done()
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls == 2
+	}, time.Second, time.Millisecond)
+
+	close(releaseCh)
+	wg.Wait()
+
+	require.NotNil(t, item1)
+	require.NotNil(t, item2)
+	assert.Equal(t, key1, item1.key)
+	assert.Equal(t, key2, item2.key)
+}
+
+func Test_SuppressedLoader_LoadContext_panic(t *testing.T) {
+	releaseCh := make(chan struct{})
+
+	l := SuppressedLoader[string, string]{
+		loader: LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+			<-releaseCh
+			panic("load failed")
+		}),
+		calls: &suppressedCalls[string, string]{},
+	}
+
+	var wg sync.WaitGroup
+
+	panics := make(chan interface{}, 2)
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() {
+				panics <- recover()
+			}()
+
+			_, _ = l.LoadContext(context.Background(), nil, "test")
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		l.calls.mu.Lock()
+		defer l.calls.mu.Unlock()
+
+		return l.calls.calls["test"] != nil && l.calls.calls["test"].waiters == 2
+	}, time.Second, time.Millisecond)
+
+	close(releaseCh)
+	wg.Wait()
+
+	assert.Equal(t, "load failed", <-panics)
+	assert.Equal(t, "load failed", <-panics)
+	assert.Empty(t, l.calls.calls)
+}
+
+func Test_SuppressedLoader_Forget(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		loadCalls int
+		releaseCh = make(chan struct{})
+	)
+
+	l := SuppressedLoader[string, string]{
+		loader: LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+			mu.Lock()
+			loadCalls++
+			mu.Unlock()
+
+			<-releaseCh
+
+			return &Item[string, string]{key: key}
+		}),
+		calls: &suppressedCalls[string, string]{},
+	}
+
+	var (
+		wg           sync.WaitGroup
+		item1, item2 *Item[string, string]
+	)
+
+	wg.Add(1)
+	go func() {
+		item1 = l.Load(nil, "test")
+		wg.Done()
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls == 1
+	}, time.Second, time.Millisecond)
+
+	l.calls.mu.Lock()
+	forgotten := l.calls.calls["test"]
+	l.calls.mu.Unlock()
+
+	// a new load is started after the key is forgotten
+	l.Forget("test")
+	assert.Empty(t, l.calls.calls)
+
+	wg.Add(1)
+	go func() {
+		item2 = l.Load(nil, "test")
+		wg.Done()
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return loadCalls == 2
+	}, time.Second, time.Millisecond)
+
+	l.calls.mu.Lock()
+	assert.NotSame(t, forgotten, l.calls.calls["test"])
+	l.calls.mu.Unlock()
+
+	close(releaseCh)
+	wg.Wait()
+
+	require.NotNil(t, item1)
+	require.NotNil(t, item2)
+	assert.NotSame(t, item1, item2)
+	assert.Empty(t, l.calls.calls)
+
+	// forgetting unknown keys is no-op
+	l.Forget("unknown")
+}
+
+func Test_detachedContext(t *testing.T) {
+	type ctxKey struct{}
+
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "value"), time.Hour)
+	cancel()
+
+	ctx := detachedContext{parent: parent}
+
+	deadline, ok := ctx.Deadline()
+	assert.False(t, ok)
+	assert.Zero(t, deadline)
+	assert.Nil(t, ctx.Done())
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, "value", ctx.Value(ctxKey{}))
 }
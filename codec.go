@@ -0,0 +1,490 @@
+package ttlcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// Codec is an interface that handles the conversion of values of a
+// single type (e.g. cache keys or values) to bytes and back.
+type Codec[T any] interface {
+	// Marshal should return the encoded form of the value.
+	Marshal(v T) ([]byte, error)
+
+	// Unmarshal should decode the data and store the result in the
+	// value pointed to by v.
+	// The method must copy the data if it needs to retain it after
+	// returning.
+	Unmarshal(data []byte, v *T) error
+}
+
+// GobCodec is a Codec that encodes values with the encoding/gob
+// package. Each value is encoded separately, along with its type
+// information.
+// Interface values require their concrete types to be registered
+// with gob.Register.
+type GobCodec[T any] struct{}
+
+// Marshal returns the gob encoding of the value.
+func (GobCodec[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the gob encoded data and stores the result in the
+// value pointed to by v.
+func (GobCodec[T]) Unmarshal(data []byte, v *T) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec is a Codec that encodes values with the encoding/json
+// package.
+type JSONCodec[T any] struct{}
+
+// Marshal returns the JSON encoding of the value.
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the JSON encoded data and stores the result in
+// the value pointed to by v.
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// BinaryType is a constraint that permits the types that are supported
+// by BinaryCodec.
+type BinaryType interface {
+	~string | ~[]byte |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// BinaryCodec is a Codec that encodes strings and byte slices as their
+// raw bytes and integers as varints, which makes it considerably
+// faster and more compact than GobCodec and JSONCodec.
+type BinaryCodec[T BinaryType] struct{}
+
+// Marshal returns the binary encoding of the value.
+func (BinaryCodec[T]) Marshal(v T) ([]byte, error) {
+	switch v := any(v).(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Slice:
+		return append([]byte(nil), rv.Bytes()...), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutVarint(buf, rv.Int())], nil
+	default:
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutUvarint(buf, rv.Uint())], nil
+	}
+}
+
+// Unmarshal decodes the binary encoded data and stores the result in
+// the value pointed to by v.
+func (BinaryCodec[T]) Unmarshal(data []byte, v *T) error {
+	switch v := any(v).(type) {
+	case *string:
+		*v = string(data)
+		return nil
+	case *[]byte:
+		*v = append([]byte(nil), data...)
+		return nil
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(string(data))
+	case reflect.Slice:
+		rv.SetBytes(append([]byte(nil), data...))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, n := binary.Varint(data)
+		if n <= 0 || n != len(data) {
+			return fmt.Errorf("ttlcache: invalid varint data for %s", rv.Type())
+		}
+
+		if rv.OverflowInt(x) {
+			return fmt.Errorf("ttlcache: value %d overflows %s", x, rv.Type())
+		}
+
+		rv.SetInt(x)
+	default:
+		x, n := binary.Uvarint(data)
+		if n <= 0 || n != len(data) {
+			return fmt.Errorf("ttlcache: invalid uvarint data for %s", rv.Type())
+		}
+
+		if rv.OverflowUint(x) {
+			return fmt.Errorf("ttlcache: value %d overflows %s", x, rv.Type())
+		}
+
+		rv.SetUint(x)
+	}
+
+	return nil
+}
+
+// snapshotItemDeleted is the flag of the records of deleted snapshot
+// items.
+const snapshotItemDeleted byte = 1
+
+// snapshotMagic starts the header of snapshots.
+var snapshotMagic = []byte("ttlc")
+
+// snapshotFormatVersion is the version of the format of the records
+// that follows the magic bytes in the header of snapshots.
+const snapshotFormatVersion byte = 1
+
+// codecSnapshotCodec is a SnapshotCodec that encodes snapshot items as
+// length-prefixed records whose keys and values are encoded with the
+// provided codecs.
+type codecSnapshotCodec[K comparable, V any] struct {
+	keyCodec   Codec[K]
+	valueCodec Codec[V]
+}
+
+// NewSnapshotCodec creates a new SnapshotCodec that encodes snapshot
+// items as compact length-prefixed binary records, using the provided
+// codecs for their keys and values.
+// If any of the codecs is nil, GobCodec is used instead.
+func NewSnapshotCodec[K comparable, V any](keyCodec Codec[K], valueCodec Codec[V]) SnapshotCodec[K, V] {
+	return newCodecSnapshotCodec(keyCodec, valueCodec)
+}
+
+// newCodecSnapshotCodec creates a new snapshot codec that uses the
+// provided key and value codecs, or GobCodec if they are nil.
+func newCodecSnapshotCodec[K comparable, V any](keyCodec Codec[K], valueCodec Codec[V]) codecSnapshotCodec[K, V] {
+	if keyCodec == nil {
+		keyCodec = GobCodec[K]{}
+	}
+
+	if valueCodec == nil {
+		valueCodec = GobCodec[V]{}
+	}
+
+	return codecSnapshotCodec[K, V]{
+		keyCodec:   keyCodec,
+		valueCodec: valueCodec,
+	}
+}
+
+// NewEncoder returns an encoder that writes to the provided writer.
+func (c codecSnapshotCodec[K, V]) NewEncoder(w io.Writer) SnapshotEncoder[K, V] {
+	return &codecSnapshotEncoder[K, V]{
+		codec: c,
+		w:     w,
+	}
+}
+
+// NewDecoder returns a decoder that reads from the provided reader.
+func (c codecSnapshotCodec[K, V]) NewDecoder(r io.Reader) SnapshotDecoder[K, V] {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	return &codecSnapshotDecoder[K, V]{
+		codec: c,
+		r:     br,
+	}
+}
+
+// appendKey appends the length-prefixed encoding of the key to the
+// buffer.
+func (c codecSnapshotCodec[K, V]) appendKey(buf []byte, key K) ([]byte, error) {
+	data, err := c.keyCodec.Marshal(key)
+	if err != nil {
+		return buf, err
+	}
+
+	buf = appendUvarint(buf, uint64(len(data)))
+
+	return append(buf, data...), nil
+}
+
+// appendItem appends the record of the snapshot item to the buffer.
+func (c codecSnapshotCodec[K, V]) appendItem(buf []byte, item SnapshotItem[K, V]) ([]byte, error) {
+	buf, err := c.appendKey(buf, item.Key)
+	if err != nil {
+		return buf, err
+	}
+
+	value, err := c.valueCodec.Marshal(item.Value)
+	if err != nil {
+		return buf, err
+	}
+
+	buf = appendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	buf = appendVarint(buf, int64(item.TTL))
+	buf = appendVarint(buf, timeToUnixNano(item.ExpiresAt))
+	buf = appendVarint(buf, item.Version)
+	buf = appendUvarint(buf, item.Cost)
+
+	var f byte
+	if item.Deleted {
+		f |= snapshotItemDeleted
+	}
+
+	return append(buf, f), nil
+}
+
+// recordReader is a reader of length-prefixed records.
+type recordReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readKey reads the length-prefixed encoding of a key. The provided
+// buffer is reused between calls.
+// io.EOF is returned only if there is no data left.
+func (c codecSnapshotCodec[K, V]) readKey(r recordReader, buf *[]byte) (K, error) {
+	var key K
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return key, err
+	}
+
+	if err := readRecordBytes(r, buf, n); err != nil {
+		return key, err
+	}
+
+	err = c.keyCodec.Unmarshal(*buf, &key)
+
+	return key, err
+}
+
+// readItem reads the record of a snapshot item. The provided buffer is
+// reused between calls.
+// io.EOF is returned only if there is no data left.
+func (c codecSnapshotCodec[K, V]) readItem(r recordReader, buf *[]byte) (SnapshotItem[K, V], error) {
+	var (
+		item SnapshotItem[K, V]
+		err  error
+	)
+
+	if item.Key, err = c.readKey(r, buf); err != nil {
+		return item, err
+	}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return item, unexpectedEOF(err)
+	}
+
+	if err := readRecordBytes(r, buf, n); err != nil {
+		return item, err
+	}
+
+	if err := c.valueCodec.Unmarshal(*buf, &item.Value); err != nil {
+		return item, err
+	}
+
+	var ttl, expiresAt int64
+
+	for _, v := range []*int64{&ttl, &expiresAt, &item.Version} {
+		if *v, err = binary.ReadVarint(r); err != nil {
+			return item, unexpectedEOF(err)
+		}
+	}
+
+	if item.Cost, err = binary.ReadUvarint(r); err != nil {
+		return item, unexpectedEOF(err)
+	}
+
+	f, err := r.ReadByte()
+	if err != nil {
+		return item, unexpectedEOF(err)
+	}
+
+	item.Deleted = f&snapshotItemDeleted != 0
+
+	item.TTL = time.Duration(ttl)
+	item.ExpiresAt = unixNanoToTime(expiresAt)
+
+	return item, nil
+}
+
+// codecSnapshotEncoder writes snapshot items as length-prefixed
+// records, preceded by the header of the snapshot.
+type codecSnapshotEncoder[K comparable, V any] struct {
+	codec  codecSnapshotCodec[K, V]
+	w      io.Writer
+	buf    []byte
+	header bool
+}
+
+// Encode writes the encoded snapshot item.
+func (e *codecSnapshotEncoder[K, V]) Encode(item SnapshotItem[K, V]) error {
+	e.buf = e.buf[:0]
+	if !e.header {
+		e.buf = append(e.buf, snapshotMagic...)
+		e.buf = append(e.buf, snapshotFormatVersion)
+	}
+
+	var err error
+
+	if e.buf, err = e.codec.appendItem(e.buf, item); err != nil {
+		return err
+	}
+
+	e.header = true
+
+	_, err = e.w.Write(e.buf)
+
+	return err
+}
+
+// codecSnapshotDecoder reads snapshot items from length-prefixed
+// records.
+type codecSnapshotDecoder[K comparable, V any] struct {
+	codec  codecSnapshotCodec[K, V]
+	r      *bufio.Reader
+	buf    []byte
+	header bool
+}
+
+// Decode reads the next snapshot item.
+func (d *codecSnapshotDecoder[K, V]) Decode() (SnapshotItem[K, V], error) {
+	if !d.header {
+		if err := d.readHeader(); err != nil {
+			return SnapshotItem[K, V]{}, err
+		}
+	}
+
+	return d.codec.readItem(d.r, &d.buf)
+}
+
+// readHeader reads the header of the snapshot and checks the format
+// of its records.
+// io.EOF is returned only if there is no data left.
+func (d *codecSnapshotDecoder[K, V]) readHeader() error {
+	d.header = true
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return fmt.Errorf("%w: missing header", ErrSnapshotFormat)
+	}
+
+	if version := header[len(snapshotMagic)]; version != snapshotFormatVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrSnapshotFormat, version)
+	}
+
+	return nil
+}
+
+// recordChunkSize is the number of bytes of a record that are read
+// before the buffer is grown any further.
+const recordChunkSize = 64 << 10
+
+// readRecordBytes reads the next n bytes into the provided buffer.
+func readRecordBytes(r recordReader, buf *[]byte, n uint64) error {
+	// the length may be corrupted, so it must not exceed the
+	// remaining data, if it is known
+	if lr, ok := r.(interface{ Len() int }); ok && n > uint64(lr.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+
+	if uint64(cap(*buf)) >= n {
+		*buf = (*buf)[:n]
+
+		_, err := io.ReadFull(r, *buf)
+
+		return unexpectedEOF(err)
+	}
+
+	// otherwise, the buffer grows only as the data is actually read,
+	// so that corrupted lengths do not cause huge allocations
+	*buf = (*buf)[:0]
+
+	for uint64(len(*buf)) < n {
+		start := len(*buf)
+
+		size := n - uint64(start)
+		if grow := uint64(start) + recordChunkSize; size > grow {
+			size = grow
+		}
+
+		if uint64(cap(*buf)-start) < size {
+			grown := make([]byte, start, uint64(start)+size)
+			copy(grown, *buf)
+			*buf = grown
+		}
+
+		*buf = (*buf)[:uint64(start)+size]
+
+		if _, err := io.ReadFull(r, (*buf)[start:]); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+
+	return nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, since it is
+// returned in the middle of a record.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// appendUvarint appends the uvarint encoding of the value.
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+// appendVarint appends the varint encoding of the value.
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
+
+// timeToUnixNano returns the Unix time of the timestamp in
+// nanoseconds, or 0 if the timestamp is zero.
+func timeToUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+// unixNanoToTime returns the timestamp of the Unix time in
+// nanoseconds, or the zero timestamp if it is 0.
+func unixNanoToTime(v int64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, v)
+}
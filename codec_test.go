@@ -0,0 +1,259 @@
+package ttlcache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codecTestValue struct {
+	Name  string
+	Count int
+}
+
+func Test_GobCodec(t *testing.T) {
+	var codec GobCodec[codecTestValue]
+
+	data, err := codec.Marshal(codecTestValue{Name: "name", Count: 3})
+	require.NoError(t, err)
+
+	var res codecTestValue
+	require.NoError(t, codec.Unmarshal(data, &res))
+	assert.Equal(t, codecTestValue{Name: "name", Count: 3}, res)
+
+	// invalid data
+	assert.Error(t, codec.Unmarshal([]byte{1, 2, 3}, &res))
+
+	// unsupported value
+	_, err = GobCodec[func()]{}.Marshal(func() {})
+	assert.Error(t, err)
+}
+
+func Test_JSONCodec(t *testing.T) {
+	var codec JSONCodec[codecTestValue]
+
+	data, err := codec.Marshal(codecTestValue{Name: "name", Count: 3})
+	require.NoError(t, err)
+	assert.Equal(t, `{"Name":"name","Count":3}`, string(data))
+
+	var res codecTestValue
+	require.NoError(t, codec.Unmarshal(data, &res))
+	assert.Equal(t, codecTestValue{Name: "name", Count: 3}, res)
+
+	// invalid data
+	assert.Error(t, codec.Unmarshal([]byte("{"), &res))
+}
+
+func Test_BinaryCodec(t *testing.T) {
+	type namedString string
+	type namedBytes []byte
+
+	testBinaryCodec[string](t, "value", []byte("value"))
+	testBinaryCodec[namedString](t, "value", []byte("value"))
+	testBinaryCodec[[]byte](t, []byte("value"), []byte("value"))
+	testBinaryCodec[namedBytes](t, namedBytes("value"), []byte("value"))
+	testBinaryCodec[int](t, -1, []byte{1})
+	testBinaryCodec[int64](t, math.MinInt64, []byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 1})
+	testBinaryCodec[uint8](t, 255, []byte{255, 1})
+	testBinaryCodec[time.Duration](t, time.Nanosecond, []byte{2})
+
+	// byte slices are copied
+	data := []byte("value")
+	var res []byte
+	require.NoError(t, BinaryCodec[[]byte]{}.Unmarshal(data, &res))
+	data[0] = 'x'
+	assert.Equal(t, []byte("value"), res)
+
+	// overflow
+	var i8 int8
+	assert.Error(t, BinaryCodec[int8]{}.Unmarshal([]byte{128, 2}, &i8))
+
+	var u8 uint8
+	assert.Error(t, BinaryCodec[uint8]{}.Unmarshal([]byte{128, 2}, &u8))
+
+	// invalid data
+	assert.Error(t, BinaryCodec[int]{}.Unmarshal(nil, new(int)))
+	assert.Error(t, BinaryCodec[int]{}.Unmarshal([]byte{1, 1}, new(int)))
+	assert.Error(t, BinaryCodec[uint]{}.Unmarshal([]byte{128}, new(uint)))
+}
+
+func testBinaryCodec[T BinaryType](t *testing.T, v T, data []byte) {
+	t.Helper()
+
+	var codec BinaryCodec[T]
+
+	res, err := codec.Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	var value T
+	require.NoError(t, codec.Unmarshal(res, &value))
+	assert.Equal(t, v, value)
+}
+
+func Test_NewSnapshotCodec(t *testing.T) {
+	codec := NewSnapshotCodec[string, int](nil, nil)
+	assert.Equal(t, codecSnapshotCodec[string, int]{
+		keyCodec:   GobCodec[string]{},
+		valueCodec: GobCodec[int]{},
+	}, codec)
+
+	codec = NewSnapshotCodec[string, int](BinaryCodec[string]{}, JSONCodec[int]{})
+	assert.Equal(t, codecSnapshotCodec[string, int]{
+		keyCodec:   BinaryCodec[string]{},
+		valueCodec: JSONCodec[int]{},
+	}, codec)
+}
+
+func Test_codecSnapshotCodec(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		codec = NewSnapshotCodec[string, int](BinaryCodec[string]{}, BinaryCodec[int]{})
+		items = []SnapshotItem[string, int]{
+			{Key: "1", Value: 1, TTL: time.Hour, ExpiresAt: time.Now().Add(time.Hour).Round(0), Version: 2, Cost: 3},
+			{Key: "2", Value: -2, TTL: NoTTL},
+			{Key: "", Value: 0, TTL: DefaultTTL, Deleted: true},
+		}
+	)
+
+	enc := codec.NewEncoder(&buf)
+	for _, item := range items {
+		require.NoError(t, enc.Encode(item))
+	}
+
+	snapshot := buf.Bytes()
+
+	dec := codec.NewDecoder(bytes.NewReader(snapshot))
+	for _, item := range items {
+		res, err := dec.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, item.Key, res.Key)
+		assert.Equal(t, item.Value, res.Value)
+		assert.Equal(t, item.TTL, res.TTL)
+		assert.True(t, item.ExpiresAt.Equal(res.ExpiresAt))
+		assert.Equal(t, item.ExpiresAt.IsZero(), res.ExpiresAt.IsZero())
+		assert.Equal(t, item.Version, res.Version)
+		assert.Equal(t, item.Cost, res.Cost)
+		assert.Equal(t, item.Deleted, res.Deleted)
+	}
+
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// truncated record
+	for i := 1; i < len(snapshot); i++ {
+		dec = codec.NewDecoder(bytes.NewReader(snapshot[:i]))
+
+		err = nil
+		for err == nil {
+			_, err = dec.Decode()
+		}
+
+		assert.Contains(t, []error{io.EOF, io.ErrUnexpectedEOF}, err)
+	}
+
+	dec = codec.NewDecoder(bytes.NewReader(snapshot[:len(snapshot)-1]))
+	_, err = dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// the header is written only once
+	header := append([]byte("ttlc"), snapshotFormatVersion)
+	assert.Equal(t, header, snapshot[:len(header)])
+	assert.Equal(t, 1, bytes.Count(snapshot, header))
+
+	// empty snapshot
+	dec = codec.NewDecoder(bytes.NewReader(nil))
+	_, err = dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// missing header
+	var noHeader []byte
+	for _, item := range items[:2] {
+		noHeader, err = codec.(codecSnapshotCodec[string, int]).appendItem(noHeader, item)
+		require.NoError(t, err)
+	}
+
+	dec = codec.NewDecoder(bytes.NewReader(noHeader))
+	_, err = dec.Decode()
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// unsupported version
+	dec = codec.NewDecoder(bytes.NewReader([]byte{'t', 't', 'l', 'c', snapshotFormatVersion + 1}))
+	_, err = dec.Decode()
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// corrupted length
+	dec = codec.NewDecoder(bytes.NewReader(append(header, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)))
+	_, err = dec.Decode()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// failed marshal
+	errMarshal := errors.New("error")
+
+	enc = NewSnapshotCodec[string, int](failingCodec[string]{err: errMarshal}, nil).NewEncoder(&buf)
+	assert.Equal(t, errMarshal, enc.Encode(items[0]))
+
+	enc = NewSnapshotCodec[string, int](nil, failingCodec[int]{err: errMarshal}).NewEncoder(&buf)
+	assert.Equal(t, errMarshal, enc.Encode(items[0]))
+
+	// failed unmarshal
+	dec = NewSnapshotCodec[string, int](failingCodec[string]{err: errMarshal}, nil).NewDecoder(bytes.NewReader(snapshot))
+	_, err = dec.Decode()
+	assert.Equal(t, errMarshal, err)
+
+	// failed write
+	errWrite := errors.New("error")
+	enc = codec.NewEncoder(failingWriter{err: errWrite})
+	assert.Equal(t, errWrite, enc.Encode(items[0]))
+}
+
+// failingCodec is a Codec that always fails.
+type failingCodec[T any] struct {
+	err error
+}
+
+func (c failingCodec[T]) Marshal(_ T) ([]byte, error) {
+	return nil, c.err
+}
+
+func (c failingCodec[T]) Unmarshal(_ []byte, _ *T) error {
+	return c.err
+}
+
+func Test_readRecordBytes(t *testing.T) {
+	data := make([]byte, recordChunkSize*3+5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// the buffer is reused
+	buf := make([]byte, 0, 10)
+	require.NoError(t, readRecordBytes(bufio.NewReader(bytes.NewReader(data)), &buf, 10))
+	assert.Equal(t, data[:10], buf)
+	assert.Equal(t, 10, cap(buf))
+
+	// the buffer grows along with the read data
+	require.NoError(t, readRecordBytes(bufio.NewReader(bytes.NewReader(data)), &buf, uint64(len(data))))
+	assert.Equal(t, data, buf)
+
+	// lengths that exceed the data do not allocate
+	buf = nil
+	err := readRecordBytes(bufio.NewReader(bytes.NewReader(data)), &buf, math.MaxUint64)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.LessOrEqual(t, cap(buf), len(data)*3)
+
+	// the remaining length is checked if it is known
+	err = readRecordBytes(bytes.NewReader(data), &buf, uint64(len(data)+1))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
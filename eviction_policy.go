@@ -0,0 +1,114 @@
+package ttlcache
+
+import "container/list"
+
+// EvictionPolicy decides which item should be evicted from the cache
+// when its capacity is reached.
+// All methods are called while the cache's items are locked, so
+// implementations do not need to be concurrently safe. However, a
+// single instance must not be shared between multiple caches.
+type EvictionPolicy[K comparable, V any] interface {
+	// OnInsert is called after a new item is added to the cache.
+	OnInsert(item *Item[K, V])
+
+	// OnAccess is called when an existing item is retrieved or
+	// touched.
+	OnAccess(item *Item[K, V])
+
+	// OnUpdate is called when an existing item's value or TTL is
+	// overwritten.
+	OnUpdate(item *Item[K, V])
+
+	// OnRemove is called after an item is removed from the cache for
+	// the provided reason.
+	OnRemove(item *Item[K, V], reason EvictionReason)
+
+	// Victim returns the item that should be evicted next.
+	// It may return nil if the policy does not track any items.
+	Victim() *Item[K, V]
+}
+
+// LRUPolicy is an eviction policy that evicts the least recently
+// used item first. It is used by default.
+type LRUPolicy[K comparable, V any] struct {
+	// a generic doubly linked list would be more convenient
+	// (and more performant?). It's possible that this
+	// will be introduced with/in go1.19+
+	list  *list.List
+	elems map[K]*list.Element
+}
+
+// NewLRUPolicy creates a new instance of LRU eviction policy.
+func NewLRUPolicy[K comparable, V any]() *LRUPolicy[K, V] {
+	return &LRUPolicy[K, V]{
+		list:  list.New(),
+		elems: make(map[K]*list.Element),
+	}
+}
+
+// OnInsert places the item at the front of the list.
+func (p *LRUPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	p.elems[item.key] = p.list.PushFront(item)
+}
+
+// OnAccess moves the item to the front of the list.
+func (p *LRUPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	if elem := p.elems[item.key]; elem != nil {
+		p.list.MoveToFront(elem)
+	}
+}
+
+// OnUpdate moves the item to the front of the list.
+func (p *LRUPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	p.OnAccess(item)
+}
+
+// OnRemove removes the item from the list.
+func (p *LRUPolicy[K, V]) OnRemove(item *Item[K, V], _ EvictionReason) {
+	if elem := p.elems[item.key]; elem != nil {
+		p.list.Remove(elem)
+		delete(p.elems, item.key)
+	}
+}
+
+// Victim returns the least recently used item.
+func (p *LRUPolicy[K, V]) Victim() *Item[K, V] {
+	elem := p.list.Back()
+	if elem == nil {
+		return nil
+	}
+
+	return elem.Value.(*Item[K, V])
+}
+
+// each calls fn for every tracked item, starting with the most
+// recently used one, until fn returns false.
+func (p *LRUPolicy[K, V]) each(fn func(*Item[K, V]) bool) {
+	for elem := p.list.Front(); elem != nil; elem = elem.Next() {
+		if !fn(elem.Value.(*Item[K, V])) {
+			return
+		}
+	}
+}
+
+// orderedPolicy is implemented by eviction policies that are able to
+// iterate over their items in a meaningful order.
+type orderedPolicy[K comparable, V any] interface {
+	each(fn func(*Item[K, V]) bool)
+}
+
+// ConcurrentAccessPolicy is an EvictionPolicy that is able to record
+// item accesses while the cache's items are only read-locked.
+// When such a policy is used, Get does not lock the cache exclusively
+// on a hit. The extensions of the items' expiration timestamps are
+// buffered instead and applied in batches (see WithAccessBuffer), so
+// they are only eventually consistent.
+type ConcurrentAccessPolicy[K comparable, V any] interface {
+	EvictionPolicy[K, V]
+
+	// OnConcurrentAccess is called instead of OnAccess when an
+	// existing item is retrieved while the cache's items are
+	// read-locked. It may be called concurrently with itself, but
+	// never concurrently with any other method of the policy.
+	OnConcurrentAccess(item *Item[K, V])
+}
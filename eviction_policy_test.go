@@ -0,0 +1,136 @@
+package ttlcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	require.NotNil(t, p)
+	assert.NotNil(t, p.list)
+	assert.NotNil(t, p.elems)
+}
+
+func Test_LRUPolicy_OnInsert(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	p.OnInsert(&Item[string, string]{key: "1"})
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	assert.Len(t, p.elems, 2)
+	assert.Equal(t, "2", p.list.Front().Value.(*Item[string, string]).key)
+	assert.Equal(t, "1", p.list.Back().Value.(*Item[string, string]).key)
+}
+
+func Test_LRUPolicy_OnAccess(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	p.OnAccess(item)
+	assert.Equal(t, "1", p.list.Front().Value.(*Item[string, string]).key)
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "3"})
+	assert.Equal(t, 2, p.list.Len())
+}
+
+func Test_LRUPolicy_OnUpdate(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	p.OnUpdate(item)
+	assert.Equal(t, "1", p.list.Front().Value.(*Item[string, string]).key)
+}
+
+func Test_LRUPolicy_OnRemove(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	p.OnRemove(item, EvictionReasonDeleted)
+	assert.Equal(t, 1, p.list.Len())
+	assert.NotContains(t, p.elems, "1")
+
+	// unknown items are ignored
+	p.OnRemove(item, EvictionReasonDeleted)
+	assert.Equal(t, 1, p.list.Len())
+}
+
+func Test_LRUPolicy_Victim(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	assert.Nil(t, p.Victim())
+
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+	assert.Same(t, item, p.Victim())
+}
+
+func Test_LRUPolicy_each(t *testing.T) {
+	p := NewLRUPolicy[string, string]()
+	p.OnInsert(&Item[string, string]{key: "1"})
+	p.OnInsert(&Item[string, string]{key: "2"})
+	p.OnInsert(&Item[string, string]{key: "3"})
+
+	var keys []string
+	p.each(func(item *Item[string, string]) bool {
+		keys = append(keys, item.key)
+		return item.key != "2"
+	})
+	assert.Equal(t, []string{"3", "2"}, keys)
+}
+
+func Test_Cache_customEvictionPolicy(t *testing.T) {
+	p := &fifoPolicy{}
+	cache := New[string, string](
+		WithCapacity[string, string](2),
+		WithEvictionPolicy[string, string](p),
+	)
+
+	cache.Set("1", "1", NoTTL)
+	cache.Set("2", "2", NoTTL)
+	cache.Get("1")
+	cache.Set("3", "3", NoTTL)
+
+	// a FIFO policy ignores accesses, so the first key is evicted
+	assert.ElementsMatch(t, []string{"2", "3"}, cache.Keys())
+	assert.Len(t, p.items, 2)
+}
+
+// fifoPolicy is a minimal EvictionPolicy implementation that evicts
+// items in their insertion order.
+type fifoPolicy struct {
+	items []*Item[string, string]
+}
+
+func (p *fifoPolicy) OnInsert(item *Item[string, string]) {
+	p.items = append(p.items, item)
+}
+
+func (p *fifoPolicy) OnAccess(_ *Item[string, string]) {}
+
+func (p *fifoPolicy) OnUpdate(_ *Item[string, string]) {}
+
+func (p *fifoPolicy) OnRemove(item *Item[string, string], _ EvictionReason) {
+	for i := range p.items {
+		if p.items[i] == item {
+			p.items = append(p.items[:i], p.items[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *fifoPolicy) Victim() *Item[string, string] {
+	if len(p.items) == 0 {
+		return nil
+	}
+
+	return p.items[0]
+}
@@ -2,12 +2,11 @@ package ttlcache
 
 import (
 	"container/heap"
-	"container/list"
 )
 
 // expirationQueue stores items that are ordered by their expiration
 // timestamps. The 0th item is closest to its expiration.
-type expirationQueue[K comparable, V any] []*list.Element
+type expirationQueue[K comparable, V any] []*Item[K, V]
 
 // newExpirationQueue creates and initializes a new expiration queue.
 func newExpirationQueue[K comparable, V any]() expirationQueue[K, V] {
@@ -22,20 +21,20 @@ func (q expirationQueue[K, V]) isEmpty() bool {
 }
 
 // update updates an existing item's value and position in the queue.
-func (q *expirationQueue[K, V]) update(elem *list.Element) {
-	heap.Fix(q, elem.Value.(*Item[K, V]).queueIndex)
+func (q *expirationQueue[K, V]) update(item *Item[K, V]) {
+	heap.Fix(q, item.queueIndex)
 }
 
 // push pushes a new item into the queue and updates the order of its
 // elements.
-func (q *expirationQueue[K, V]) push(elem *list.Element) {
-	heap.Push(q, elem)
+func (q *expirationQueue[K, V]) push(item *Item[K, V]) {
+	heap.Push(q, item)
 }
 
 // remove removes an item from the queue and updates the order of its
 // elements.
-func (q *expirationQueue[K, V]) remove(elem *list.Element) {
-	heap.Remove(q, elem.Value.(*Item[K, V]).queueIndex)
+func (q *expirationQueue[K, V]) remove(item *Item[K, V]) {
+	heap.Remove(q, item.queueIndex)
 }
 
 // Len returns the total number of items in the queue.
@@ -46,7 +45,7 @@ func (q expirationQueue[K, V]) Len() int {
 // Less checks if the item at the i position expires sooner than
 // the one at the j position.
 func (q expirationQueue[K, V]) Less(i, j int) bool {
-	item1, item2 := q[i].Value.(*Item[K, V]), q[j].Value.(*Item[K, V])
+	item1, item2 := q[i], q[j]
 	if item1.expiresAt.IsZero() {
 		return false
 	}
@@ -61,25 +60,25 @@ func (q expirationQueue[K, V]) Less(i, j int) bool {
 // Swap switches the places of two queue items.
 func (q expirationQueue[K, V]) Swap(i, j int) {
 	q[i], q[j] = q[j], q[i]
-	q[i].Value.(*Item[K, V]).queueIndex = i
-	q[j].Value.(*Item[K, V]).queueIndex = j
+	q[i].queueIndex = i
+	q[j].queueIndex = j
 }
 
 // Push appends a new item to the item slice.
 func (q *expirationQueue[K, V]) Push(x interface{}) {
-	elem := x.(*list.Element)
-	elem.Value.(*Item[K, V]).queueIndex = len(*q)
-	*q = append(*q, elem)
+	item := x.(*Item[K, V])
+	item.queueIndex = len(*q)
+	*q = append(*q, item)
 }
 
 // Pop removes and returns the last item.
 func (q *expirationQueue[K, V]) Pop() interface{} {
 	old := *q
 	i := len(old) - 1
-	elem := old[i]
-	elem.Value.(*Item[K, V]).queueIndex = -1
+	item := old[i]
+	item.queueIndex = -1
 	old[i] = nil // avoid memory leak
 	*q = old[:i]
 
-	return elem
+	return item
 }
@@ -1,7 +1,6 @@
 package ttlcache
 
 import (
-	"container/list"
 	"testing"
 	"time"
 
@@ -21,70 +20,58 @@ func Test_expirationQueue_isEmpty(t *testing.T) {
 func Test_expirationQueue_update(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test2",
-				queueIndex: 1,
-				expiresAt:  time.Now().Add(time.Minute),
-			},
+			value:      "test2",
+			queueIndex: 1,
+			expiresAt:  time.Now().Add(time.Minute),
 		},
 	}
 
 	q.update(q[1])
 	require.Len(t, q, 2)
-	assert.Equal(t, "test2", q[0].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test2", q[0].value)
 }
 
 func Test_expirationQueue_push(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 	}
-	elem := &list.Element{
-		Value: &Item[string, string]{
-			value:      "test2",
-			queueIndex: 1,
-			expiresAt:  time.Now().Add(time.Minute),
-		},
+	item := &Item[string, string]{
+		value:      "test2",
+		queueIndex: 1,
+		expiresAt:  time.Now().Add(time.Minute),
 	}
 
-	q.push(elem)
+	q.push(item)
 	require.Len(t, q, 2)
-	assert.Equal(t, "test2", q[0].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test2", q[0].value)
 }
 
 func Test_expirationQueue_remove(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test2",
-				queueIndex: 1,
-				expiresAt:  time.Now().Add(time.Minute),
-			},
+			value:      "test2",
+			queueIndex: 1,
+			expiresAt:  time.Now().Add(time.Minute),
 		},
 	}
 
 	q.remove(q[1])
 	require.Len(t, q, 1)
-	assert.Equal(t, "test1", q[0].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test1", q[0].value)
 }
 
 func Test_expirationQueue_Len(t *testing.T) {
@@ -94,24 +81,18 @@ func Test_expirationQueue_Len(t *testing.T) {
 func Test_expirationQueue_Less(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test2",
-				queueIndex: 1,
-				expiresAt:  time.Now().Add(time.Minute),
-			},
+			value:      "test2",
+			queueIndex: 1,
+			expiresAt:  time.Now().Add(time.Minute),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test3",
-				queueIndex: 2,
-			},
+			value:      "test3",
+			queueIndex: 2,
 		},
 	}
 
@@ -124,71 +105,59 @@ func Test_expirationQueue_Less(t *testing.T) {
 func Test_expirationQueue_Swap(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test2",
-				queueIndex: 1,
-				expiresAt:  time.Now().Add(time.Minute),
-			},
+			value:      "test2",
+			queueIndex: 1,
+			expiresAt:  time.Now().Add(time.Minute),
 		},
 	}
 
 	q.Swap(0, 1)
-	assert.Equal(t, "test2", q[0].Value.(*Item[string, string]).value)
-	assert.Equal(t, "test1", q[1].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test2", q[0].value)
+	assert.Equal(t, "test1", q[1].value)
 }
 
 func Test_expirationQueue_Push(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 	}
 
-	elem := &list.Element{
-		Value: &Item[string, string]{
-			value:      "test2",
-			queueIndex: 1,
-			expiresAt:  time.Now().Add(time.Minute),
-		},
+	item := &Item[string, string]{
+		value:      "test2",
+		queueIndex: 1,
+		expiresAt:  time.Now().Add(time.Minute),
 	}
 
-	q.Push(elem)
+	q.Push(item)
 	require.Len(t, q, 2)
-	assert.Equal(t, "test2", q[1].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test2", q[1].value)
 }
 
 func Test_expirationQueue_Pop(t *testing.T) {
 	q := expirationQueue[string, string]{
 		{
-			Value: &Item[string, string]{
-				value:      "test1",
-				queueIndex: 0,
-				expiresAt:  time.Now().Add(time.Hour),
-			},
+			value:      "test1",
+			queueIndex: 0,
+			expiresAt:  time.Now().Add(time.Hour),
 		},
 		{
-			Value: &Item[string, string]{
-				value:      "test2",
-				queueIndex: 1,
-				expiresAt:  time.Now().Add(time.Minute),
-			},
+			value:      "test2",
+			queueIndex: 1,
+			expiresAt:  time.Now().Add(time.Minute),
 		},
 	}
 
 	v := q.Pop()
 	require.NotNil(t, v)
-	assert.Equal(t, "test2", v.(*list.Element).Value.(*Item[string, string]).value)
+	assert.Equal(t, "test2", v.(*Item[string, string]).value)
 	require.Len(t, q, 1)
-	assert.Equal(t, "test1", q[0].Value.(*Item[string, string]).value)
+	assert.Equal(t, "test1", q[0].value)
 }
@@ -0,0 +1,122 @@
+package ttlcache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// hashSeed is the seed that is used to hash all keys of the
+// current process.
+var hashSeed = maphash.MakeSeed()
+
+// hashKey returns a 64-bit hash of the provided key.
+// Keys of common primitive types are hashed directly, while all
+// other keys are hashed by their values, field by field, so that
+// keys that are equal always have the same hash. Pointers and
+// channels are hashed by their identity rather than by the values
+// they point to.
+func hashKey[K comparable](key K) uint64 {
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+
+	switch k := any(key).(type) {
+	case string:
+		h.WriteString(k)
+	case int:
+		writeUint64(&h, uint64(k))
+	case int8:
+		writeUint64(&h, uint64(k))
+	case int16:
+		writeUint64(&h, uint64(k))
+	case int32:
+		writeUint64(&h, uint64(k))
+	case int64:
+		writeUint64(&h, uint64(k))
+	case uint:
+		writeUint64(&h, uint64(k))
+	case uint8:
+		writeUint64(&h, uint64(k))
+	case uint16:
+		writeUint64(&h, uint64(k))
+	case uint32:
+		writeUint64(&h, uint64(k))
+	case uint64:
+		writeUint64(&h, k)
+	case uintptr:
+		writeUint64(&h, uint64(k))
+	default:
+		writeKey(&h, key)
+	}
+
+	return h.Sum64()
+}
+
+// writeKey writes the provided key of any other type into the hash.
+// It is a separate function, so that keys of primitive types do not
+// have to be converted into reflection values, which allocates.
+func writeKey[K comparable](h *maphash.Hash, key K) {
+	writeValue(h, reflect.ValueOf(key))
+}
+
+// writeValue writes the provided comparable value into the hash.
+func writeValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		// nil interface
+		h.WriteByte(0)
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat64(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat64(h, real(c))
+		writeFloat64(h, imag(c))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		writeValue(h, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			writeValue(h, v.Field(i))
+		}
+	default:
+		// values of other kinds are not comparable
+		panic("ttlcache: unhashable key type " + v.Type().String())
+	}
+}
+
+// writeFloat64 writes the bits of the provided value into the hash.
+// Positive and negative zeros are written the same way, since they
+// are equal.
+func writeFloat64(h *maphash.Hash, v float64) {
+	if v == 0 {
+		v = 0
+	}
+
+	writeUint64(h, math.Float64bits(v))
+}
+
+// writeUint64 writes the little endian representation of the
+// provided value into the hash.
+func writeUint64(h *maphash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	h.Write(b[:])
+}
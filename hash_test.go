@@ -0,0 +1,74 @@
+package ttlcache
+
+import (
+	"hash/maphash"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_hashKey(t *testing.T) {
+	assert.Equal(t, hashKey("test"), hashKey("test"))
+	assert.NotEqual(t, hashKey("test"), hashKey("test1"))
+
+	assert.Equal(t, hashKey(123), hashKey(123))
+	assert.NotEqual(t, hashKey(123), hashKey(124))
+	assert.Equal(t, hashKey(uint64(123)), hashKey(uint64(123)))
+
+	type key struct {
+		a int
+		b string
+	}
+
+	assert.Equal(t, hashKey(key{a: 1, b: "1"}), hashKey(key{a: 1, b: "1"}))
+	assert.NotEqual(t, hashKey(key{a: 1, b: "1"}), hashKey(key{a: 1, b: "2"}))
+
+	// pointers are hashed by their identity
+	p := &key{a: 1}
+	h := hashKey(p)
+	p.a = 2
+	assert.Equal(t, h, hashKey(p))
+	assert.NotEqual(t, h, hashKey(&key{a: 2}))
+
+	// equal floats have the same hash
+	assert.Equal(t, hashKey(0.0), hashKey(math.Copysign(0, -1)))
+	assert.Equal(t, hashKey([2]float32{1, 0}), hashKey([2]float32{1, float32(math.Copysign(0, -1))}))
+	assert.Equal(t, hashKey(complex(0, 1)), hashKey(complex(math.Copysign(0, -1), 1)))
+
+	type namedInt int
+	assert.Equal(t, hashKey(namedInt(1)), hashKey(namedInt(1)))
+	assert.NotEqual(t, hashKey(namedInt(1)), hashKey(namedInt(2)))
+}
+
+func Test_writeValue(t *testing.T) {
+	hash := func(v interface{}) uint64 {
+		var h maphash.Hash
+		h.SetSeed(hashSeed)
+		writeValue(&h, reflect.ValueOf(&v).Elem())
+
+		return h.Sum64()
+	}
+
+	// interfaces are hashed by their dynamic values
+	assert.Equal(t, hash(1), hash(1))
+	assert.Equal(t, hash(nil), hash(nil))
+	assert.NotEqual(t, hash(true), hash(false))
+	assert.Equal(t, hashKey(uint16(1)), hash(uint16(1)))
+
+	// non-comparable values cannot be hashed
+	assert.Panics(t, func() {
+		hash([]int{1})
+	})
+}
+
+func Test_writeUint64(t *testing.T) {
+	var h1, h2 maphash.Hash
+	h1.SetSeed(hashSeed)
+	h2.SetSeed(hashSeed)
+
+	writeUint64(&h1, 1)
+	h2.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+	assert.Equal(t, h2.Sum64(), h1.Sum64())
+}
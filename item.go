@@ -14,6 +14,16 @@ const (
 	DefaultTTL time.Duration = 0
 )
 
+const (
+	// refreshBackoffMin is the time after the first failed
+	// background refresh of an item before it is refreshed again.
+	refreshBackoffMin = time.Second
+
+	// refreshBackoffMax is the maximum time between failed
+	// background refreshes of an item.
+	refreshBackoffMax = time.Minute
+)
+
 // Item holds all the information that is associated with a single
 // cache value.
 type Item[K comparable, V any] struct {
@@ -31,9 +41,27 @@ type Item[K comparable, V any] struct {
 	value              V
 	ttl                time.Duration
 	expiresAt          time.Time
+	updatedAt          time.Time
 	queueIndex         int
+	wheelSlot          *timerSlot[K, V]
+	wheelPrev          *Item[K, V]
+	wheelNext          *Item[K, V]
+	cost               uint64
 	version            int64
 	enableVersionTrack bool
+
+	// saved reports whether the item was written by SaveSince, while
+	// savedVersion and savedExpiresAt are its version and expiration
+	// timestamp at the time it was last written.
+	saved          bool
+	savedVersion   int64
+	savedExpiresAt time.Time
+
+	// refreshFailures is the number of consecutive failed
+	// background refreshes, and refreshRetryAt is the time before
+	// which the item is not refreshed again.
+	refreshFailures int
+	refreshRetryAt  time.Time
 }
 
 // newItem creates a new cache item.
@@ -42,6 +70,7 @@ func newItem[K comparable, V any](key K, value V, ttl time.Duration, enableVersi
 		key:                key,
 		value:              value,
 		ttl:                ttl,
+		updatedAt:          time.Now(),
 		enableVersionTrack: enableVersionTrack,
 	}
 	if !enableVersionTrack {
@@ -59,18 +88,60 @@ func (item *Item[K, V]) update(value V, ttl time.Duration) {
 
 	item.value = value
 	item.ttl = ttl
+	item.updatedAt = time.Now()
 
 	// reset expiration timestamp because the new TTL may be
 	// 0 or below
 	item.expiresAt = time.Time{}
 	item.touchUnsafe()
 
+	// the new value does not need to wait for earlier failures
+	item.refreshFailures = 0
+	item.refreshRetryAt = time.Time{}
+
 	// update version if it is enabled.
 	if item.enableVersionTrack {
 		item.version++
 	}
 }
 
+// updateCost modifies the item's cost.
+func (item *Item[K, V]) updateCost(cost uint64) {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+
+	item.cost = cost
+}
+
+// refreshFailed records a failed background refresh of the item and
+// postpones the next one. The delay doubles with every consecutive
+// failure.
+func (item *Item[K, V]) refreshFailed() {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+
+	backoff := refreshBackoffMin
+	for i := 0; i < item.refreshFailures && backoff < refreshBackoffMax; i++ {
+		backoff *= 2
+	}
+
+	if backoff > refreshBackoffMax {
+		backoff = refreshBackoffMax
+	}
+
+	item.refreshFailures++
+	item.refreshRetryAt = time.Now().Add(backoff)
+}
+
+// canRefresh checks if enough time has passed since the last failed
+// background refresh of the item.
+func (item *Item[K, V]) canRefresh() bool {
+	item.mu.RLock()
+	defer item.mu.RUnlock()
+
+	return !time.Now().Before(item.refreshRetryAt)
+}
+
 // touch updates the item's expiration timestamp.
 func (item *Item[K, V]) touch() {
 	item.mu.Lock()
@@ -91,6 +162,8 @@ func (item *Item[K, V]) touchUnsafe() {
 
 // IsExpired returns a bool value that indicates whether the item
 // is expired.
+// Stale items that are returned by the cache during its grace period
+// are expired as well.
 func (item *Item[K, V]) IsExpired() bool {
 	item.mu.RLock()
 	defer item.mu.RUnlock()
@@ -108,6 +181,15 @@ func (item *Item[K, V]) isExpiredUnsafe() bool {
 	return item.expiresAt.Before(time.Now())
 }
 
+// changedSinceSave returns a bool value that indicates whether the
+// item was changed or touched since it was last written by SaveSince.
+// Not concurrently safe.
+func (item *Item[K, V]) changedSinceSave() bool {
+	return !item.saved ||
+		item.version != item.savedVersion ||
+		!item.expiresAt.Equal(item.savedExpiresAt)
+}
+
 // Key returns the key of the item.
 func (item *Item[K, V]) Key() K {
 	item.mu.RLock()
@@ -140,6 +222,23 @@ func (item *Item[K, V]) ExpiresAt() time.Time {
 	return item.expiresAt
 }
 
+// UpdatedAt returns the timestamp of the item's creation or of its
+// last value update.
+func (item *Item[K, V]) UpdatedAt() time.Time {
+	item.mu.RLock()
+	defer item.mu.RUnlock()
+
+	return item.updatedAt
+}
+
+// Cost returns the cost of the item.
+func (item *Item[K, V]) Cost() uint64 {
+	item.mu.RLock()
+	defer item.mu.RUnlock()
+
+	return item.cost
+}
+
 // Version returns the version of the item. Version shows the total number of
 // changes made to the item.
 func (item *Item[K, V]) Version() int64 {
@@ -17,6 +17,7 @@ func Test_newItem(t *testing.T) {
 	assert.Equal(t, false, item.enableVersionTrack)
 	assert.Equal(t, int64(-1), item.version)
 	assert.WithinDuration(t, time.Now().Add(time.Hour), item.expiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now(), item.updatedAt, time.Minute)
 }
 
 func Test_Item_update(t *testing.T) {
@@ -31,12 +32,39 @@ func Test_Item_update(t *testing.T) {
 	assert.Equal(t, time.Hour, item.ttl)
 	assert.Equal(t, int64(1), item.version)
 	assert.WithinDuration(t, time.Now().Add(time.Hour), item.expiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now(), item.updatedAt, time.Minute)
 
 	item.update("hi", NoTTL)
 	assert.Equal(t, "hi", item.value)
 	assert.Equal(t, NoTTL, item.ttl)
 	assert.Equal(t, int64(2), item.version)
 	assert.Zero(t, item.expiresAt)
+
+	// refresh failures are forgotten
+	item.refreshFailed()
+	item.update("hey", NoTTL)
+	assert.Zero(t, item.refreshFailures)
+	assert.True(t, item.canRefresh())
+}
+
+func Test_Item_refreshFailed(t *testing.T) {
+	var item Item[string, string]
+	assert.True(t, item.canRefresh())
+
+	item.refreshFailed()
+	assert.Equal(t, 1, item.refreshFailures)
+	assert.WithinDuration(t, time.Now().Add(refreshBackoffMin), item.refreshRetryAt, time.Second/2)
+	assert.False(t, item.canRefresh())
+
+	item.refreshFailed()
+	assert.WithinDuration(t, time.Now().Add(refreshBackoffMin*2), item.refreshRetryAt, time.Second/2)
+
+	item.refreshFailures = 100
+	item.refreshFailed()
+	assert.WithinDuration(t, time.Now().Add(refreshBackoffMax), item.refreshRetryAt, time.Second/2)
+
+	item.refreshRetryAt = time.Now().Add(-time.Millisecond)
+	assert.True(t, item.canRefresh())
 }
 
 func Test_Item_touch(t *testing.T) {
@@ -105,3 +133,20 @@ func Test_Item_Version(t *testing.T) {
 	item := Item[string, string]{version: 5}
 	assert.Equal(t, int64(5), item.Version())
 }
+
+func Test_Item_updateCost(t *testing.T) {
+	item := Item[string, string]{cost: 1}
+	item.updateCost(5)
+	assert.Equal(t, uint64(5), item.cost)
+}
+
+func Test_Item_UpdatedAt(t *testing.T) {
+	now := time.Now()
+	item := Item[string, string]{updatedAt: now}
+	assert.Equal(t, now, item.UpdatedAt())
+}
+
+func Test_Item_Cost(t *testing.T) {
+	item := Item[string, string]{cost: 5}
+	assert.Equal(t, uint64(5), item.Cost())
+}
@@ -0,0 +1,180 @@
+package ttlcache
+
+import "container/list"
+
+// LFUPolicy is an eviction policy that evicts the least frequently
+// used item first. Items with the same frequency are evicted in the
+// least recently used order.
+// All frequencies are periodically halved, so that items that were
+// popular a long time ago eventually become evictable.
+type LFUPolicy[K comparable, V any] struct {
+	// freqs contains *lfuBucket values ordered by their frequency,
+	// starting with the lowest one.
+	freqs   *list.List
+	entries map[K]*lfuEntry[K, V]
+
+	agingPeriod uint64
+	ops         uint64
+}
+
+// lfuBucket holds all items that have the same access frequency.
+type lfuBucket struct {
+	freq  uint64
+	items *list.List
+}
+
+// lfuEntry holds the position of a single item inside of the
+// frequency buckets.
+type lfuEntry[K comparable, V any] struct {
+	bucket *list.Element
+	elem   *list.Element
+}
+
+// NewLFUPolicy creates a new instance of LFU eviction policy.
+// The agingPeriod parameter specifies the number of insertions and
+// accesses after which all frequencies are halved. If it is 0,
+// frequencies are never decayed.
+func NewLFUPolicy[K comparable, V any](agingPeriod uint64) *LFUPolicy[K, V] {
+	return &LFUPolicy[K, V]{
+		freqs:       list.New(),
+		entries:     make(map[K]*lfuEntry[K, V]),
+		agingPeriod: agingPeriod,
+	}
+}
+
+// OnInsert places the item into the lowest frequency bucket.
+func (p *LFUPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	bucket := p.freqs.Front()
+	if bucket == nil || bucket.Value.(*lfuBucket).freq != 1 {
+		bucket = p.freqs.PushFront(&lfuBucket{freq: 1, items: list.New()})
+	}
+
+	p.entries[item.key] = &lfuEntry[K, V]{
+		bucket: bucket,
+		elem:   bucket.Value.(*lfuBucket).items.PushFront(item),
+	}
+
+	p.tick()
+}
+
+// OnAccess increments the item's frequency.
+func (p *LFUPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	entry := p.entries[item.key]
+	if entry == nil {
+		return
+	}
+
+	cur := entry.bucket.Value.(*lfuBucket)
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != cur.freq+1 {
+		next = p.freqs.InsertAfter(&lfuBucket{freq: cur.freq + 1, items: list.New()}, entry.bucket)
+	}
+
+	cur.items.Remove(entry.elem)
+	if cur.items.Len() == 0 {
+		p.freqs.Remove(entry.bucket)
+	}
+
+	entry.bucket = next
+	entry.elem = next.Value.(*lfuBucket).items.PushFront(item)
+
+	p.tick()
+}
+
+// OnUpdate increments the item's frequency.
+func (p *LFUPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	p.OnAccess(item)
+}
+
+// OnRemove removes the item from its frequency bucket.
+func (p *LFUPolicy[K, V]) OnRemove(item *Item[K, V], _ EvictionReason) {
+	entry := p.entries[item.key]
+	if entry == nil {
+		return
+	}
+
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.items.Remove(entry.elem)
+	if bucket.items.Len() == 0 {
+		p.freqs.Remove(entry.bucket)
+	}
+
+	delete(p.entries, item.key)
+}
+
+// Victim returns the least recently used item out of the least
+// frequently used ones.
+func (p *LFUPolicy[K, V]) Victim() *Item[K, V] {
+	bucket := p.freqs.Front()
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.Value.(*lfuBucket).items.Back().Value.(*Item[K, V])
+}
+
+// each calls fn for every tracked item, starting with the most
+// frequently used one, until fn returns false.
+func (p *LFUPolicy[K, V]) each(fn func(*Item[K, V]) bool) {
+	for bucket := p.freqs.Back(); bucket != nil; bucket = bucket.Prev() {
+		items := bucket.Value.(*lfuBucket).items
+		for elem := items.Front(); elem != nil; elem = elem.Next() {
+			if !fn(elem.Value.(*Item[K, V])) {
+				return
+			}
+		}
+	}
+}
+
+// tick registers a single operation and ages the frequencies
+// if the aging period has elapsed.
+func (p *LFUPolicy[K, V]) tick() {
+	if p.agingPeriod == 0 {
+		return
+	}
+
+	p.ops++
+	if p.ops < p.agingPeriod {
+		return
+	}
+
+	p.ops = 0
+	p.age()
+}
+
+// age halves the frequencies of all items and merges the buckets
+// whose frequencies become equal.
+func (p *LFUPolicy[K, V]) age() {
+	var prev *list.Element
+
+	for bucket := p.freqs.Front(); bucket != nil; {
+		next := bucket.Next()
+		b := bucket.Value.(*lfuBucket)
+
+		b.freq /= 2
+		if b.freq == 0 {
+			b.freq = 1
+		}
+
+		if prev == nil || prev.Value.(*lfuBucket).freq != b.freq {
+			prev = bucket
+			bucket = next
+
+			continue
+		}
+
+		// merge into the previous bucket; the items of this bucket
+		// were used more frequently, so they are placed in front
+		// of the previous bucket's items
+		pb := prev.Value.(*lfuBucket)
+		for elem := b.items.Back(); elem != nil; elem = elem.Prev() {
+			item := elem.Value.(*Item[K, V])
+			entry := p.entries[item.key]
+			entry.bucket = prev
+			entry.elem = pb.items.PushFront(item)
+		}
+
+		p.freqs.Remove(bucket)
+		bucket = next
+	}
+}
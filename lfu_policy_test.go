@@ -0,0 +1,200 @@
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy[string, string](10)
+	require.NotNil(t, p)
+	assert.NotNil(t, p.freqs)
+	assert.NotNil(t, p.entries)
+	assert.Equal(t, uint64(10), p.agingPeriod)
+}
+
+func Test_LFUPolicy_OnInsert(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	p.OnInsert(&Item[string, string]{key: "1"})
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	require.Equal(t, 1, p.freqs.Len())
+	bucket := p.freqs.Front().Value.(*lfuBucket)
+	assert.Equal(t, uint64(1), bucket.freq)
+	assert.Equal(t, 2, bucket.items.Len())
+	assert.Len(t, p.entries, 2)
+}
+
+func Test_LFUPolicy_OnAccess(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+
+	p.OnAccess(item1)
+	p.OnAccess(item1)
+	p.OnAccess(item2)
+
+	assert.Equal(t, []uint64{2, 3}, lfuFreqs(p))
+	assert.Equal(t, uint64(3), p.entries["1"].bucket.Value.(*lfuBucket).freq)
+	assert.Equal(t, uint64(2), p.entries["2"].bucket.Value.(*lfuBucket).freq)
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "3"})
+	assert.Len(t, p.entries, 2)
+}
+
+func Test_LFUPolicy_OnUpdate(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	p.OnUpdate(item)
+	assert.Equal(t, []uint64{2}, lfuFreqs(p))
+}
+
+func Test_LFUPolicy_OnRemove(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnAccess(item1)
+
+	p.OnRemove(item1, EvictionReasonDeleted)
+	assert.Equal(t, []uint64{1}, lfuFreqs(p))
+	assert.NotContains(t, p.entries, "1")
+
+	// unknown items are ignored
+	p.OnRemove(item1, EvictionReasonDeleted)
+	assert.Len(t, p.entries, 1)
+}
+
+func Test_LFUPolicy_Victim(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	assert.Nil(t, p.Victim())
+
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnInsert(item3)
+
+	// least recently used item out of the least frequently used ones
+	assert.Same(t, item1, p.Victim())
+
+	p.OnAccess(item1)
+	assert.Same(t, item2, p.Victim())
+
+	p.OnAccess(item2)
+	p.OnAccess(item3)
+	assert.Same(t, item1, p.Victim())
+}
+
+func Test_LFUPolicy_each(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	item1 := &Item[string, string]{key: "1"}
+	p.OnInsert(item1)
+	p.OnInsert(&Item[string, string]{key: "2"})
+	p.OnInsert(&Item[string, string]{key: "3"})
+	p.OnAccess(item1)
+
+	var keys []string
+	p.each(func(item *Item[string, string]) bool {
+		keys = append(keys, item.key)
+		return item.key != "3"
+	})
+	assert.Equal(t, []string{"1", "3"}, keys)
+}
+
+func Test_LFUPolicy_age(t *testing.T) {
+	p := NewLFUPolicy[string, string](0)
+	items := make([]*Item[string, string], 4)
+	for i := range items {
+		items[i] = &Item[string, string]{key: string(rune('a' + i))}
+		p.OnInsert(items[i])
+
+		for j := 0; j < i*2; j++ {
+			p.OnAccess(items[i])
+		}
+	}
+	require.Equal(t, []uint64{1, 3, 5, 7}, lfuFreqs(p))
+
+	p.age()
+	assert.Equal(t, []uint64{1, 2, 3}, lfuFreqs(p))
+	assert.Equal(t, 2, p.freqs.Front().Value.(*lfuBucket).items.Len())
+	assert.Same(t, items[0], p.Victim())
+
+	for _, item := range items {
+		assert.Same(t, item, p.entries[item.key].elem.Value)
+	}
+}
+
+func Test_LFUPolicy_tick(t *testing.T) {
+	p := NewLFUPolicy[string, string](3)
+	hot := &Item[string, string]{key: "hot"}
+	p.OnInsert(hot)
+	p.OnAccess(hot)
+	p.OnAccess(hot) // aging is triggered
+	assert.Equal(t, []uint64{1}, lfuFreqs(p))
+	assert.Zero(t, p.ops)
+
+	// aging is disabled
+	p = NewLFUPolicy[string, string](0)
+	p.OnInsert(hot)
+	p.OnAccess(hot)
+	p.OnAccess(hot)
+	assert.Equal(t, []uint64{3}, lfuFreqs(p))
+	assert.Zero(t, p.ops)
+}
+
+func Test_Cache_LFUPolicy(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		evicted []string
+	)
+
+	cache := New[string, string](
+		WithCapacity[string, string](2),
+		WithEvictionPolicy[string, string](NewLFUPolicy[string, string](0)),
+	)
+	del := cache.OnEviction(func(_ context.Context, r EvictionReason, item *Item[string, string]) {
+		assert.Equal(t, EvictionReasonCapacityReached, r)
+
+		mu.Lock()
+		evicted = append(evicted, item.Key())
+		mu.Unlock()
+
+		wg.Done()
+	})
+	defer del()
+
+	wg.Add(2)
+
+	cache.Set("hot", "1", NoTTL)
+	cache.Get("hot")
+	cache.Set("cold1", "2", NoTTL)
+	cache.Set("cold2", "3", NoTTL)
+	cache.Set("cold3", "4", NoTTL)
+
+	wg.Wait()
+	assert.ElementsMatch(t, []string{"hot", "cold3"}, cache.Keys())
+	assert.ElementsMatch(t, []string{"cold1", "cold2"}, evicted)
+}
+
+// lfuFreqs returns the frequencies of all non-empty buckets.
+func lfuFreqs(p *LFUPolicy[string, string]) []uint64 {
+	var res []uint64
+	for bucket := p.freqs.Front(); bucket != nil; bucket = bucket.Next() {
+		res = append(res, bucket.Value.(*lfuBucket).freq)
+	}
+
+	return res
+}
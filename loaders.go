@@ -0,0 +1,834 @@
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by CircuitBreakerLoader when a load is
+// skipped because the circuit is open.
+var ErrCircuitOpen = errors.New("ttlcache: circuit breaker is open")
+
+// TimeoutLoader wraps another Loader and limits the duration of each
+// of its loads.
+type TimeoutLoader[K comparable, V any] struct {
+	loader  Loader[K, V]
+	timeout time.Duration
+
+	metricsMu sync.RWMutex
+	metrics   TimeoutLoaderMetrics
+}
+
+// TimeoutLoaderMetrics contains the metrics of a TimeoutLoader.
+type TimeoutLoaderMetrics struct {
+	// Loads specifies how many loads were started.
+	Loads uint64
+
+	// Timeouts specifies how many loads were abandoned because they
+	// did not complete in time.
+	Timeouts uint64
+}
+
+// NewTimeoutLoader creates a new instance of timeout loader that
+// abandons the loads of the provided loader once the timeout elapses.
+// If the timeout is 0 or below, the loads are not limited.
+func NewTimeoutLoader[K comparable, V any](loader Loader[K, V], timeout time.Duration) *TimeoutLoader[K, V] {
+	return &TimeoutLoader[K, V]{
+		loader:  loader,
+		timeout: timeout,
+	}
+}
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid, could not be
+// retrieved or the load timed out.
+func (l *TimeoutLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// See LoadContext for more details.
+func (l *TimeoutLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+// The context that is passed to the wrapped Loader is cancelled once
+// the timeout elapses, at which point context.DeadlineExceeded is
+// returned without waiting for the wrapped Loader any longer.
+func (l *TimeoutLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	l.metricsMu.Lock()
+	l.metrics.Loads++
+	l.metricsMu.Unlock()
+
+	if l.timeout <= 0 {
+		return loadContext(ctx, l.loader, c, key)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	type result struct {
+		item *Item[K, V]
+		err  error
+	}
+
+	// the channel is buffered, so that the goroutine is able to exit
+	// after the load is abandoned
+	resCh := make(chan result, 1)
+
+	go func() {
+		item, err := loadContext(ctx, l.loader, c, key)
+		resCh <- result{item: item, err: err}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.item, res.err
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			l.metricsMu.Lock()
+			l.metrics.Timeouts++
+			l.metricsMu.Unlock()
+		}
+
+		return nil, err
+	}
+}
+
+// Metrics returns the metrics of the loader.
+func (l *TimeoutLoader[K, V]) Metrics() TimeoutLoaderMetrics {
+	l.metricsMu.RLock()
+	defer l.metricsMu.RUnlock()
+
+	return l.metrics
+}
+
+// RetryConfig holds the configuration of a RetryLoader.
+type RetryConfig struct {
+	// MaxAttempts specifies the maximum number of attempts of each
+	// load, including the first one.
+	// If it is 0 or below, 3 attempts are made.
+	MaxAttempts int
+
+	// InitialBackoff specifies the delay before the first retry.
+	// If it is 0 or below, 100 milliseconds are used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff specifies the maximum delay between two attempts.
+	// If it is 0 or below, the delay is not limited.
+	MaxBackoff time.Duration
+
+	// Multiplier specifies the factor by which the delay grows after
+	// each retry.
+	// If it is below 1, 2 is used.
+	Multiplier float64
+
+	// Jitter specifies the fraction (between 0 and 1) of each delay
+	// that is randomized, so that concurrent retries are spread out.
+	Jitter float64
+
+	// ShouldRetry reports whether a load that failed with the provided
+	// error should be retried.
+	// If it is nil, all errors except for context cancellations are
+	// retried.
+	ShouldRetry func(error) bool
+}
+
+// RetryLoader wraps another Loader and retries its failed loads with
+// exponential backoff.
+type RetryLoader[K comparable, V any] struct {
+	loader Loader[K, V]
+	cfg    RetryConfig
+
+	metricsMu sync.RWMutex
+	metrics   RetryLoaderMetrics
+}
+
+// RetryLoaderMetrics contains the metrics of a RetryLoader.
+type RetryLoaderMetrics struct {
+	// Loads specifies how many loads were started.
+	Loads uint64
+
+	// Retries specifies how many additional attempts were made.
+	Retries uint64
+
+	// Failures specifies how many loads failed after all attempts
+	// (or with an error that should not be retried).
+	Failures uint64
+}
+
+// NewRetryLoader creates a new instance of retry loader that retries
+// the failed loads of the provided loader according to the provided
+// configuration.
+func NewRetryLoader[K comparable, V any](loader Loader[K, V], cfg RetryConfig) *RetryLoader[K, V] {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 100 * time.Millisecond
+	}
+
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2
+	}
+
+	if cfg.Jitter < 0 {
+		cfg.Jitter = 0
+	} else if cfg.Jitter > 1 {
+		cfg.Jitter = 1
+	}
+
+	if cfg.ShouldRetry == nil {
+		cfg.ShouldRetry = func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		}
+	}
+
+	return &RetryLoader[K, V]{
+		loader: loader,
+		cfg:    cfg,
+	}
+}
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved after all attempts.
+func (l *RetryLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// See LoadContext for more details.
+func (l *RetryLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+// Loads that fail with an error that should be retried are attempted
+// again after a backoff delay, until they succeed, the maximum number
+// of attempts is reached or the context is done. The last error is
+// returned if all attempts fail, while the context's error is returned
+// if it is done during a backoff delay.
+func (l *RetryLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	l.metricsMu.Lock()
+	l.metrics.Loads++
+	l.metricsMu.Unlock()
+
+	backoff := l.cfg.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		item, err := loadContext(ctx, l.loader, c, key)
+		if err == nil {
+			return item, nil
+		}
+
+		if attempt >= l.cfg.MaxAttempts || !l.cfg.ShouldRetry(err) {
+			l.metricsMu.Lock()
+			l.metrics.Failures++
+			l.metricsMu.Unlock()
+
+			return item, err
+		}
+
+		timer := time.NewTimer(l.jitter(backoff))
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			l.metricsMu.Lock()
+			l.metrics.Failures++
+			l.metricsMu.Unlock()
+
+			return nil, ctx.Err()
+		}
+
+		l.metricsMu.Lock()
+		l.metrics.Retries++
+		l.metricsMu.Unlock()
+
+		backoff = time.Duration(float64(backoff) * l.cfg.Multiplier)
+		if l.cfg.MaxBackoff > 0 && backoff > l.cfg.MaxBackoff {
+			backoff = l.cfg.MaxBackoff
+		}
+	}
+}
+
+// jitter randomly shortens the provided delay by up to the configured
+// fraction.
+func (l *RetryLoader[K, V]) jitter(d time.Duration) time.Duration {
+	if l.cfg.Jitter == 0 {
+		return d
+	}
+
+	return d - time.Duration(rand.Float64()*l.cfg.Jitter*float64(d))
+}
+
+// Metrics returns the metrics of the loader.
+func (l *RetryLoader[K, V]) Metrics() RetryLoaderMetrics {
+	l.metricsMu.RLock()
+	defer l.metricsMu.RUnlock()
+
+	return l.metrics
+}
+
+// CircuitState is used to specify the state of a circuit breaker.
+type CircuitState int
+
+// Available circuit breaker states.
+const (
+	// CircuitClosed indicates that loads are allowed.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen indicates that loads are skipped, since the
+	// wrapped loader recently kept failing.
+	CircuitOpen
+
+	// CircuitHalfOpen indicates that a limited number of probe
+	// loads are allowed to determine whether the wrapped loader
+	// has recovered.
+	CircuitHalfOpen
+)
+
+// CircuitBreakerConfig holds the configuration of a
+// CircuitBreakerLoader.
+type CircuitBreakerConfig struct {
+	// FailureThreshold specifies the number of consecutive failed
+	// loads after which the circuit is opened.
+	// If it is 0 or below, 5 is used.
+	FailureThreshold int
+
+	// OpenTimeout specifies how long the circuit stays open before
+	// it becomes half-open.
+	// If it is 0 or below, 30 seconds are used.
+	OpenTimeout time.Duration
+
+	// SuccessThreshold specifies the number of consecutive successful
+	// probe loads after which a half-open circuit is closed.
+	// If it is 0 or below, 1 is used.
+	SuccessThreshold int
+
+	// IsFailure reports whether the provided error should be counted
+	// as a failure of the wrapped loader.
+	// If it is nil, all errors except for context cancellations are
+	// counted.
+	IsFailure func(error) bool
+}
+
+// CircuitBreakerLoader wraps another Loader and skips its loads while
+// it keeps failing, so that a failing backend is not overloaded.
+type CircuitBreakerLoader[K comparable, V any] struct {
+	loader Loader[K, V]
+	cfg    CircuitBreakerConfig
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	probing   bool
+	openedAt  time.Time
+	metrics   CircuitBreakerMetrics
+}
+
+// CircuitBreakerMetrics contains the metrics of a
+// CircuitBreakerLoader.
+type CircuitBreakerMetrics struct {
+	// Loads specifies how many loads were passed to the wrapped
+	// loader.
+	Loads uint64
+
+	// Failures specifies how many of the loads failed.
+	Failures uint64
+
+	// Rejections specifies how many loads were skipped because the
+	// circuit was open (or half-open with a probe in progress).
+	Rejections uint64
+
+	// Trips specifies how many times the circuit was opened.
+	Trips uint64
+}
+
+// NewCircuitBreakerLoader creates a new instance of circuit breaker
+// loader that guards the provided loader according to the provided
+// configuration.
+func NewCircuitBreakerLoader[K comparable, V any](loader Loader[K, V], cfg CircuitBreakerConfig) *CircuitBreakerLoader[K, V] {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = 1
+	}
+
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		}
+	}
+
+	return &CircuitBreakerLoader[K, V]{
+		loader: loader,
+		cfg:    cfg,
+	}
+}
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid, could not be
+// retrieved or the circuit is open.
+func (l *CircuitBreakerLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// See LoadContext for more details.
+func (l *CircuitBreakerLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// It returns a nil item and a nil error if the item is not found/valid,
+// and a non-nil error if the item could not be retrieved.
+// ErrCircuitOpen is returned without calling the wrapped Loader while
+// the circuit is open, or while it is half-open and a probe load is
+// already in progress.
+func (l *CircuitBreakerLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	probe, ok := l.allow()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	item, err := loadContext(ctx, l.loader, c, key)
+	l.done(probe, err)
+
+	return item, err
+}
+
+// allow reports whether a load is allowed and whether it is a probe
+// load of a half-open circuit.
+func (l *CircuitBreakerLoader[K, V]) allow() (probe, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.state == CircuitOpen && time.Since(l.openedAt) >= l.cfg.OpenTimeout {
+		l.state = CircuitHalfOpen
+		l.successes = 0
+	}
+
+	switch l.state {
+	case CircuitOpen:
+		l.metrics.Rejections++
+		return false, false
+	case CircuitHalfOpen:
+		if l.probing {
+			l.metrics.Rejections++
+			return false, false
+		}
+
+		l.probing = true
+		probe = true
+	}
+
+	l.metrics.Loads++
+
+	return probe, true
+}
+
+// done records the result of a load that was allowed by allow.
+func (l *CircuitBreakerLoader[K, V]) done(probe bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failed := err != nil && l.cfg.IsFailure(err)
+	if failed {
+		l.metrics.Failures++
+	}
+
+	if probe {
+		l.probing = false
+
+		if failed {
+			l.open()
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		l.successes++
+		if l.successes >= l.cfg.SuccessThreshold {
+			l.state = CircuitClosed
+			l.failures = 0
+		}
+
+		return
+	}
+
+	// the results of loads that were started before the circuit
+	// was opened do not affect it anymore
+	if l.state != CircuitClosed {
+		return
+	}
+
+	if !failed {
+		if err == nil {
+			l.failures = 0
+		}
+
+		return
+	}
+
+	l.failures++
+	if l.failures >= l.cfg.FailureThreshold {
+		l.open()
+	}
+}
+
+// open opens the circuit.
+// Not concurrently safe.
+func (l *CircuitBreakerLoader[K, V]) open() {
+	l.state = CircuitOpen
+	l.openedAt = time.Now()
+	l.failures = 0
+	l.metrics.Trips++
+}
+
+// State returns the current state of the circuit.
+func (l *CircuitBreakerLoader[K, V]) State() CircuitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.state == CircuitOpen && time.Since(l.openedAt) >= l.cfg.OpenTimeout {
+		return CircuitHalfOpen
+	}
+
+	return l.state
+}
+
+// Metrics returns the metrics of the loader.
+func (l *CircuitBreakerLoader[K, V]) Metrics() CircuitBreakerMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.metrics
+}
+
+// FallbackLoader wraps multiple Loaders (e.g. the primary and the
+// replica store) and tries them in order until one of them returns
+// an item.
+type FallbackLoader[K comparable, V any] struct {
+	loaders []Loader[K, V]
+
+	metricsMu sync.RWMutex
+	metrics   SourceLoaderMetrics
+}
+
+// SourceLoaderMetrics contains the metrics of a loader that reads
+// from multiple sources.
+type SourceLoaderMetrics struct {
+	// Loads specifies how many loads were started.
+	Loads uint64
+
+	// Sources specifies how many loads were satisfied by each of the
+	// sources, in the order in which the sources were provided.
+	Sources []uint64
+
+	// Misses specifies how many loads were not satisfied by any of
+	// the sources, because none of them found the item or because
+	// all of them failed.
+	Misses uint64
+
+	// Failures specifies how many times a source failed.
+	Failures uint64
+
+	// Hedges specifies how many times an additional source was
+	// started because the previous ones had not answered in time.
+	// It is only tracked by HedgedLoader.
+	Hedges uint64
+}
+
+// NewFallbackLoader creates a new instance of fallback loader that
+// tries the provided loaders in order.
+func NewFallbackLoader[K comparable, V any](loaders ...Loader[K, V]) *FallbackLoader[K, V] {
+	return &FallbackLoader[K, V]{
+		loaders: loaders,
+		metrics: SourceLoaderMetrics{
+			Sources: make([]uint64, len(loaders)),
+		},
+	}
+}
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved from any of the sources.
+func (l *FallbackLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// See LoadContext for more details.
+func (l *FallbackLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// The wrapped Loaders are called in order until one of them returns an
+// item; the ones that do not find the item or fail are skipped.
+// If none of them returns an item, the error of the first failed
+// Loader is returned, or nil if none of them failed. The remaining
+// Loaders are not called once the context is done.
+func (l *FallbackLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	l.metricsMu.Lock()
+	l.metrics.Loads++
+	l.metricsMu.Unlock()
+
+	var firstErr error
+
+	for i, loader := range l.loaders {
+		if i > 0 && ctx.Err() != nil {
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+
+			break
+		}
+
+		item, err := loadContext(ctx, loader, c, key)
+		if err != nil {
+			l.metricsMu.Lock()
+			l.metrics.Failures++
+			l.metricsMu.Unlock()
+
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		if item != nil {
+			l.metricsMu.Lock()
+			l.metrics.Sources[i]++
+			l.metricsMu.Unlock()
+
+			return item, nil
+		}
+	}
+
+	l.metricsMu.Lock()
+	l.metrics.Misses++
+	l.metricsMu.Unlock()
+
+	return nil, firstErr
+}
+
+// Metrics returns the metrics of the loader.
+func (l *FallbackLoader[K, V]) Metrics() SourceLoaderMetrics {
+	l.metricsMu.RLock()
+	defer l.metricsMu.RUnlock()
+
+	return l.metrics.clone()
+}
+
+// HedgedLoader wraps multiple Loaders (e.g. the primary and the
+// replica store) and starts each of them after the previous ones have
+// not answered within a delay, so that a single slow source does not
+// slow down the load.
+type HedgedLoader[K comparable, V any] struct {
+	loaders []Loader[K, V]
+	delay   time.Duration
+
+	metricsMu sync.RWMutex
+	metrics   SourceLoaderMetrics
+}
+
+// NewHedgedLoader creates a new instance of hedged loader that starts
+// the provided loaders in order, each after the provided delay.
+func NewHedgedLoader[K comparable, V any](delay time.Duration, loaders ...Loader[K, V]) *HedgedLoader[K, V] {
+	return &HedgedLoader[K, V]{
+		loaders: loaders,
+		delay:   delay,
+		metrics: SourceLoaderMetrics{
+			Sources: make([]uint64, len(loaders)),
+		},
+	}
+}
+
+// Load executes a custom item retrieval logic and returns the item that
+// is associated with the key.
+// It returns nil if the item is not found/valid or could not be
+// retrieved from any of the sources.
+func (l *HedgedLoader[K, V]) Load(c *Cache[K, V], key K) *Item[K, V] {
+	item, _ := l.LoadWithError(c, key)
+	return item
+}
+
+// LoadWithError executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// See LoadContext for more details.
+func (l *HedgedLoader[K, V]) LoadWithError(c *Cache[K, V], key K) (*Item[K, V], error) {
+	return l.LoadContext(context.Background(), c, key)
+}
+
+// LoadContext executes a custom item retrieval logic and returns the
+// item that is associated with the key.
+// The first wrapped Loader is started immediately. Each of the
+// following ones is started when the delay elapses without any item
+// being returned, or as soon as all started Loaders do not find the
+// item or fail. The first returned item is used and the context of the
+// remaining loads is cancelled. If none of the Loaders returns an
+// item, the error of the first failed Loader is returned, or nil if
+// none of them failed. The context's error is returned as soon as the
+// context is done.
+// Since the loads run concurrently, Loaders that store the items in
+// the cache themselves may overwrite each other's items; ResultLoaders
+// do not have this problem.
+func (l *HedgedLoader[K, V]) LoadContext(ctx context.Context, c *Cache[K, V], key K) (*Item[K, V], error) {
+	l.metricsMu.Lock()
+	l.metrics.Loads++
+	l.metricsMu.Unlock()
+
+	if len(l.loaders) == 0 {
+		l.metricsMu.Lock()
+		l.metrics.Misses++
+		l.metricsMu.Unlock()
+
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		source int
+		item   *Item[K, V]
+		err    error
+	}
+
+	// the channel is buffered, so that the goroutines of the
+	// remaining loads are able to exit after an item is returned
+	resCh := make(chan result, len(l.loaders))
+
+	start := func(i int) {
+		go func() {
+			item, err := loadContext(ctx, l.loaders[i], c, key)
+			resCh <- result{source: i, item: item, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(l.delay)
+	defer timer.Stop()
+
+	var (
+		started  = 1
+		pending  = 1
+		firstErr error
+	)
+
+	start(0)
+
+	for pending > 0 {
+		select {
+		case res := <-resCh:
+			pending--
+
+			if res.err != nil {
+				l.metricsMu.Lock()
+				l.metrics.Failures++
+				l.metricsMu.Unlock()
+
+				if firstErr == nil {
+					firstErr = res.err
+				}
+			} else if res.item != nil {
+				l.metricsMu.Lock()
+				l.metrics.Sources[res.source]++
+				l.metricsMu.Unlock()
+
+				return res.item, nil
+			}
+
+			if pending > 0 || started == len(l.loaders) || ctx.Err() != nil {
+				continue
+			}
+		case <-ctx.Done():
+			l.metricsMu.Lock()
+			l.metrics.Misses++
+			l.metricsMu.Unlock()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+			if started == len(l.loaders) {
+				continue
+			}
+
+			l.metricsMu.Lock()
+			l.metrics.Hedges++
+			l.metricsMu.Unlock()
+		}
+
+		start(started)
+		started++
+		pending++
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(l.delay)
+	}
+
+	l.metricsMu.Lock()
+	l.metrics.Misses++
+	l.metricsMu.Unlock()
+
+	return nil, firstErr
+}
+
+// Metrics returns the metrics of the loader.
+func (l *HedgedLoader[K, V]) Metrics() SourceLoaderMetrics {
+	l.metricsMu.RLock()
+	defer l.metricsMu.RUnlock()
+
+	return l.metrics.clone()
+}
+
+// clone returns a copy of the metrics that does not share the sources
+// slice with the original.
+func (m SourceLoaderMetrics) clone() SourceLoaderMetrics {
+	m.Sources = append([]uint64(nil), m.Sources...)
+	return m
+}
@@ -0,0 +1,698 @@
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewTimeoutLoader(t *testing.T) {
+	l := NewTimeoutLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	}), time.Second)
+	require.NotNil(t, l)
+	assert.NotNil(t, l.loader)
+	assert.Equal(t, time.Second, l.timeout)
+}
+
+func Test_TimeoutLoader_Load(t *testing.T) {
+	l := NewTimeoutLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}), time.Second)
+
+	item := l.Load(nil, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_TimeoutLoader_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	l := NewTimeoutLoader[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}), time.Second)
+
+	item, err := l.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+}
+
+func Test_TimeoutLoader_LoadContext(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	// completed in time
+	l := NewTimeoutLoader[string, string](ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+
+		return &Item[string, string]{key: key}, nil
+	}), time.Second)
+
+	item, err := l.LoadContext(ctx, nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, TimeoutLoaderMetrics{Loads: 1}, l.Metrics())
+
+	// timed out
+	releaseCh := make(chan struct{})
+	defer close(releaseCh)
+
+	l = NewTimeoutLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		<-releaseCh
+		return &Item[string, string]{key: key}
+	}), time.Millisecond)
+
+	item, err = l.LoadContext(ctx, nil, "test")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, item)
+	assert.Equal(t, TimeoutLoaderMetrics{Loads: 1, Timeouts: 1}, l.Metrics())
+
+	// cancelled
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	item, err = l.LoadContext(ctx, nil, "test")
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, item)
+	assert.Equal(t, TimeoutLoaderMetrics{Loads: 2, Timeouts: 1}, l.Metrics())
+
+	// not limited
+	l = NewTimeoutLoader[string, string](ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+
+		return &Item[string, string]{key: key}, nil
+	}), 0)
+
+	item, err = l.LoadContext(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, TimeoutLoaderMetrics{Loads: 1}, l.Metrics())
+}
+
+func Test_NewRetryLoader(t *testing.T) {
+	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	})
+
+	// defaults
+	l := NewRetryLoader[string, string](loader, RetryConfig{Jitter: 2})
+	require.NotNil(t, l)
+	assert.NotNil(t, l.loader)
+	assert.Equal(t, 3, l.cfg.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, l.cfg.InitialBackoff)
+	assert.Equal(t, float64(2), l.cfg.Multiplier)
+	assert.Equal(t, float64(1), l.cfg.Jitter)
+	require.NotNil(t, l.cfg.ShouldRetry)
+	assert.True(t, l.cfg.ShouldRetry(errors.New("error")))
+	assert.False(t, l.cfg.ShouldRetry(context.Canceled))
+
+	// custom values
+	l = NewRetryLoader[string, string](loader, RetryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     1.5,
+		Jitter:         0.5,
+	})
+	assert.Equal(t, 5, l.cfg.MaxAttempts)
+	assert.Equal(t, time.Second, l.cfg.InitialBackoff)
+	assert.Equal(t, time.Minute, l.cfg.MaxBackoff)
+	assert.Equal(t, 1.5, l.cfg.Multiplier)
+	assert.Equal(t, 0.5, l.cfg.Jitter)
+}
+
+func Test_RetryLoader_Load(t *testing.T) {
+	l := NewRetryLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}), RetryConfig{})
+
+	item := l.Load(nil, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_RetryLoader_LoadWithError(t *testing.T) {
+	var calls int
+
+	errLoad := errors.New("error")
+
+	l := NewRetryLoader[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], key string) (*Item[string, string], error) {
+		calls++
+		if calls < 3 {
+			return nil, errLoad
+		}
+
+		return &Item[string, string]{key: key}, nil
+	}), RetryConfig{InitialBackoff: time.Millisecond})
+
+	item, err := l.LoadWithError(nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, RetryLoaderMetrics{Loads: 1, Retries: 2}, l.Metrics())
+}
+
+func Test_RetryLoader_LoadContext(t *testing.T) {
+	var calls int
+
+	errLoad := errors.New("error")
+	errFatal := errors.New("fatal")
+
+	l := NewRetryLoader[string, string](ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		calls++
+
+		if key == "fatal" {
+			return nil, errFatal
+		}
+
+		return nil, errLoad
+	}), RetryConfig{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		Jitter:         0.5,
+		ShouldRetry: func(err error) bool {
+			return err != errFatal
+		},
+	})
+
+	// all attempts fail
+	item, err := l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, RetryLoaderMetrics{Loads: 1, Retries: 3, Failures: 1}, l.Metrics())
+
+	// not retried
+	calls = 0
+
+	item, err = l.LoadContext(context.Background(), nil, "fatal")
+	assert.Equal(t, errFatal, err)
+	assert.Nil(t, item)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, RetryLoaderMetrics{Loads: 2, Retries: 3, Failures: 2}, l.Metrics())
+
+	// cancelled during backoff
+	calls = 0
+	l.cfg.InitialBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	item, err = l.LoadContext(ctx, nil, "test")
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, item)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, RetryLoaderMetrics{Loads: 3, Retries: 3, Failures: 3}, l.Metrics())
+}
+
+func Test_RetryLoader_jitter(t *testing.T) {
+	l := NewRetryLoader[string, string](nil, RetryConfig{})
+	assert.Equal(t, time.Second, l.jitter(time.Second))
+
+	l.cfg.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := l.jitter(time.Second)
+		assert.LessOrEqual(t, d, time.Second)
+		assert.GreaterOrEqual(t, d, time.Second/2)
+	}
+}
+
+func Test_NewCircuitBreakerLoader(t *testing.T) {
+	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	})
+
+	// defaults
+	l := NewCircuitBreakerLoader[string, string](loader, CircuitBreakerConfig{})
+	require.NotNil(t, l)
+	assert.NotNil(t, l.loader)
+	assert.Equal(t, 5, l.cfg.FailureThreshold)
+	assert.Equal(t, 30*time.Second, l.cfg.OpenTimeout)
+	assert.Equal(t, 1, l.cfg.SuccessThreshold)
+	require.NotNil(t, l.cfg.IsFailure)
+	assert.True(t, l.cfg.IsFailure(errors.New("error")))
+	assert.False(t, l.cfg.IsFailure(context.Canceled))
+	assert.Equal(t, CircuitClosed, l.State())
+
+	// custom values
+	l = NewCircuitBreakerLoader[string, string](loader, CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		SuccessThreshold: 3,
+	})
+	assert.Equal(t, 2, l.cfg.FailureThreshold)
+	assert.Equal(t, time.Minute, l.cfg.OpenTimeout)
+	assert.Equal(t, 3, l.cfg.SuccessThreshold)
+}
+
+func Test_CircuitBreakerLoader_Load(t *testing.T) {
+	l := NewCircuitBreakerLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}), CircuitBreakerConfig{})
+
+	item := l.Load(nil, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_CircuitBreakerLoader_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	l := NewCircuitBreakerLoader[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}), CircuitBreakerConfig{FailureThreshold: 1})
+
+	item, err := l.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+
+	item, err = l.LoadWithError(nil, "test")
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Nil(t, item)
+}
+
+func Test_CircuitBreakerLoader_LoadContext(t *testing.T) {
+	var (
+		calls   int
+		loadErr error
+	)
+
+	l := NewCircuitBreakerLoader[string, string](ContextLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		calls++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		return &Item[string, string]{key: key}, nil
+	}), CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Hour,
+		SuccessThreshold: 2,
+	})
+
+	// consecutive failures open the circuit
+	loadErr = errors.New("error")
+
+	_, err := l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, CircuitClosed, l.State())
+
+	_, err = l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, CircuitOpen, l.State())
+
+	// loads are skipped while the circuit is open
+	_, err = l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, CircuitBreakerMetrics{Loads: 2, Failures: 2, Rejections: 1, Trips: 1}, l.Metrics())
+
+	// failed probe opens the circuit again
+	l.openedAt = time.Now().Add(-time.Hour)
+	assert.Equal(t, CircuitHalfOpen, l.State())
+
+	_, err = l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, CircuitOpen, l.State())
+	assert.Equal(t, uint64(2), l.Metrics().Trips)
+
+	// successful probes close the circuit
+	loadErr = nil
+	l.openedAt = time.Now().Add(-time.Hour)
+
+	item, err := l.LoadContext(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, CircuitHalfOpen, l.State())
+
+	_, err = l.LoadContext(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, l.State())
+
+	// cancellations are not failures
+	loadErr = context.Canceled
+
+	for i := 0; i < 3; i++ {
+		_, err = l.LoadContext(context.Background(), nil, "test")
+		assert.Equal(t, context.Canceled, err)
+	}
+
+	assert.Equal(t, CircuitClosed, l.State())
+
+	// successes reset the failure count
+	loadErr = errors.New("error")
+	_, _ = l.LoadContext(context.Background(), nil, "test")
+
+	loadErr = nil
+	_, _ = l.LoadContext(context.Background(), nil, "test")
+
+	loadErr = errors.New("error")
+	_, _ = l.LoadContext(context.Background(), nil, "test")
+	assert.Equal(t, CircuitClosed, l.State())
+}
+
+func Test_CircuitBreakerLoader_allow(t *testing.T) {
+	l := NewCircuitBreakerLoader[string, string](nil, CircuitBreakerConfig{OpenTimeout: time.Hour})
+
+	// closed
+	probe, ok := l.allow()
+	assert.False(t, probe)
+	assert.True(t, ok)
+
+	// open
+	l.open()
+
+	probe, ok = l.allow()
+	assert.False(t, probe)
+	assert.False(t, ok)
+
+	// half-open allows a single probe at a time
+	l.openedAt = time.Now().Add(-time.Hour)
+
+	probe, ok = l.allow()
+	assert.True(t, probe)
+	assert.True(t, ok)
+	assert.Equal(t, CircuitHalfOpen, l.state)
+
+	probe, ok = l.allow()
+	assert.False(t, probe)
+	assert.False(t, ok)
+
+	assert.Equal(t, CircuitBreakerMetrics{Loads: 2, Rejections: 2, Trips: 1}, l.metrics)
+}
+
+func Test_CircuitBreakerLoader_done(t *testing.T) {
+	l := NewCircuitBreakerLoader[string, string](nil, CircuitBreakerConfig{FailureThreshold: 1})
+
+	// loads started before the circuit was opened are ignored
+	l.open()
+	l.done(false, nil)
+	assert.Equal(t, CircuitOpen, l.state)
+
+	// probe failures that are not counted keep the circuit half-open
+	l.state = CircuitHalfOpen
+	l.probing = true
+	l.done(true, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, l.state)
+	assert.False(t, l.probing)
+	assert.Zero(t, l.successes)
+}
+
+func Test_NewFallbackLoader(t *testing.T) {
+	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	})
+
+	l := NewFallbackLoader[string, string](loader, loader)
+	require.NotNil(t, l)
+	assert.Len(t, l.loaders, 2)
+	assert.Equal(t, []uint64{0, 0}, l.metrics.Sources)
+}
+
+func Test_FallbackLoader_Load(t *testing.T) {
+	l := NewFallbackLoader[string, string](LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}))
+
+	item := l.Load(nil, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_FallbackLoader_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	l := NewFallbackLoader[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}))
+
+	item, err := l.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+}
+
+func Test_FallbackLoader_LoadContext(t *testing.T) {
+	var calls []string
+
+	errPrimary := errors.New("primary")
+	errReplica := errors.New("replica")
+
+	primary := ContextLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		calls = append(calls, "primary")
+
+		switch key {
+		case "primary":
+			return &Item[string, string]{key: key, value: "primary"}, nil
+		case "missing":
+			return nil, nil
+		default:
+			return nil, errPrimary
+		}
+	})
+	replica := ContextLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		calls = append(calls, "replica")
+
+		switch key {
+		case "error":
+			return nil, errReplica
+		case "missing":
+			return nil, nil
+		default:
+			return &Item[string, string]{key: key, value: "replica"}, nil
+		}
+	})
+
+	l := NewFallbackLoader[string, string](primary, replica)
+
+	// satisfied by the primary source
+	item, err := l.LoadContext(context.Background(), nil, "primary")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "primary", item.value)
+	assert.Equal(t, []string{"primary"}, calls)
+
+	// satisfied by the replica source
+	calls = nil
+
+	item, err = l.LoadContext(context.Background(), nil, "replica")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "replica", item.value)
+	assert.Equal(t, []string{"primary", "replica"}, calls)
+
+	// not found
+	item, err = l.LoadContext(context.Background(), nil, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	// all sources failed
+	item, err = l.LoadContext(context.Background(), nil, "error")
+	assert.Equal(t, errPrimary, err)
+	assert.Nil(t, item)
+
+	// context is done
+	calls = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	item, err = l.LoadContext(ctx, nil, "replica")
+	assert.Equal(t, errPrimary, err)
+	assert.Nil(t, item)
+	assert.Equal(t, []string{"primary"}, calls)
+
+	assert.Equal(t, SourceLoaderMetrics{
+		Loads:    5,
+		Sources:  []uint64{1, 1},
+		Misses:   3,
+		Failures: 4,
+	}, l.Metrics())
+}
+
+func Test_FallbackLoader_Metrics(t *testing.T) {
+	l := NewFallbackLoader[string, string](nil)
+
+	m := l.Metrics()
+	m.Sources[0] = 5
+	assert.Equal(t, []uint64{0}, l.metrics.Sources)
+}
+
+func Test_NewHedgedLoader(t *testing.T) {
+	loader := LoaderFunc[string, string](func(_ *Cache[string, string], _ string) *Item[string, string] {
+		return nil
+	})
+
+	l := NewHedgedLoader[string, string](time.Second, loader, loader)
+	require.NotNil(t, l)
+	assert.Len(t, l.loaders, 2)
+	assert.Equal(t, time.Second, l.delay)
+	assert.Equal(t, []uint64{0, 0}, l.metrics.Sources)
+}
+
+func Test_HedgedLoader_Load(t *testing.T) {
+	l := NewHedgedLoader[string, string](time.Second, LoaderFunc[string, string](func(_ *Cache[string, string], key string) *Item[string, string] {
+		return &Item[string, string]{key: key}
+	}))
+
+	item := l.Load(nil, "test")
+	require.NotNil(t, item)
+	assert.Equal(t, "test", item.key)
+}
+
+func Test_HedgedLoader_LoadWithError(t *testing.T) {
+	errLoad := errors.New("error")
+
+	l := NewHedgedLoader[string, string](time.Second, LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+		return nil, errLoad
+	}))
+
+	item, err := l.LoadWithError(nil, "test")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+}
+
+func Test_HedgedLoader_LoadContext(t *testing.T) {
+	errLoad := errors.New("error")
+
+	// source returns the provided result unless the key requests it
+	// to hang until its context is done
+	source := func(name string) Loader[string, string] {
+		return ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+			switch key {
+			case name + " hangs", "all hang":
+				<-ctx.Done()
+				return nil, ctx.Err()
+			case name + " fails", "all fail":
+				return nil, errLoad
+			case "missing":
+				return nil, nil
+			}
+
+			return &Item[string, string]{key: key, value: name}, nil
+		})
+	}
+
+	l := NewHedgedLoader[string, string](time.Hour, source("primary"), source("replica"))
+
+	// satisfied by the primary source
+	item, err := l.LoadContext(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "primary", item.value)
+
+	// the replica is started immediately after the primary fails
+	item, err = l.LoadContext(context.Background(), nil, "primary fails")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "replica", item.value)
+
+	// not found
+	item, err = l.LoadContext(context.Background(), nil, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	// all sources failed
+	item, err = l.LoadContext(context.Background(), nil, "all fail")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+
+	assert.Equal(t, SourceLoaderMetrics{
+		Loads:    4,
+		Sources:  []uint64{1, 1},
+		Misses:   2,
+		Failures: 3,
+	}, l.Metrics())
+
+	// the replica is started after the delay
+	l = NewHedgedLoader[string, string](time.Millisecond, source("primary"), source("replica"))
+
+	item, err = l.LoadContext(context.Background(), nil, "primary hangs")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "replica", item.value)
+	assert.Equal(t, SourceLoaderMetrics{
+		Loads:   1,
+		Sources: []uint64{0, 1},
+		Hedges:  1,
+	}, l.Metrics())
+
+	// context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	item, err = l.LoadContext(ctx, nil, "all hang")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, item)
+
+	// no sources
+	l = NewHedgedLoader[string, string](time.Millisecond)
+
+	item, err = l.LoadContext(context.Background(), nil, "test")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Equal(t, uint64(1), l.Metrics().Misses)
+}
+
+func Test_HedgedLoader_suppressed(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	releaseCh := make(chan struct{})
+
+	hedged := NewHedgedLoader[string, string](time.Hour, ContextLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], key string) (*Item[string, string], error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		<-releaseCh
+
+		return &Item[string, string]{key: key}, nil
+	}))
+
+	l := NewSuppressedLoader[string, string](hedged, nil)
+
+	var (
+		wg           sync.WaitGroup
+		item1, item2 *Item[string, string]
+	)
+
+	wg.Add(2)
+
+	go func() {
+		item1 = l.Load(nil, "test")
+		wg.Done()
+	}()
+
+	go func() {
+		item2 = l.Load(nil, "test")
+		wg.Done()
+	}()
+
+	assert.Eventually(t, func() bool {
+		l.calls.mu.Lock()
+		defer l.calls.mu.Unlock()
+
+		return l.calls.calls["test"] != nil && l.calls.calls["test"].waiters == 2
+	}, time.Second, time.Millisecond)
+
+	close(releaseCh)
+	wg.Wait()
+
+	require.NotNil(t, item1)
+	assert.Same(t, item1, item2)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []uint64{1}, hedged.Metrics().Sources)
+}
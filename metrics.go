@@ -11,6 +11,10 @@ type Metrics struct {
 	// Retrievals made with a loader function are not tracked.
 	Hits uint64
 
+	// StaleHits specifies how many of the hits returned items that
+	// expired within the cache's grace period.
+	StaleHits uint64
+
 	// Misses specifies how many items were not found in the cache.
 	// Retrievals made with a loader function are tracked as well.
 	Misses uint64
@@ -18,4 +22,45 @@ type Metrics struct {
 	// Evictions specifies how many items were removed from the
 	// cache.
 	Evictions uint64
+
+	// Rejections specifies how many items were refused admission
+	// by the eviction policy. Rejected items are included in the
+	// evictions count as well.
+	Rejections uint64
+
+	// CostRejections specifies how many items were not stored
+	// because their cost exceeded the maximum cost of the cache.
+	// Since these items were never stored, they are not included in
+	// the evictions count.
+	CostRejections uint64
+
+	// LoadErrors specifies how many times a loader failed to
+	// retrieve a missing item. Only errors returned by loaders that
+	// implement the LoaderWithError or ContextLoader interfaces are
+	// tracked.
+	LoadErrors uint64
+
+	// Refreshes specifies how many background reloads of items
+	// were started.
+	Refreshes uint64
+
+	// RefreshErrors specifies how many background reloads of items
+	// failed. Failed reloads are included in the load errors count
+	// as well.
+	RefreshErrors uint64
+
+	// NegativeInsertions specifies how many keys were marked as
+	// absent because the loader did not find them.
+	NegativeInsertions uint64
+
+	// NegativeHits specifies how many times a key that was marked as
+	// absent was retrieved without calling the loader. These
+	// retrievals are included in the misses count as well.
+	NegativeHits uint64
+
+	// EstimatedBytes specifies the estimated number of bytes that
+	// the items currently stored in the cache occupy in memory.
+	// It is only tracked when the cache is created with the
+	// WithMaxMemory option.
+	EstimatedBytes uint64
 }
@@ -22,6 +22,38 @@ type options[K comparable, V any] struct {
 	loader             Loader[K, V]
 	disableTouchOnHit  bool
 	enableVersionTrack bool
+	evictionPolicy     EvictionPolicy[K, V]
+	evictionPolicyFunc func(capacity uint64) EvictionPolicy[K, V]
+	maxCost            uint64
+	costFunc           func(K, V) uint64
+	trackMemory        bool
+	accessBufferSize   int
+	wheelTick          time.Duration
+	refreshAfter       time.Duration
+	gracePeriod        time.Duration
+	negativeTTL        time.Duration
+	negativeCapacity   uint64
+	batchLoader        BatchLoader[K, V]
+	maxBatchSize       int
+	snapshotCodec      SnapshotCodec[K, V]
+	keyCodec           Codec[K]
+	valueCodec         Codec[V]
+	disableLoadEvents  bool
+}
+
+// snapshotCodecOrDefault returns the snapshot codec. If it is not set,
+// a snapshot codec that uses the key and value codecs is returned when
+// any of them is set, or the gob snapshot codec otherwise.
+func (o *options[K, V]) snapshotCodecOrDefault() SnapshotCodec[K, V] {
+	if o.snapshotCodec != nil {
+		return o.snapshotCodec
+	}
+
+	if o.keyCodec != nil || o.valueCodec != nil {
+		return NewSnapshotCodec(o.keyCodec, o.valueCodec)
+	}
+
+	return GobSnapshotCodec[K, V]{}
 }
 
 // applyOptions applies the provided option values to the option struct.
@@ -39,6 +71,101 @@ func WithCapacity[K comparable, V any](c uint64) Option[K, V] {
 	})
 }
 
+// WithMaxCost sets the maximum total cost of all items in the cache.
+// When it is reached, items chosen by the eviction policy are evicted
+// until the new item fits. Items whose cost alone exceeds the maximum
+// cost are not stored at all.
+// It has no effect when passing into Get().
+func WithMaxCost[K comparable, V any](c uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.maxCost = c
+	})
+}
+
+// WithCostFunc sets the function that is used to calculate the cost
+// of each item. By default, each item costs 1.
+// It has no effect when passing into Get().
+func WithCostFunc[K comparable, V any](fn func(K, V) uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.costFunc = fn
+	})
+}
+
+// WithMaxMemory sets the maximum estimated number of bytes that all
+// items in the cache may occupy in memory. Item sizes are estimated
+// automatically (see EstimateSize) and the estimated total is
+// reported by the cache's metrics. If 0 is passed, memory usage is
+// only estimated, but not limited.
+// It overrides the values set by WithMaxCost and WithCostFunc.
+// It has no effect when passing into Get().
+func WithMaxMemory[K comparable, V any](bytes uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.maxCost = bytes
+		opts.costFunc = memoryCost[K, V]
+		opts.trackMemory = true
+	})
+}
+
+// WithEvictionPolicy sets the policy that is used to choose which
+// item should be evicted when the capacity of the cache is reached.
+// By default, the least recently used item is evicted.
+// It has no effect when passing into Get().
+func WithEvictionPolicy[K comparable, V any](p EvictionPolicy[K, V]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.evictionPolicy = p
+	})
+}
+
+// WithEvictionPolicyFunc sets the function that is used to create
+// the eviction policy of the cache. The function receives the
+// capacity of the cache (or of a single shard, when used with
+// NewSharded), or its maximum cost if the capacity is not set, in
+// which case the policy should be sized by the costs of items, e.g.
+// with NewWeightedTinyLFUPolicy. It is ignored when an eviction
+// policy instance is set with WithEvictionPolicy.
+// It has no effect when passing into Get().
+func WithEvictionPolicyFunc[K comparable, V any](fn func(capacity uint64) EvictionPolicy[K, V]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.evictionPolicyFunc = fn
+	})
+}
+
+// WithAccessBuffer enables buffered recording of item retrievals.
+// When it is enabled, Get only read-locks the cache on a hit and
+// records the access into a lossy buffer. Buffered accesses are
+// applied to the eviction policy and to the items' expiration
+// timestamps in batches of the provided size, once the cache can be
+// locked without waiting.
+// As a result, the order of items in the eviction policy and the
+// values returned by Item.ExpiresAt are only eventually consistent:
+// some accesses may be applied late or not at all (e.g. when the
+// buffer is full), so an item may expire or be evicted even though it
+// was retrieved recently.
+// If 0 is passed, accesses are recorded immediately (the default),
+// unless the eviction policy is a ConcurrentAccessPolicy, in which
+// case only the touches of items are buffered.
+// It has no effect when passing into Get().
+func WithAccessBuffer[K comparable, V any](size int) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.accessBufferSize = size
+	})
+}
+
+// WithTimingWheel makes the cache track expiration timestamps with a
+// hierarchical timing wheel of the provided resolution instead of a
+// binary heap, so that scheduling and rescheduling (e.g. touching)
+// an item takes constant time.
+// Expired items are deleted in batches once per tick by the automatic
+// cleanup process (see Start) or by DeleteExpired, so they may remain
+// in the cache for up to one tick after they expire. Expired items
+// are never returned by Get, though.
+// It has no effect when passing into Get().
+func WithTimingWheel[K comparable, V any](tick time.Duration) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.wheelTick = tick
+	})
+}
+
 // WithTTL sets the TTL of the cache.
 // It has no effect when passing into Get().
 func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
@@ -65,6 +192,125 @@ func WithLoader[K comparable, V any](l Loader[K, V]) Option[K, V] {
 	})
 }
 
+// WithBatchLoader sets the batch loader of the cache, which is used
+// by GetMany to load all missing items at once.
+// When passing into GetMany(), it sets an ephemeral batch loader that
+// is used instead of the cache's default one.
+func WithBatchLoader[K comparable, V any](l BatchLoader[K, V]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.batchLoader = l
+	})
+}
+
+// WithMaxBatchSize sets the maximum number of keys that are passed to
+// a single call of the batch loader. Larger sets of missing keys are
+// split into multiple calls, which are executed concurrently. A value
+// of 0 or below means that the number of keys is not limited.
+// It has no effect when passing into Get().
+func WithMaxBatchSize[K comparable, V any](n int) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.maxBatchSize = n
+	})
+}
+
+// WithRefreshAfter sets the age after which an item is reloaded in
+// the background. When an item whose value was set at least the
+// provided duration ago is retrieved with Get, GetE or GetContext,
+// the cached item is returned immediately and a single background
+// reload of the item is started using the loader. If the reload
+// fails, the cached item is kept until it expires and the failure is
+// reported to the OnRefreshFailure subscribers. Further reloads of
+// the item are then postponed, starting at one second and doubling
+// with every consecutive failure up to one minute.
+// It has no effect when passing into Get().
+func WithRefreshAfter[K comparable, V any](d time.Duration) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.refreshAfter = d
+	})
+}
+
+// WithGracePeriod sets the duration for which expired items are
+// retained in the cache. During this period, Get keeps returning
+// the stale item (its IsExpired method reports true) and reloads it
+// in the background using the loader, so that callers are not
+// exposed to load latency. If the reload fails, the stale item keeps
+// being returned until the grace period ends, and further reloads are
+// postponed just like with WithRefreshAfter.
+// It has no effect when passing into Get().
+func WithGracePeriod[K comparable, V any](d time.Duration) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.gracePeriod = d
+	})
+}
+
+// WithNegativeTTL sets the duration for which keys that the loader
+// did not find are remembered as absent. During this period, Get
+// returns nil (and GetE/GetContext return ErrKnownAbsent) for such
+// keys without calling the loader again. Absent keys are not counted
+// by Len and are not returned by Keys; setting an item removes its
+// key's absence mark.
+// It has no effect when passing into Get().
+func WithNegativeTTL[K comparable, V any](d time.Duration) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.negativeTTL = d
+	})
+}
+
+// WithNegativeCapacity sets the maximum number of keys that are
+// remembered as absent (see WithNegativeTTL). Once it is reached,
+// the keys that were marked first are forgotten. By default, 10000
+// keys are remembered.
+// It has no effect when passing into Get().
+func WithNegativeCapacity[K comparable, V any](c uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.negativeCapacity = c
+	})
+}
+
+// WithSnapshotCodec sets the codec that is used to encode and decode
+// the items of cache snapshots. By default, GobSnapshotCodec is used.
+// When passing into Save() or Load(), it overrides the default value
+// of the cache.
+func WithSnapshotCodec[K comparable, V any](codec SnapshotCodec[K, V]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.snapshotCodec = codec
+	})
+}
+
+// WithKeyCodec sets the codec that is used to encode and decode the
+// keys of the cache, e.g. when its items are saved with Save(). When
+// the snapshot codec is not set, snapshots are encoded as compact
+// binary records (see NewSnapshotCodec) whenever the key or value
+// codec is set. GobCodec is used for the one that is not set.
+// When passing into Save() or Load(), it overrides the default value
+// of the cache.
+func WithKeyCodec[K comparable, V any](codec Codec[K]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.keyCodec = codec
+	})
+}
+
+// WithValueCodec sets the codec that is used to encode and decode the
+// values of the cache, e.g. when its items are saved with Save(). See
+// WithKeyCodec for more details.
+// When passing into Save() or Load(), it overrides the default value
+// of the cache.
+func WithValueCodec[K comparable, V any](codec Codec[V]) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.valueCodec = codec
+	})
+}
+
+// WithDisableLoadEvents prevents the cache instance from triggering
+// insertion events for the items that are restored by Load().
+// When passing into Load(), it overrides the default value of the
+// cache.
+func WithDisableLoadEvents[K comparable, V any]() Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.disableLoadEvents = true
+	})
+}
+
 // WithDisableTouchOnHit prevents the cache instance from
 // extending/touching an item's expiration timestamp when it is being
 // retrieved.
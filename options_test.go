@@ -1,10 +1,12 @@
 package ttlcache
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_optionFunc_apply(t *testing.T) {
@@ -35,6 +37,64 @@ func Test_WithCapacity(t *testing.T) {
 	assert.Equal(t, uint64(12), opts.capacity)
 }
 
+func Test_WithMaxCost(t *testing.T) {
+	var opts options[string, string]
+
+	WithMaxCost[string, string](12).apply(&opts)
+	assert.Equal(t, uint64(12), opts.maxCost)
+}
+
+func Test_WithCostFunc(t *testing.T) {
+	var opts options[string, string]
+
+	WithCostFunc[string, string](func(_ string, v string) uint64 {
+		return uint64(len(v))
+	}).apply(&opts)
+	require.NotNil(t, opts.costFunc)
+	assert.Equal(t, uint64(3), opts.costFunc("key", "abc"))
+}
+
+func Test_WithMaxMemory(t *testing.T) {
+	var opts options[string, string]
+
+	WithMaxMemory[string, string](12).apply(&opts)
+	assert.Equal(t, uint64(12), opts.maxCost)
+	assert.NotNil(t, opts.costFunc)
+	assert.True(t, opts.trackMemory)
+}
+
+func Test_WithEvictionPolicy(t *testing.T) {
+	var opts options[string, string]
+
+	p := NewLRUPolicy[string, string]()
+	WithEvictionPolicy[string, string](p).apply(&opts)
+	assert.Same(t, p, opts.evictionPolicy)
+}
+
+func Test_WithEvictionPolicyFunc(t *testing.T) {
+	var opts options[string, string]
+
+	WithEvictionPolicyFunc[string, string](func(capacity uint64) EvictionPolicy[string, string] {
+		return NewARCPolicy[string, string](capacity)
+	}).apply(&opts)
+	require.NotNil(t, opts.evictionPolicyFunc)
+	assert.IsType(t, &ARCPolicy[string, string]{}, opts.evictionPolicyFunc(1))
+}
+
+func Test_WithAccessBuffer(t *testing.T) {
+	var opts options[string, string]
+
+	WithAccessBuffer[string, string](64).apply(&opts)
+	assert.Equal(t, 64, opts.accessBufferSize)
+}
+
+func Test_WithTimingWheel(t *testing.T) {
+	var opts options[string, string]
+
+	WithTimingWheel[string, string](time.Millisecond).apply(&opts)
+	assert.Equal(t, time.Millisecond, opts.wheelTick)
+}
+
 func Test_WithTTL(t *testing.T) {
 	var opts options[string, string]
 
@@ -62,6 +122,91 @@ func Test_WithLoader(t *testing.T) {
 	assert.NotNil(t, opts.loader)
 }
 
+func Test_WithGracePeriod(t *testing.T) {
+	var opts options[string, string]
+
+	WithGracePeriod[string, string](time.Minute).apply(&opts)
+	assert.Equal(t, time.Minute, opts.gracePeriod)
+}
+
+func Test_WithNegativeTTL(t *testing.T) {
+	var opts options[string, string]
+
+	WithNegativeTTL[string, string](time.Minute).apply(&opts)
+	assert.Equal(t, time.Minute, opts.negativeTTL)
+}
+
+func Test_WithNegativeCapacity(t *testing.T) {
+	var opts options[string, string]
+
+	WithNegativeCapacity[string, string](5).apply(&opts)
+	assert.Equal(t, uint64(5), opts.negativeCapacity)
+}
+
+func Test_WithBatchLoader(t *testing.T) {
+	var opts options[string, string]
+
+	l := BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], _ []string) (map[string]string, error) {
+		return nil, nil
+	})
+	WithBatchLoader[string, string](l).apply(&opts)
+	assert.NotNil(t, opts.batchLoader)
+}
+
+func Test_WithMaxBatchSize(t *testing.T) {
+	var opts options[string, string]
+
+	WithMaxBatchSize[string, string](10).apply(&opts)
+	assert.Equal(t, 10, opts.maxBatchSize)
+}
+
+func Test_WithSnapshotCodec(t *testing.T) {
+	var opts options[string, string]
+
+	WithSnapshotCodec[string, string](GobSnapshotCodec[string, string]{}).apply(&opts)
+	assert.Equal(t, GobSnapshotCodec[string, string]{}, opts.snapshotCodec)
+}
+
+func Test_WithKeyCodec(t *testing.T) {
+	var opts options[string, string]
+
+	WithKeyCodec[string, string](BinaryCodec[string]{}).apply(&opts)
+	assert.Equal(t, BinaryCodec[string]{}, opts.keyCodec)
+}
+
+func Test_WithValueCodec(t *testing.T) {
+	var opts options[string, string]
+
+	WithValueCodec[string, string](JSONCodec[string]{}).apply(&opts)
+	assert.Equal(t, JSONCodec[string]{}, opts.valueCodec)
+}
+
+func Test_WithDisableLoadEvents(t *testing.T) {
+	var opts options[string, string]
+
+	WithDisableLoadEvents[string, string]().apply(&opts)
+	assert.True(t, opts.disableLoadEvents)
+}
+
+func Test_options_snapshotCodecOrDefault(t *testing.T) {
+	var opts options[string, string]
+	assert.Equal(t, GobSnapshotCodec[string, string]{}, opts.snapshotCodecOrDefault())
+
+	opts.valueCodec = JSONCodec[string]{}
+	assert.Equal(t, NewSnapshotCodec[string, string](nil, JSONCodec[string]{}), opts.snapshotCodecOrDefault())
+
+	codec := &recordingSnapshotCodec{}
+	opts.snapshotCodec = codec
+	assert.Same(t, codec, opts.snapshotCodecOrDefault())
+}
+
+func Test_WithRefreshAfter(t *testing.T) {
+	var opts options[string, string]
+
+	WithRefreshAfter[string, string](time.Minute).apply(&opts)
+	assert.Equal(t, time.Minute, opts.refreshAfter)
+}
+
 func Test_WithDisableTouchOnHit(t *testing.T) {
 	var opts options[string, string]
 
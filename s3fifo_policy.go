@@ -0,0 +1,174 @@
+package ttlcache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// S3FIFOPolicy is an S3-FIFO eviction policy.
+// New items are placed into a small FIFO queue, and only the items
+// that are accessed while in it are moved to the main FIFO queue
+// when they reach its tail. The keys of items that are evicted from
+// the small queue are remembered in a ghost queue, so that they are
+// inserted directly into the main queue when they are added again.
+// Items in the main queue are reinserted, instead of being evicted,
+// as long as they were accessed since they were last examined.
+// An access only increments the item's frequency counter, without
+// moving it.
+type S3FIFOPolicy[K comparable, V any] struct {
+	small *list.List
+	main  *list.List
+	ghost *list.List
+
+	elems  map[K]*list.Element
+	ghosts map[K]*list.Element
+
+	smallCap int
+	ghostCap int
+}
+
+// s3fifoNode holds a single item, its access frequency and the queue
+// it belongs to.
+type s3fifoNode[K comparable, V any] struct {
+	item  *Item[K, V]
+	freq  uint32
+	small bool
+}
+
+// NewS3FIFOPolicy creates a new instance of S3-FIFO eviction policy.
+// The capacity parameter should match the capacity of the cache;
+// it is used to size the small and the ghost queues.
+func NewS3FIFOPolicy[K comparable, V any](capacity uint64) *S3FIFOPolicy[K, V] {
+	smallCap := int(capacity / 10)
+	if smallCap < 1 {
+		smallCap = 1
+	}
+
+	ghostCap := int(capacity) - smallCap
+	if ghostCap < 1 {
+		ghostCap = 1
+	}
+
+	return &S3FIFOPolicy[K, V]{
+		small:    list.New(),
+		main:     list.New(),
+		ghost:    list.New(),
+		elems:    make(map[K]*list.Element),
+		ghosts:   make(map[K]*list.Element),
+		smallCap: smallCap,
+		ghostCap: ghostCap,
+	}
+}
+
+// OnInsert places the item at the head of the small queue or, if its
+// key is remembered in the ghost queue, at the head of the main
+// queue.
+func (p *S3FIFOPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	node := &s3fifoNode[K, V]{item: item, small: true}
+	queue := p.small
+
+	if ghost := p.ghosts[item.key]; ghost != nil {
+		p.ghost.Remove(ghost)
+		delete(p.ghosts, item.key)
+
+		node.small = false
+		queue = p.main
+	}
+
+	p.elems[item.key] = queue.PushFront(node)
+}
+
+// OnAccess increments the item's frequency.
+func (p *S3FIFOPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	p.OnConcurrentAccess(item)
+}
+
+// OnConcurrentAccess increments the item's frequency.
+func (p *S3FIFOPolicy[K, V]) OnConcurrentAccess(item *Item[K, V]) {
+	elem := p.elems[item.key]
+	if elem == nil {
+		return
+	}
+
+	node := elem.Value.(*s3fifoNode[K, V])
+	for {
+		freq := atomic.LoadUint32(&node.freq)
+		if freq >= 3 || atomic.CompareAndSwapUint32(&node.freq, freq, freq+1) {
+			return
+		}
+	}
+}
+
+// OnUpdate increments the item's frequency.
+func (p *S3FIFOPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	p.OnConcurrentAccess(item)
+}
+
+// OnRemove removes the item from its queue. If the item was evicted
+// from the small queue because the capacity of the cache was reached,
+// its key is remembered in the ghost queue.
+func (p *S3FIFOPolicy[K, V]) OnRemove(item *Item[K, V], reason EvictionReason) {
+	elem := p.elems[item.key]
+	if elem == nil {
+		return
+	}
+
+	node := elem.Value.(*s3fifoNode[K, V])
+	delete(p.elems, item.key)
+
+	if !node.small {
+		p.main.Remove(elem)
+		return
+	}
+
+	p.small.Remove(elem)
+
+	if reason != EvictionReasonCapacityReached {
+		return
+	}
+
+	p.ghosts[item.key] = p.ghost.PushFront(item.key)
+	if p.ghost.Len() > p.ghostCap {
+		key := p.ghost.Remove(p.ghost.Back()).(K)
+		delete(p.ghosts, key)
+	}
+}
+
+// Victim returns the tail item of the small queue if it is full,
+// or the tail item of the main queue otherwise. Accessed tail items
+// of the small queue are moved to the main queue and accessed tail
+// items of the main queue are reinserted before a victim is found.
+func (p *S3FIFOPolicy[K, V]) Victim() *Item[K, V] {
+	for {
+		if p.small.Len() > 0 && (p.small.Len() >= p.smallCap || p.main.Len() == 0) {
+			elem := p.small.Back()
+			node := elem.Value.(*s3fifoNode[K, V])
+
+			if atomic.LoadUint32(&node.freq) == 0 {
+				return node.item
+			}
+
+			p.small.Remove(elem)
+			atomic.StoreUint32(&node.freq, 0)
+			node.small = false
+			p.elems[node.item.key] = p.main.PushFront(node)
+
+			continue
+		}
+
+		elem := p.main.Back()
+		if elem == nil {
+			return nil
+		}
+
+		node := elem.Value.(*s3fifoNode[K, V])
+
+		freq := atomic.LoadUint32(&node.freq)
+		if freq == 0 {
+			return node.item
+		}
+
+		atomic.StoreUint32(&node.freq, freq-1)
+		p.main.MoveToFront(elem)
+	}
+}
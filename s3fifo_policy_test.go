@@ -0,0 +1,197 @@
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewS3FIFOPolicy(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](100)
+	require.NotNil(t, p)
+	assert.NotNil(t, p.small)
+	assert.NotNil(t, p.main)
+	assert.NotNil(t, p.ghost)
+	assert.NotNil(t, p.elems)
+	assert.NotNil(t, p.ghosts)
+	assert.Equal(t, 10, p.smallCap)
+	assert.Equal(t, 90, p.ghostCap)
+
+	p = NewS3FIFOPolicy[string, string](1)
+	assert.Equal(t, 1, p.smallCap)
+	assert.Equal(t, 1, p.ghostCap)
+}
+
+func Test_S3FIFOPolicy_OnInsert(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](10)
+
+	// new key
+	p.OnInsert(&Item[string, string]{key: "1"})
+	assert.Equal(t, 1, p.small.Len())
+	assert.True(t, p.elems["1"].Value.(*s3fifoNode[string, string]).small)
+
+	// key in ghost queue
+	p.ghosts["2"] = p.ghost.PushFront("2")
+
+	p.OnInsert(&Item[string, string]{key: "2"})
+	assert.Equal(t, 1, p.main.Len())
+	assert.False(t, p.elems["2"].Value.(*s3fifoNode[string, string]).small)
+	assert.Zero(t, p.ghost.Len())
+	assert.Empty(t, p.ghosts)
+}
+
+func Test_S3FIFOPolicy_OnAccess(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](10)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	p.OnAccess(item)
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+
+	// frequency is capped
+	for i := 0; i < 5; i++ {
+		p.OnAccess(item)
+	}
+	assert.Equal(t, uint32(3), p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "2"})
+	assert.Len(t, p.elems, 1)
+}
+
+func Test_S3FIFOPolicy_OnConcurrentAccess(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](10)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			p.OnConcurrentAccess(item)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, uint32(2), p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+}
+
+func Test_S3FIFOPolicy_OnUpdate(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](10)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	p.OnUpdate(item)
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+}
+
+func Test_S3FIFOPolicy_OnRemove(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](2)
+	items := []*Item[string, string]{{key: "1"}, {key: "2"}, {key: "3"}, {key: "4"}}
+	for _, item := range items {
+		p.OnInsert(item)
+	}
+
+	// deleted items from the small queue are not remembered
+	p.OnRemove(items[0], EvictionReasonDeleted)
+	assert.Equal(t, 3, p.small.Len())
+	assert.Empty(t, p.ghosts)
+
+	// evicted items from the small queue are remembered
+	p.OnRemove(items[1], EvictionReasonCapacityReached)
+	assert.Contains(t, p.ghosts, "2")
+
+	// ghost queue is bounded
+	p.OnRemove(items[2], EvictionReasonCapacityReached)
+	assert.Equal(t, 1, p.ghost.Len())
+	assert.Contains(t, p.ghosts, "3")
+
+	// evicted items from the main queue are not remembered
+	p.OnInsert(items[2])
+	require.Equal(t, 1, p.main.Len())
+
+	p.OnRemove(items[2], EvictionReasonCapacityReached)
+	assert.Zero(t, p.main.Len())
+	assert.Empty(t, p.ghosts)
+
+	// unknown items are ignored
+	p.OnRemove(items[0], EvictionReasonCapacityReached)
+	assert.Len(t, p.elems, 1)
+}
+
+func Test_S3FIFOPolicy_Victim(t *testing.T) {
+	p := NewS3FIFOPolicy[string, string](10)
+	assert.Nil(t, p.Victim())
+
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+
+	// unaccessed tail of the small queue
+	assert.Same(t, item1, p.Victim())
+
+	// accessed tail of the small queue is moved to the main queue
+	p.OnAccess(item1)
+	assert.Same(t, item2, p.Victim())
+	assert.Equal(t, 1, p.main.Len())
+	assert.False(t, p.elems["1"].Value.(*s3fifoNode[string, string]).small)
+	assert.Zero(t, p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+
+	// small queue is not full
+	p.OnRemove(item2, EvictionReasonCapacityReached)
+	p.OnInsert(item3)
+	p.smallCap = 2
+	assert.Same(t, item1, p.Victim())
+
+	// accessed tail of the main queue is reinserted
+	p.OnRemove(item3, EvictionReasonDeleted)
+	p.OnInsert(item2) // the key is remembered in the ghost queue
+	p.OnAccess(item1)
+	p.OnAccess(item1)
+	assert.Same(t, item2, p.Victim())
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*s3fifoNode[string, string]).freq)
+	assert.Same(t, item1, p.main.Front().Value.(*s3fifoNode[string, string]).item)
+}
+
+func Test_Cache_S3FIFOPolicy(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		evicted []string
+	)
+
+	cache := New[string, string](
+		WithCapacity[string, string](3),
+		WithEvictionPolicy[string, string](NewS3FIFOPolicy[string, string](3)),
+	)
+	del := cache.OnEviction(func(_ context.Context, r EvictionReason, item *Item[string, string]) {
+		assert.Equal(t, EvictionReasonCapacityReached, r)
+
+		mu.Lock()
+		evicted = append(evicted, item.Key())
+		mu.Unlock()
+
+		wg.Done()
+	})
+	defer del()
+
+	wg.Add(2)
+
+	cache.Set("1", "1", NoTTL)
+	cache.Set("2", "2", NoTTL)
+	cache.Set("3", "3", NoTTL)
+	cache.Get("1")
+	cache.Set("4", "4", NoTTL)
+	cache.Set("5", "5", NoTTL)
+
+	wg.Wait()
+	assert.ElementsMatch(t, []string{"2", "3"}, evicted)
+	assert.ElementsMatch(t, []string{"1", "4", "5"}, cache.Keys())
+}
@@ -0,0 +1,689 @@
+package ttlcache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrSnapshotFormat is returned when a snapshot that was written
+	// by a secure snapshot codec or a codec that was created with
+	// NewSnapshotCodec has an invalid or unsupported header, or when
+	// a snapshot or the records of a write-ahead log do not match
+	// the configuration of the codec that reads them.
+	ErrSnapshotFormat = errors.New("ttlcache: invalid snapshot format")
+
+	// ErrSnapshotDecryption is returned when a snapshot or a record of
+	// a write-ahead log cannot be decrypted, e.g. because it was
+	// modified or the wrong key was provided.
+	ErrSnapshotDecryption = errors.New("ttlcache: snapshot cannot be decrypted")
+
+	// ErrSnapshotKeyNotFound is returned by StaticKeyProvider when the
+	// requested key is not found.
+	ErrSnapshotKeyNotFound = errors.New("ttlcache: snapshot key not found")
+)
+
+// SnapshotCompression specifies the compression algorithm of
+// snapshots.
+type SnapshotCompression byte
+
+// Available compression algorithms.
+const (
+	SnapshotCompressionNone SnapshotCompression = iota
+	SnapshotCompressionGzip
+)
+
+// KeyProvider is an interface that provides the keys that are used
+// to encrypt and decrypt snapshots. Each key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey should return the ID and the value of the key that
+	// is used to encrypt new snapshots.
+	CurrentKey() (string, []byte, error)
+
+	// Key should return the value of the key with the provided ID,
+	// which is used to decrypt the snapshots that were encrypted with
+	// it, even after it is no longer the current key.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider that holds a fixed set of keys.
+type StaticKeyProvider struct {
+	// CurrentID specifies the ID of the key that is used to encrypt
+	// new snapshots.
+	CurrentID string
+
+	// Keys holds the values of the keys by their IDs.
+	Keys map[string][]byte
+}
+
+// CurrentKey returns the ID and the value of the current key.
+func (p StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := p.Key(p.CurrentID)
+	return p.CurrentID, key, err
+}
+
+// Key returns the value of the key with the provided ID.
+func (p StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrSnapshotKeyNotFound, id)
+	}
+
+	return key, nil
+}
+
+// SecureSnapshotConfig holds the configuration of a secure snapshot
+// codec.
+type SecureSnapshotConfig struct {
+	// CodecName specifies the name of the wrapped codec, which is
+	// recorded in the header of each snapshot. Snapshots that were
+	// written with another codec name are rejected.
+	CodecName string
+
+	// Compression specifies the compression algorithm of new
+	// snapshots. Existing snapshots are decompressed with the
+	// algorithm that is recorded in their headers.
+	Compression SnapshotCompression
+
+	// Keys specifies the provider of the encryption keys.
+	// If it is nil, snapshots are not encrypted. Otherwise,
+	// unencrypted snapshots are rejected.
+	Keys KeyProvider
+
+	// ChunkSize specifies the size in bytes of the chunks that are
+	// encrypted separately.
+	// If it is 0 or below, 64 KiB are used.
+	ChunkSize int
+}
+
+const (
+	// secureSnapshotMagic starts the header of each snapshot.
+	secureSnapshotMagic = "TTLS"
+
+	// secureSnapshotVersion is the version of the format of new
+	// snapshots.
+	secureSnapshotVersion = 1
+
+	// maxSecureSnapshotChunkSize limits the chunk size of the
+	// snapshots that are read, since their headers are not trusted
+	// before the first chunk is decrypted.
+	maxSecureSnapshotChunkSize = 16 << 20
+)
+
+// secureSnapshotCodec is a SnapshotCodec that compresses and encrypts
+// the output of the wrapped codec.
+type secureSnapshotCodec[K comparable, V any] struct {
+	codec SnapshotCodec[K, V]
+	cfg   SecureSnapshotConfig
+}
+
+// NewSecureSnapshotCodec creates a new SnapshotCodec that compresses
+// the snapshots that are encoded by the provided codec and encrypts
+// them with AES-GCM, according to the provided configuration. If the
+// codec is nil, GobSnapshotCodec is used.
+// Each snapshot starts with a header that records the version of the
+// format, the compression algorithm, the codec name and the ID of the
+// encryption key, so that the snapshots remain readable after the
+// current key is rotated or the configuration is changed. The header
+// is authenticated along with the encrypted data, which is split into
+// chunks, so that modified, reordered or truncated snapshots are
+// rejected.
+// When the codec is used by a cache that was created with Open, the
+// records of the write-ahead log segments are encrypted with the same
+// keys too, though they are not compressed.
+func NewSecureSnapshotCodec[K comparable, V any](codec SnapshotCodec[K, V], cfg SecureSnapshotConfig) SnapshotCodec[K, V] {
+	if codec == nil {
+		codec = GobSnapshotCodec[K, V]{}
+	}
+
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 64 << 10
+	}
+
+	return secureSnapshotCodec[K, V]{
+		codec: codec,
+		cfg:   cfg,
+	}
+}
+
+// NewEncoder returns an encoder that writes the header to the
+// provided writer, followed by the compressed and encrypted snapshot
+// items. The snapshot is complete only after the encoder is closed.
+func (c secureSnapshotCodec[K, V]) NewEncoder(w io.Writer) SnapshotEncoder[K, V] {
+	enc := &secureSnapshotEncoder[K, V]{}
+	enc.err = enc.init(c, w)
+
+	return enc
+}
+
+// NewDecoder returns a decoder that reads the header and the snapshot
+// items from the provided reader.
+func (c secureSnapshotCodec[K, V]) NewDecoder(r io.Reader) SnapshotDecoder[K, V] {
+	return &secureSnapshotDecoder[K, V]{
+		codec: c,
+		r:     r,
+	}
+}
+
+// secureSnapshotHeader holds the header of a snapshot.
+type secureSnapshotHeader struct {
+	version     byte
+	compression SnapshotCompression
+	encrypted   bool
+	codecName   string
+	keyID       string
+	chunkSize   int
+	nonce       []byte
+}
+
+// encode returns the binary form of the header.
+func (h secureSnapshotHeader) encode() []byte {
+	buf := append([]byte(secureSnapshotMagic), h.version, byte(h.compression))
+
+	var encrypted byte
+	if h.encrypted {
+		encrypted = 1
+	}
+
+	buf = append(buf, encrypted)
+	buf = appendUvarint(buf, uint64(len(h.codecName)))
+	buf = append(buf, h.codecName...)
+	buf = appendUvarint(buf, uint64(len(h.keyID)))
+	buf = append(buf, h.keyID...)
+	buf = appendUvarint(buf, uint64(h.chunkSize))
+	buf = appendUvarint(buf, uint64(len(h.nonce)))
+
+	return append(buf, h.nonce...)
+}
+
+// readSecureSnapshotHeader reads the header of a snapshot and returns
+// it along with its binary form.
+func readSecureSnapshotHeader(r *bufio.Reader) (secureSnapshotHeader, []byte, error) {
+	var h secureSnapshotHeader
+
+	rr := &recordingReader{r: r}
+
+	fixed := make([]byte, len(secureSnapshotMagic)+3)
+	if _, err := io.ReadFull(rr, fixed); err != nil {
+		return h, nil, unexpectedEOF(err)
+	}
+
+	if string(fixed[:len(secureSnapshotMagic)]) != secureSnapshotMagic {
+		return h, nil, fmt.Errorf("%w: missing header", ErrSnapshotFormat)
+	}
+
+	h.version = fixed[len(secureSnapshotMagic)]
+	h.compression = SnapshotCompression(fixed[len(secureSnapshotMagic)+1])
+	h.encrypted = fixed[len(secureSnapshotMagic)+2] == 1
+
+	if h.version != secureSnapshotVersion {
+		return h, nil, fmt.Errorf("%w: unsupported version %d", ErrSnapshotFormat, h.version)
+	}
+
+	var buf []byte
+
+	readField := func() (string, error) {
+		n, err := binary.ReadUvarint(rr)
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		if n > 1<<10 {
+			return "", fmt.Errorf("%w: header field is too long", ErrSnapshotFormat)
+		}
+
+		if err := readRecordBytes(rr, &buf, n); err != nil {
+			return "", err
+		}
+
+		return string(buf), nil
+	}
+
+	var err error
+
+	if h.codecName, err = readField(); err != nil {
+		return h, nil, err
+	}
+
+	if h.keyID, err = readField(); err != nil {
+		return h, nil, err
+	}
+
+	chunkSize, err := binary.ReadUvarint(rr)
+	if err != nil {
+		return h, nil, unexpectedEOF(err)
+	}
+
+	if chunkSize == 0 || chunkSize > maxSecureSnapshotChunkSize {
+		return h, nil, fmt.Errorf("%w: invalid chunk size %d", ErrSnapshotFormat, chunkSize)
+	}
+
+	h.chunkSize = int(chunkSize)
+
+	nonce, err := readField()
+	if err != nil {
+		return h, nil, err
+	}
+
+	h.nonce = []byte(nonce)
+
+	return h, rr.data, nil
+}
+
+// recordingReader records the data that is read from the wrapped
+// reader.
+type recordingReader struct {
+	r    *bufio.Reader
+	data []byte
+}
+
+// Read reads and records data.
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.data = append(r.data, p[:n]...)
+
+	return n, err
+}
+
+// ReadByte reads and records a single byte.
+func (r *recordingReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.data = append(r.data, b)
+	}
+
+	return b, err
+}
+
+// newSnapshotAEAD creates a new AES-GCM cipher with the provided key.
+func newSnapshotAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// secureSnapshotEncoder compresses and encrypts the output of the
+// wrapped encoder.
+type secureSnapshotEncoder[K comparable, V any] struct {
+	enc     SnapshotEncoder[K, V]
+	closers []io.Closer
+	err     error
+}
+
+// init writes the header to the provided writer and prepares the
+// chain of writers that the wrapped encoder writes to.
+func (e *secureSnapshotEncoder[K, V]) init(c secureSnapshotCodec[K, V], w io.Writer) error {
+	h := secureSnapshotHeader{
+		version:     secureSnapshotVersion,
+		compression: c.cfg.Compression,
+		encrypted:   c.cfg.Keys != nil,
+		codecName:   c.cfg.CodecName,
+		chunkSize:   c.cfg.ChunkSize,
+	}
+
+	if h.compression > SnapshotCompressionGzip {
+		return fmt.Errorf("%w: unknown compression %d", ErrSnapshotFormat, h.compression)
+	}
+
+	var (
+		aead cipher.AEAD
+		err  error
+	)
+
+	if h.encrypted {
+		var key []byte
+
+		if h.keyID, key, err = c.cfg.Keys.CurrentKey(); err != nil {
+			return err
+		}
+
+		if aead, err = newSnapshotAEAD(key); err != nil {
+			return err
+		}
+
+		h.nonce = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(h.nonce); err != nil {
+			return err
+		}
+	}
+
+	header := h.encode()
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if aead != nil {
+		sw := &sealWriter{
+			w:      w,
+			aead:   aead,
+			header: header,
+			nonce:  h.nonce,
+			buf:    make([]byte, 0, h.chunkSize),
+		}
+		e.closers = append(e.closers, sw)
+		w = sw
+	}
+
+	if h.compression == SnapshotCompressionGzip {
+		zw := gzip.NewWriter(w)
+		e.closers = append(e.closers, zw)
+		w = zw
+	}
+
+	e.enc = c.codec.NewEncoder(w)
+
+	return nil
+}
+
+// Encode writes the encoded snapshot item.
+func (e *secureSnapshotEncoder[K, V]) Encode(item SnapshotItem[K, V]) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.enc.Encode(item)
+}
+
+// Close flushes the compressed data and writes the final encrypted
+// chunk.
+func (e *secureSnapshotEncoder[K, V]) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if c, ok := e.enc.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+
+	// the outermost writers must be closed first
+	for i := len(e.closers) - 1; i >= 0; i-- {
+		if err := e.closers[i].Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secureSnapshotDecoder decrypts and decompresses the input of the
+// wrapped decoder.
+type secureSnapshotDecoder[K comparable, V any] struct {
+	codec secureSnapshotCodec[K, V]
+	r     io.Reader
+	dec   SnapshotDecoder[K, V]
+	err   error
+}
+
+// init reads the header from the reader and prepares the chain of
+// readers that the wrapped decoder reads from.
+func (d *secureSnapshotDecoder[K, V]) init() error {
+	br := bufio.NewReader(d.r)
+
+	h, header, err := readSecureSnapshotHeader(br)
+	if err != nil {
+		return err
+	}
+
+	if d.codec.cfg.CodecName != "" && h.codecName != d.codec.cfg.CodecName {
+		return fmt.Errorf("%w: snapshot codec %q does not match %q", ErrSnapshotFormat, h.codecName, d.codec.cfg.CodecName)
+	}
+
+	var r io.Reader = br
+
+	switch {
+	case h.encrypted && d.codec.cfg.Keys == nil:
+		return fmt.Errorf("%w: snapshot is encrypted, but no keys are provided", ErrSnapshotFormat)
+	case !h.encrypted && d.codec.cfg.Keys != nil:
+		return fmt.Errorf("%w: snapshot is not encrypted", ErrSnapshotFormat)
+	case h.encrypted:
+		key, err := d.codec.cfg.Keys.Key(h.keyID)
+		if err != nil {
+			return err
+		}
+
+		aead, err := newSnapshotAEAD(key)
+		if err != nil {
+			return err
+		}
+
+		if len(h.nonce) != aead.NonceSize() {
+			return fmt.Errorf("%w: invalid nonce", ErrSnapshotFormat)
+		}
+
+		r = &openReader{
+			r:         br,
+			aead:      aead,
+			header:    header,
+			nonce:     h.nonce,
+			chunkSize: h.chunkSize,
+		}
+	}
+
+	switch h.compression {
+	case SnapshotCompressionNone:
+	case SnapshotCompressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		r = zr
+	default:
+		return fmt.Errorf("%w: unknown compression %d", ErrSnapshotFormat, h.compression)
+	}
+
+	d.dec = d.codec.codec.NewDecoder(r)
+
+	return nil
+}
+
+// Decode reads the next snapshot item.
+func (d *secureSnapshotDecoder[K, V]) Decode() (SnapshotItem[K, V], error) {
+	if d.dec == nil && d.err == nil {
+		d.err = d.init()
+	}
+
+	if d.err != nil {
+		return SnapshotItem[K, V]{}, d.err
+	}
+
+	return d.dec.Decode()
+}
+
+// chunkNonce returns the nonce of the chunk with the provided index,
+// which is derived from the provided base nonce.
+func chunkNonce(dst, base []byte, index uint64) []byte {
+	dst = append(dst[:0], base...)
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], index)
+
+	for i := range counter {
+		dst[len(dst)-len(counter)+i] ^= counter[i]
+	}
+
+	return dst
+}
+
+// chunkAAD returns the additional authenticated data of a chunk,
+// which binds it to the snapshot's header and marks the final chunk.
+func chunkAAD(dst, header []byte, final bool) []byte {
+	dst = append(dst[:0], header...)
+
+	if final {
+		return append(dst, 1)
+	}
+
+	return append(dst, 0)
+}
+
+// sealWriter encrypts the written data in chunks. Each chunk is
+// written along with a flag that marks the final chunk and its
+// length.
+type sealWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	nonce  []byte
+	buf    []byte
+	index  uint64
+
+	// scratch buffers
+	chunkNonce []byte
+	aad        []byte
+	out        []byte
+}
+
+// Write buffers the provided data and encrypts all complete chunks.
+// The last chunk is kept until the writer is closed, since it may
+// be the final one.
+func (w *sealWriter) Write(p []byte) (int, error) {
+	var n int
+
+	for len(p) > 0 {
+		if len(w.buf) == cap(w.buf) {
+			if err := w.seal(false); err != nil {
+				return n, err
+			}
+		}
+
+		c := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+	}
+
+	return n, nil
+}
+
+// Close encrypts and writes the final chunk.
+func (w *sealWriter) Close() error {
+	return w.seal(true)
+}
+
+// seal encrypts and writes the buffered chunk.
+func (w *sealWriter) seal(final bool) error {
+	w.chunkNonce = chunkNonce(w.chunkNonce, w.nonce, w.index)
+	w.aad = chunkAAD(w.aad, w.header, final)
+
+	var flag byte
+	if final {
+		flag = 1
+	}
+
+	w.out = append(w.out[:0], flag, 0, 0, 0, 0)
+	w.out = w.aead.Seal(w.out, w.chunkNonce, w.buf, w.aad)
+	binary.LittleEndian.PutUint32(w.out[1:], uint32(len(w.out)-5))
+
+	if _, err := w.w.Write(w.out); err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:0]
+	w.index++
+
+	return nil
+}
+
+// openReader decrypts the chunks that were written by sealWriter.
+type openReader struct {
+	r         io.Reader
+	aead      cipher.AEAD
+	header    []byte
+	nonce     []byte
+	chunkSize int
+	index     uint64
+	final     bool
+
+	plain []byte
+	off   int
+
+	// scratch buffers
+	chunkNonce []byte
+	aad        []byte
+	in         []byte
+}
+
+// Read reads the decrypted data. io.ErrUnexpectedEOF is returned if
+// the data ends before the final chunk, while ErrSnapshotFormat is
+// returned if any data follows it.
+func (r *openReader) Read(p []byte) (int, error) {
+	for r.off == len(r.plain) {
+		if r.final {
+			return 0, io.EOF
+		}
+
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain[r.off:])
+	r.off += n
+
+	return n, nil
+}
+
+// open reads and decrypts the next chunk.
+func (r *openReader) open() error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r.r, prefix[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	n := int(binary.LittleEndian.Uint32(prefix[1:]))
+	if prefix[0] > 1 || n > r.chunkSize+r.aead.Overhead() {
+		return ErrSnapshotDecryption
+	}
+
+	if cap(r.in) < n {
+		r.in = make([]byte, n)
+	}
+
+	r.in = r.in[:n]
+	if _, err := io.ReadFull(r.r, r.in); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	final := prefix[0] == 1
+	r.chunkNonce = chunkNonce(r.chunkNonce, r.nonce, r.index)
+	r.aad = chunkAAD(r.aad, r.header, final)
+
+	plain, err := r.aead.Open(r.plain[:0], r.chunkNonce, r.in, r.aad)
+	if err != nil {
+		return ErrSnapshotDecryption
+	}
+
+	r.plain = plain
+	r.off = 0
+	r.index++
+	r.final = final
+
+	if final {
+		var b [1]byte
+
+		n, err := io.ReadFull(r.r, b[:])
+		if n > 0 {
+			return fmt.Errorf("%w: data after the final chunk", ErrSnapshotFormat)
+		}
+
+		if err != io.EOF {
+			return err
+		}
+	}
+
+	return nil
+}
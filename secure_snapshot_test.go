@@ -0,0 +1,270 @@
+package ttlcache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StaticKeyProvider(t *testing.T) {
+	keys := StaticKeyProvider{
+		CurrentID: "2",
+		Keys: map[string][]byte{
+			"1": []byte("key1"),
+			"2": []byte("key2"),
+		},
+	}
+
+	id, key, err := keys.CurrentKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2", id)
+	assert.Equal(t, []byte("key2"), key)
+
+	key, err = keys.Key("1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key1"), key)
+
+	_, err = keys.Key("3")
+	assert.ErrorIs(t, err, ErrSnapshotKeyNotFound)
+
+	keys.CurrentID = "3"
+	_, _, err = keys.CurrentKey()
+	assert.ErrorIs(t, err, ErrSnapshotKeyNotFound)
+}
+
+func Test_NewSecureSnapshotCodec(t *testing.T) {
+	codec := NewSecureSnapshotCodec[string, string](nil, SecureSnapshotConfig{})
+	assert.Equal(t, secureSnapshotCodec[string, string]{
+		codec: GobSnapshotCodec[string, string]{},
+		cfg:   SecureSnapshotConfig{ChunkSize: 64 << 10},
+	}, codec)
+
+	inner := NewSnapshotCodec[string, string](BinaryCodec[string]{}, BinaryCodec[string]{})
+	codec = NewSecureSnapshotCodec[string, string](inner, SecureSnapshotConfig{ChunkSize: 10})
+	assert.Equal(t, secureSnapshotCodec[string, string]{
+		codec: inner,
+		cfg:   SecureSnapshotConfig{ChunkSize: 10},
+	}, codec)
+}
+
+func Test_secureSnapshotCodec(t *testing.T) {
+	keys := StaticKeyProvider{
+		CurrentID: "1",
+		Keys: map[string][]byte{
+			"1": bytes.Repeat([]byte{1}, 32),
+			"2": bytes.Repeat([]byte{2}, 16),
+		},
+	}
+
+	items := []SnapshotItem[string, string]{
+		{Key: "1", Value: strings.Repeat("value1", 100), TTL: time.Hour, ExpiresAt: time.Now().Add(time.Hour)},
+		{Key: "2", Value: "value2", TTL: NoTTL},
+		{Key: "3", Deleted: true},
+	}
+
+	encode := func(cfg SecureSnapshotConfig, items []SnapshotItem[string, string]) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, encodeSnapshot(&buf, NewSecureSnapshotCodec[string, string](nil, cfg), items))
+
+		return buf.Bytes()
+	}
+
+	decode := func(cfg SecureSnapshotConfig, data []byte) ([]SnapshotItem[string, string], error) {
+		dec := NewSecureSnapshotCodec[string, string](nil, cfg).NewDecoder(bytes.NewReader(data))
+
+		var res []SnapshotItem[string, string]
+		for {
+			item, err := dec.Decode()
+			if err == io.EOF {
+				return res, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, item)
+		}
+	}
+
+	// all combinations
+	for _, cfg := range []SecureSnapshotConfig{
+		{},
+		{Compression: SnapshotCompressionGzip},
+		{Keys: keys, ChunkSize: 16},
+		{Keys: keys, CodecName: "gob", Compression: SnapshotCompressionGzip, ChunkSize: 16},
+	} {
+		data := encode(cfg, items)
+		if cfg.Keys != nil {
+			assert.NotContains(t, string(data), "value2")
+		}
+
+		res, err := decode(cfg, data)
+		require.NoError(t, err)
+		require.Len(t, res, len(items))
+
+		for i, item := range items {
+			assert.Equal(t, item.Key, res[i].Key)
+			assert.Equal(t, item.Value, res[i].Value)
+			assert.True(t, item.ExpiresAt.Equal(res[i].ExpiresAt))
+			assert.Equal(t, item.Deleted, res[i].Deleted)
+		}
+
+		// empty snapshot
+		res, err = decode(cfg, encode(cfg, nil))
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	}
+
+	cfg := SecureSnapshotConfig{
+		CodecName:   "gob",
+		Compression: SnapshotCompressionGzip,
+		Keys:        keys,
+		ChunkSize:   16,
+	}
+	data := encode(cfg, items)
+
+	// rotated key and changed compression
+	rotated := cfg
+	rotated.Compression = SnapshotCompressionNone
+	rotated.Keys = StaticKeyProvider{CurrentID: "2", Keys: keys.Keys}
+
+	res, err := decode(rotated, data)
+	require.NoError(t, err)
+	assert.Len(t, res, len(items))
+
+	// unknown key
+	_, err = decode(SecureSnapshotConfig{Keys: StaticKeyProvider{Keys: map[string][]byte{"2": keys.Keys["2"]}}}, data)
+	assert.ErrorIs(t, err, ErrSnapshotKeyNotFound)
+
+	// wrong key
+	_, err = decode(SecureSnapshotConfig{Keys: StaticKeyProvider{Keys: map[string][]byte{"1": keys.Keys["2"]}}}, data)
+	assert.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	// codec mismatch
+	_, err = decode(SecureSnapshotConfig{CodecName: "json", Keys: keys}, data)
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// missing keys
+	_, err = decode(SecureSnapshotConfig{}, data)
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// unencrypted snapshot
+	_, err = decode(cfg, encode(SecureSnapshotConfig{}, items))
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// modified data
+	for _, i := range []int{5, len(data) / 2, len(data) - 1} {
+		modified := append([]byte(nil), data...)
+		modified[i]++
+
+		_, err = decode(cfg, modified)
+		assert.Error(t, err)
+	}
+
+	// truncated data
+	for i := 0; i < len(data); i++ {
+		_, err = decode(cfg, data[:i])
+		assert.Error(t, err)
+	}
+
+	// trailing data
+	for _, cfg := range []SecureSnapshotConfig{cfg, {Keys: keys}} {
+		_, err = decode(cfg, append(encode(cfg, items), 0))
+		assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+		_, err = decode(cfg, append(encode(cfg, nil), encode(cfg, items)...))
+		assert.ErrorIs(t, err, ErrSnapshotFormat)
+	}
+
+	// plain data
+	_, err = decode(SecureSnapshotConfig{}, []byte("invalid data"))
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// unsupported version
+	modified := append([]byte(nil), data...)
+	modified[len(secureSnapshotMagic)]++
+
+	_, err = decode(cfg, modified)
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// failed encoding
+	errWrite := errors.New("error")
+	for _, cfg := range []SecureSnapshotConfig{
+		{Compression: SnapshotCompressionGzip + 1},
+		{Keys: StaticKeyProvider{}},
+		{Keys: StaticKeyProvider{CurrentID: "1", Keys: map[string][]byte{"1": []byte("key")}}},
+	} {
+		enc := NewSecureSnapshotCodec[string, string](nil, cfg).NewEncoder(&bytes.Buffer{})
+		assert.Error(t, enc.Encode(items[0]))
+		assert.Error(t, enc.(io.Closer).Close())
+	}
+
+	err = encodeSnapshot(failingWriter{err: errWrite}, NewSecureSnapshotCodec[string, string](nil, cfg), items)
+	assert.Equal(t, errWrite, err)
+}
+
+func Test_secureSnapshotCodec_cache(t *testing.T) {
+	codec := NewSecureSnapshotCodec[string, string](
+		NewSnapshotCodec[string, string](BinaryCodec[string]{}, BinaryCodec[string]{}),
+		SecureSnapshotConfig{
+			CodecName:   "binary",
+			Compression: SnapshotCompressionGzip,
+			Keys: StaticKeyProvider{
+				CurrentID: "1",
+				Keys:      map[string][]byte{"1": bytes.Repeat([]byte{1}, 32)},
+			},
+		},
+	)
+
+	cache := New[string, string](WithSnapshotCodec[string, string](codec))
+	cache.Set("1", "value1", NoTTL)
+	cache.Set("2", "value2", time.Hour)
+
+	var buf bytes.Buffer
+	require.NoError(t, cache.Save(&buf))
+	assert.NotContains(t, buf.String(), "value1")
+
+	restored := New[string, string](WithSnapshotCodec[string, string](codec))
+	require.NoError(t, restored.Load(&buf))
+	assert.ElementsMatch(t, []string{"1", "2"}, restored.Keys())
+	assert.Equal(t, "value1", restored.Get("1").Value())
+
+	// write-ahead log segments are encrypted too
+	dir := t.TempDir()
+
+	c, err := Open[string, string](WALConfig{Dir: dir}, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	c.Set("1", "value1", NoTTL)
+	path := c.items.wal.path(1, walSegmentExt)
+	require.NoError(t, c.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "value1")
+
+	c, err = Open[string, string](WALConfig{Dir: dir}, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	assert.Equal(t, "value1", c.Get("1").Value())
+	require.NoError(t, c.Close())
+
+	_, err = Open[string, string](WALConfig{Dir: dir})
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+}
+
+func Test_chunkNonce(t *testing.T) {
+	base := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	assert.Equal(t, base, chunkNonce(nil, base, 0))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 13}, chunkNonce(nil, base, 1))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12}, chunkNonce(nil, base, 256))
+}
@@ -0,0 +1,514 @@
+package ttlcache
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedCache is a cache that spreads its items across multiple
+// independent Cache shards by the hashes of their keys. Each shard
+// has its own lock, eviction policy and expiration queue, so
+// operations on keys of different shards do not contend with each
+// other.
+// Capacity and maximum cost limits are divided between shards, so
+// items are evicted when their shard (rather than the whole cache)
+// reaches its limit.
+type ShardedCache[K comparable, V any] struct {
+	shards []*Cache[K, V]
+
+	// epoch identifies the cache instance in snapshot tokens.
+	epoch uint64
+
+	// delta holds the state of the delta snapshots that are written
+	// by SaveSince.
+	delta struct {
+		mu  sync.Mutex
+		seq uint64
+	}
+}
+
+// NewSharded creates a new instance of sharded cache with the
+// provided number of shards. All options are applied to each shard,
+// except for the capacity and the maximum cost, which are divided
+// between shards. If the capacity or the maximum cost is smaller than
+// the number of shards, the number of shards is reduced accordingly.
+// Since an eviction policy instance must not be shared between
+// shards, WithEvictionPolicyFunc should be used instead of
+// WithEvictionPolicy; NewSharded panics if an eviction policy
+// instance is provided for more than a single shard.
+func NewSharded[K comparable, V any](shards int, opts ...Option[K, V]) *ShardedCache[K, V] {
+	var o options[K, V]
+	applyOptions(&o, opts...)
+
+	n := uint64(1)
+	if shards > 1 {
+		n = uint64(shards)
+	}
+
+	if o.capacity > 0 && o.capacity < n {
+		n = o.capacity
+	}
+
+	if o.maxCost > 0 && o.maxCost < n {
+		n = o.maxCost
+	}
+
+	if o.evictionPolicy != nil && n > 1 {
+		panic("ttlcache: an eviction policy instance cannot be shared between shards")
+	}
+
+	c := &ShardedCache[K, V]{
+		shards: make([]*Cache[K, V], n),
+		epoch:  atomic.AddUint64(&lastEpoch, 1),
+	}
+
+	for i := range c.shards {
+		shardOpts := append(opts[:len(opts):len(opts)],
+			WithCapacity[K, V](splitLimit(o.capacity, n, uint64(i))),
+			WithMaxCost[K, V](splitLimit(o.maxCost, n, uint64(i))),
+		)
+
+		c.shards[i] = New[K, V](shardOpts...)
+	}
+
+	return c
+}
+
+// splitLimit returns the part of the provided limit that belongs to
+// the i-th of n shards. The parts of all shards add up to the limit.
+func splitLimit(limit, n, i uint64) uint64 {
+	res := limit / n
+	if i < limit%n {
+		res++
+	}
+
+	return res
+}
+
+// shard returns the shard that the provided key belongs to.
+func (c *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+
+	return c.shards[hashKey(key)%uint64(len(c.shards))]
+}
+
+// Shards returns the number of shards.
+func (c *ShardedCache[K, V]) Shards() int {
+	return len(c.shards)
+}
+
+// Set creates a new item from the provided key and value, adds
+// it to the key's shard and then returns it. If an item associated
+// with the provided key already exists, the new item overwrites the
+// existing one.
+func (c *ShardedCache[K, V]) Set(key K, value V, ttl time.Duration) *Item[K, V] {
+	return c.shard(key).Set(key, value, ttl)
+}
+
+// SetWithCost creates a new item from the provided key, value and
+// cost, adds it to the key's shard and then returns it.
+// See Cache.SetWithCost for more details.
+func (c *ShardedCache[K, V]) SetWithCost(key K, value V, ttl time.Duration, cost uint64) *Item[K, V] {
+	return c.shard(key).SetWithCost(key, value, ttl, cost)
+}
+
+// Get retrieves an item from the cache by the provided key.
+// See Cache.Get for more details.
+// A loader receives the key's shard as its cache parameter.
+func (c *ShardedCache[K, V]) Get(key K, opts ...Option[K, V]) *Item[K, V] {
+	return c.shard(key).Get(key, opts...)
+}
+
+// GetE retrieves an item from the cache by the provided key, just like
+// Get does, but it also returns the error that occurred while loading
+// a missing item.
+// See Cache.GetE for more details.
+func (c *ShardedCache[K, V]) GetE(key K, opts ...Option[K, V]) (*Item[K, V], error) {
+	return c.shard(key).GetE(key, opts...)
+}
+
+// GetContext retrieves an item from the cache by the provided key,
+// just like GetE does, but it also passes the provided context to the
+// loader.
+// See Cache.GetContext for more details.
+func (c *ShardedCache[K, V]) GetContext(ctx context.Context, key K, opts ...Option[K, V]) (*Item[K, V], error) {
+	return c.shard(key).GetContext(ctx, key, opts...)
+}
+
+// GetMany retrieves multiple items from the cache by the provided keys
+// and returns the found ones mapped by their keys.
+// See Cache.GetMany for more details.
+// Missing items are loaded separately for each shard, so a batch
+// loader is called at least once for every shard that has missing
+// keys.
+func (c *ShardedCache[K, V]) GetMany(keys []K, opts ...Option[K, V]) map[K]*Item[K, V] {
+	items, _ := c.GetManyContext(context.Background(), keys, opts...)
+	return items
+}
+
+// GetManyContext retrieves multiple items from the cache by the
+// provided keys, just like GetMany does, but it also passes the
+// provided context to the loaders and returns the first error that
+// occurred while loading the missing items.
+// See Cache.GetManyContext for more details.
+func (c *ShardedCache[K, V]) GetManyContext(ctx context.Context, keys []K, opts ...Option[K, V]) (map[K]*Item[K, V], error) {
+	if len(c.shards) == 1 {
+		return c.shards[0].GetManyContext(ctx, keys, opts...)
+	}
+
+	shardKeys := make(map[*Cache[K, V]][]K)
+	for _, key := range keys {
+		s := c.shard(key)
+		shardKeys[s] = append(shardKeys[s], key)
+	}
+
+	var (
+		res = make(map[K]*Item[K, V], len(keys))
+		err error
+	)
+
+	for s, keys := range shardKeys {
+		items, shardErr := s.GetManyContext(ctx, keys, opts...)
+		for key, item := range items {
+			res[key] = item
+		}
+
+		if shardErr != nil && err == nil {
+			err = shardErr
+		}
+	}
+
+	return res, err
+}
+
+// Delete deletes an item from the cache. If the item associated with
+// the key is not found, the method is no-op.
+func (c *ShardedCache[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// Has checks whether the key exists in the cache.
+func (c *ShardedCache[K, V]) Has(key K) bool {
+	return c.shard(key).Has(key)
+}
+
+// GetOrSet returns the existing value for the key if present.
+// Otherwise, it sets and returns the given value. The retrieved
+// result is true if the value was retrieved, false if set.
+func (c *ShardedCache[K, V]) GetOrSet(key K, value V, opts ...Option[K, V]) (*Item[K, V], bool) {
+	return c.shard(key).GetOrSet(key, value, opts...)
+}
+
+// GetAndDelete deletes the value for a key, returning the previous
+// value if any. The retrieved result reports whether the key was present.
+func (c *ShardedCache[K, V]) GetAndDelete(key K, opts ...Option[K, V]) (*Item[K, V], bool) {
+	return c.shard(key).GetAndDelete(key, opts...)
+}
+
+// DeleteAll deletes all items from the cache.
+func (c *ShardedCache[K, V]) DeleteAll() {
+	for _, s := range c.shards {
+		s.DeleteAll()
+	}
+}
+
+// DeleteExpired deletes all expired items from the cache.
+func (c *ShardedCache[K, V]) DeleteExpired() {
+	for _, s := range c.shards {
+		s.DeleteExpired()
+	}
+}
+
+// Touch simulates an item's retrieval without actually returning it.
+// Its main purpose is to extend an item's expiration timestamp.
+// If the item is not found, the method is no-op.
+func (c *ShardedCache[K, V]) Touch(key K) {
+	c.shard(key).Touch(key)
+}
+
+// Len returns the number of items in the cache.
+func (c *ShardedCache[K, V]) Len() int {
+	var res int
+	for _, s := range c.shards {
+		res += s.Len()
+	}
+
+	return res
+}
+
+// Cost returns the total cost of all items in the cache.
+func (c *ShardedCache[K, V]) Cost() uint64 {
+	var res uint64
+	for _, s := range c.shards {
+		res += s.Cost()
+	}
+
+	return res
+}
+
+// Keys returns all keys currently present in the cache.
+func (c *ShardedCache[K, V]) Keys() []K {
+	var res []K
+	for _, s := range c.shards {
+		res = append(res, s.Keys()...)
+	}
+
+	return res
+}
+
+// Items returns a copy of all items in the cache.
+// It does not update any expiration timestamps.
+func (c *ShardedCache[K, V]) Items() map[K]*Item[K, V] {
+	res := make(map[K]*Item[K, V])
+	for _, s := range c.shards {
+		for k, item := range s.Items() {
+			res[k] = item
+		}
+	}
+
+	return res
+}
+
+// Save writes all items of all shards that are not expired to the
+// provided writer, so that they can be restored later with Load.
+// See Cache.Save for more details.
+func (c *ShardedCache[K, V]) Save(w io.Writer, opts ...Option[K, V]) error {
+	saveOpts := options[K, V]{
+		snapshotCodec: c.shards[0].options.snapshotCodec,
+		keyCodec:      c.shards[0].options.keyCodec,
+		valueCodec:    c.shards[0].options.valueCodec,
+	}
+
+	applyOptions(&saveOpts, opts...)
+
+	var items []SnapshotItem[K, V]
+	for _, s := range c.shards {
+		items = append(items, s.lockedSnapshot()...)
+	}
+
+	return encodeSnapshot(w, saveOpts.snapshotCodecOrDefault(), items)
+}
+
+// SaveSince writes the items of all shards that were changed, touched
+// or deleted since the provided token was returned to the provided
+// writer and returns the token of the written delta snapshot, which
+// should be passed into the next call. The tokens of the cache are
+// not interchangeable with the tokens of its shards.
+// See Cache.SaveSince for more details.
+func (c *ShardedCache[K, V]) SaveSince(w io.Writer, token SnapshotToken, opts ...Option[K, V]) (SnapshotToken, error) {
+	saveOpts := options[K, V]{
+		snapshotCodec: c.shards[0].options.snapshotCodec,
+		keyCodec:      c.shards[0].options.keyCodec,
+		valueCodec:    c.shards[0].options.valueCodec,
+	}
+
+	applyOptions(&saveOpts, opts...)
+
+	c.delta.mu.Lock()
+	defer c.delta.mu.Unlock()
+
+	full := token == SnapshotToken{}
+	if !full && (token.Epoch != c.epoch || token.Seq != c.delta.seq) {
+		return token, ErrDeltaUnavailable
+	}
+
+	deltas := make([]*deltaSnapshot[K, V], 0, len(c.shards))
+	abort := func() {
+		for i, d := range deltas {
+			c.shards[i].abortDelta(d)
+		}
+	}
+
+	var items []SnapshotItem[K, V]
+	for _, s := range c.shards {
+		d, ok := s.delta(full)
+		if !ok {
+			abort()
+			return token, ErrDeltaUnavailable
+		}
+
+		deltas = append(deltas, d)
+		items = append(items, d.items...)
+	}
+
+	if err := encodeSnapshot(w, saveOpts.snapshotCodecOrDefault(), items); err != nil {
+		abort()
+		return token, err
+	}
+
+	for i, d := range deltas {
+		c.shards[i].commitDelta(d)
+	}
+
+	c.delta.seq++
+
+	return SnapshotToken{Epoch: c.epoch, Seq: c.delta.seq}, nil
+}
+
+// Load reads the items that were written by Save from the provided
+// reader and adds each of them to its key's shard. The number of
+// shards does not have to match the one of the saved cache.
+// See Cache.Load for more details.
+func (c *ShardedCache[K, V]) Load(r io.Reader, opts ...Option[K, V]) error {
+	return c.LoadChain([]io.Reader{r}, opts...)
+}
+
+// LoadChain reads the items that were written by Save or SaveSince
+// from the provided readers and adds each of them to its key's shard,
+// just like Load does.
+// See Cache.LoadChain for more details.
+func (c *ShardedCache[K, V]) LoadChain(rs []io.Reader, opts ...Option[K, V]) error {
+	loadOpts := options[K, V]{
+		snapshotCodec:     c.shards[0].options.snapshotCodec,
+		keyCodec:          c.shards[0].options.keyCodec,
+		valueCodec:        c.shards[0].options.valueCodec,
+		disableLoadEvents: c.shards[0].options.disableLoadEvents,
+	}
+
+	applyOptions(&loadOpts, opts...)
+
+	state := newSnapshotState[K, V]()
+	for _, r := range rs {
+		if err := decodeSnapshot(r, loadOpts.snapshotCodecOrDefault(), state); err != nil {
+			return err
+		}
+	}
+
+	shardDeleted := make(map[*Cache[K, V]][]K)
+	for _, key := range state.deletedKeys() {
+		s := c.shard(key)
+		shardDeleted[s] = append(shardDeleted[s], key)
+	}
+
+	shardItems := make(map[*Cache[K, V]][]SnapshotItem[K, V])
+	for _, item := range state.items() {
+		s := c.shard(item.Key)
+		shardItems[s] = append(shardItems[s], item)
+	}
+
+	for _, s := range c.shards {
+		if len(shardDeleted[s]) > 0 || len(shardItems[s]) > 0 {
+			s.restore(shardDeleted[s], shardItems[s], !loadOpts.disableLoadEvents)
+		}
+	}
+
+	return nil
+}
+
+// Metrics returns the metrics of all shards added together.
+func (c *ShardedCache[K, V]) Metrics() Metrics {
+	var res Metrics
+	for _, s := range c.shards {
+		m := s.Metrics()
+		res.Insertions += m.Insertions
+		res.Hits += m.Hits
+		res.StaleHits += m.StaleHits
+		res.Misses += m.Misses
+		res.Evictions += m.Evictions
+		res.Rejections += m.Rejections
+		res.CostRejections += m.CostRejections
+		res.LoadErrors += m.LoadErrors
+		res.Refreshes += m.Refreshes
+		res.RefreshErrors += m.RefreshErrors
+		res.NegativeInsertions += m.NegativeInsertions
+		res.NegativeHits += m.NegativeHits
+		res.EstimatedBytes += m.EstimatedBytes
+	}
+
+	return res
+}
+
+// Start starts the automatic cleanup processes of all shards.
+// It blocks until Stop is called.
+func (c *ShardedCache[K, V]) Start() {
+	var wg sync.WaitGroup
+
+	wg.Add(len(c.shards))
+	for _, s := range c.shards {
+		go func(s *Cache[K, V]) {
+			s.Start()
+			wg.Done()
+		}(s)
+	}
+
+	wg.Wait()
+}
+
+// Stop stops the automatic cleanup processes of all shards.
+// It blocks until all of them exit.
+func (c *ShardedCache[K, V]) Stop() {
+	for _, s := range c.shards {
+		s.Stop()
+	}
+}
+
+// OnInsertion adds the provided function to be executed when
+// a new item is inserted into any of the shards.
+// See Cache.OnInsertion for more details.
+func (c *ShardedCache[K, V]) OnInsertion(fn func(context.Context, *Item[K, V])) func() {
+	dels := make([]func(), len(c.shards))
+	for i, s := range c.shards {
+		dels[i] = s.OnInsertion(fn)
+	}
+
+	return func() {
+		for _, del := range dels {
+			del()
+		}
+	}
+}
+
+// OnEviction adds the provided function to be executed when
+// an item is evicted/deleted from any of the shards.
+// See Cache.OnEviction for more details.
+func (c *ShardedCache[K, V]) OnEviction(fn func(context.Context, EvictionReason, *Item[K, V])) func() {
+	dels := make([]func(), len(c.shards))
+	for i, s := range c.shards {
+		dels[i] = s.OnEviction(fn)
+	}
+
+	return func() {
+		for _, del := range dels {
+			del()
+		}
+	}
+}
+
+// OnRefreshFailure adds the provided function to be executed when
+// a background reload of an item in any of the shards fails.
+// See Cache.OnRefreshFailure for more details.
+func (c *ShardedCache[K, V]) OnRefreshFailure(fn func(context.Context, K, error)) func() {
+	dels := make([]func(), len(c.shards))
+	for i, s := range c.shards {
+		dels[i] = s.OnRefreshFailure(fn)
+	}
+
+	return func() {
+		for _, del := range dels {
+			del()
+		}
+	}
+}
+
+// Range iterates over all items of each shard and calls fn function.
+// It calls fn function until it returns false.
+// Shards are visited one after another; items of a single shard are
+// visited in the order defined by its eviction policy.
+func (c *ShardedCache[K, V]) Range(fn func(item *Item[K, V]) bool) {
+	ok := true
+	for _, s := range c.shards {
+		s.Range(func(item *Item[K, V]) bool {
+			ok = fn(item)
+			return ok
+		})
+
+		if !ok {
+			return
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package ttlcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewSharded(t *testing.T) {
+	c := NewSharded[string, string](4,
+		WithTTL[string, string](time.Hour),
+		WithCapacity[string, string](10),
+		WithMaxCost[string, string](20),
+	)
+	require.NotNil(t, c)
+	require.Len(t, c.shards, 4)
+
+	var capacity, maxCost uint64
+	for _, s := range c.shards {
+		assert.Equal(t, time.Hour, s.options.ttl)
+		capacity += s.options.capacity
+		maxCost += s.options.maxCost
+	}
+
+	assert.Equal(t, uint64(10), capacity)
+	assert.Equal(t, uint64(20), maxCost)
+
+	// shard count is limited by capacity
+	c = NewSharded[string, string](8, WithCapacity[string, string](3))
+	assert.Len(t, c.shards, 3)
+
+	// shard count is limited by max cost
+	c = NewSharded[string, string](8, WithMaxCost[string, string](2))
+	assert.Len(t, c.shards, 2)
+
+	// invalid shard count
+	c = NewSharded[string, string](0)
+	assert.Len(t, c.shards, 1)
+
+	// per-shard policies
+	c = NewSharded[string, string](2,
+		WithCapacity[string, string](5),
+		WithEvictionPolicyFunc[string, string](func(capacity uint64) EvictionPolicy[string, string] {
+			return NewARCPolicy[string, string](capacity)
+		}),
+	)
+	require.Len(t, c.shards, 2)
+	assert.NotSame(t, c.shards[0].items.policy, c.shards[1].items.policy)
+	assert.Equal(t, 3, c.shards[0].items.policy.(*ARCPolicy[string, string]).capacity)
+	assert.Equal(t, 2, c.shards[1].items.policy.(*ARCPolicy[string, string]).capacity)
+
+	// shared policy instance
+	assert.Panics(t, func() {
+		NewSharded[string, string](2, WithEvictionPolicy[string, string](NewLRUPolicy[string, string]()))
+	})
+
+	assert.NotPanics(t, func() {
+		NewSharded[string, string](1, WithEvictionPolicy[string, string](NewLRUPolicy[string, string]()))
+	})
+}
+
+func Test_splitLimit(t *testing.T) {
+	assert.Equal(t, uint64(3), splitLimit(10, 4, 0))
+	assert.Equal(t, uint64(3), splitLimit(10, 4, 1))
+	assert.Equal(t, uint64(2), splitLimit(10, 4, 2))
+	assert.Equal(t, uint64(2), splitLimit(10, 4, 3))
+	assert.Equal(t, uint64(0), splitLimit(0, 4, 0))
+}
+
+func Test_ShardedCache_shard(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		assert.Same(t, c.shard(key), c.shard(key))
+	}
+
+	used := make(map[*Cache[string, string]]struct{})
+	for i := 0; i < 100; i++ {
+		used[c.shard(fmt.Sprint(i))] = struct{}{}
+	}
+
+	assert.Len(t, used, 4)
+
+	// pointer keys stay in their shards when their values change
+	type key struct {
+		id int
+	}
+
+	pc := NewSharded[*key, string](16)
+	keys := make([]*key, 100)
+	for i := range keys {
+		keys[i] = &key{id: i}
+		pc.Set(keys[i], "value", NoTTL)
+	}
+
+	for _, k := range keys {
+		k.id++
+		assert.NotNil(t, pc.Get(k))
+	}
+
+	assert.Equal(t, 100, pc.Len())
+}
+
+func Test_ShardedCache_Shards(t *testing.T) {
+	assert.Equal(t, 3, NewSharded[string, string](3).Shards())
+}
+
+func Test_ShardedCache_SetGetDelete(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		item := c.Set(key, "value"+key, time.Hour)
+		require.NotNil(t, item)
+		assert.Same(t, item, c.shard(key).items.values[key])
+	}
+
+	item := c.Get("5")
+	require.NotNil(t, item)
+	assert.Equal(t, "value5", item.Value())
+	assert.True(t, c.Has("5"))
+
+	c.Delete("5")
+	assert.Nil(t, c.Get("5"))
+	assert.False(t, c.Has("5"))
+
+	item = c.SetWithCost("cost", "value", NoTTL, 3)
+	require.NotNil(t, item)
+	assert.Equal(t, uint64(3), item.Cost())
+	assert.Equal(t, uint64(22), c.Cost())
+
+	item, retrieved := c.GetOrSet("6", "other")
+	assert.True(t, retrieved)
+	assert.Equal(t, "value6", item.Value())
+
+	item, retrieved = c.GetOrSet("new", "other")
+	assert.False(t, retrieved)
+	assert.Equal(t, "other", item.Value())
+
+	item, present := c.GetAndDelete("new")
+	assert.True(t, present)
+	assert.Equal(t, "other", item.Value())
+	assert.False(t, c.Has("new"))
+}
+
+func Test_ShardedCache_GetE(t *testing.T) {
+	errLoad := errors.New("error")
+	c := NewSharded[string, string](4,
+		WithLoader[string, string](LoaderWithErrorFunc[string, string](func(_ *Cache[string, string], _ string) (*Item[string, string], error) {
+			return nil, errLoad
+		})),
+	)
+
+	c.Set("test", "value", NoTTL)
+
+	item, err := c.GetE("test")
+	assert.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "value", item.Value())
+
+	item, err = c.GetE("missing")
+	assert.Equal(t, errLoad, err)
+	assert.Nil(t, item)
+	assert.Equal(t, uint64(1), c.Metrics().LoadErrors)
+}
+
+func Test_ShardedCache_GetContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := NewSharded[string, string](4,
+		WithLoader[string, string](ContextLoaderFunc[string, string](func(ctx context.Context, _ *Cache[string, string], _ string) (*Item[string, string], error) {
+			return nil, ctx.Err()
+		})),
+	)
+
+	item, err := c.GetContext(ctx, "missing")
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, item)
+}
+
+func Test_ShardedCache_GetMany(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		loaded []string
+	)
+
+	c := NewSharded[string, string](4,
+		WithBatchLoader[string, string](BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], keys []string) (map[string]string, error) {
+			mu.Lock()
+			loaded = append(loaded, keys...)
+			mu.Unlock()
+
+			res := make(map[string]string)
+			for _, key := range keys {
+				res[key] = "loaded"
+			}
+
+			return res, nil
+		})),
+	)
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+
+	c.Set("0", "value", NoTTL)
+
+	items := c.GetMany(keys)
+	assert.Len(t, items, 20)
+	assert.Equal(t, "value", items["0"].Value())
+	assert.Equal(t, "loaded", items["1"].Value())
+	assert.Len(t, loaded, 19)
+	assert.Equal(t, 20, c.Len())
+
+	// errors
+	errLoad := errors.New("error")
+
+	items, err := c.GetManyContext(context.Background(), []string{"20", "21"}, WithBatchLoader[string, string](BatchLoaderFunc[string, string](func(_ context.Context, _ *Cache[string, string], _ []string) (map[string]string, error) {
+		return nil, errLoad
+	})))
+	assert.Equal(t, errLoad, err)
+	assert.Empty(t, items)
+}
+
+func Test_ShardedCache_Save(t *testing.T) {
+	var buf bytes.Buffer
+
+	src := NewSharded[string, string](4)
+	for i := 0; i < 20; i++ {
+		src.Set(fmt.Sprint(i), fmt.Sprint("value", i), NoTTL)
+	}
+
+	require.NoError(t, src.Save(&buf))
+
+	// the number of shards may differ
+	var (
+		mu         sync.Mutex
+		insertions int
+	)
+
+	dst := NewSharded[string, string](3)
+	del := dst.OnInsertion(func(_ context.Context, _ *Item[string, string]) {
+		mu.Lock()
+		insertions++
+		mu.Unlock()
+	})
+
+	require.NoError(t, dst.Load(bytes.NewReader(buf.Bytes()), WithDisableLoadEvents[string, string]()))
+	del()
+	assert.Equal(t, 20, dst.Len())
+	assert.Zero(t, insertions)
+
+	for i := 0; i < 20; i++ {
+		item := dst.Get(fmt.Sprint(i))
+		require.NotNil(t, item)
+		assert.Equal(t, fmt.Sprint("value", i), item.Value())
+		assert.Same(t, dst.shard(item.Key()).items.values[item.Key()], item)
+	}
+
+	// invalid snapshot
+	dst = NewSharded[string, string](3)
+	assert.Error(t, dst.Load(bytes.NewReader(buf.Bytes()[:buf.Len()-1])))
+	assert.Zero(t, dst.Len())
+}
+
+func Test_ShardedCache_SaveSince(t *testing.T) {
+	var base, delta bytes.Buffer
+
+	c := NewSharded[string, string](3, WithVersion[string, string](true))
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprint(i), fmt.Sprint("value", i), NoTTL)
+	}
+
+	token0, err := c.SaveSince(&base, SnapshotToken{})
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotToken{Epoch: c.epoch, Seq: 1}, token0)
+
+	// only the changes are written
+	c.Delete("0")
+	c.Set("1", "value1b", NoTTL)
+	c.Set("10", "value10", NoTTL)
+
+	codec := &recordingSnapshotCodec{}
+
+	token1, err := c.SaveSince(&delta, token0)
+	require.NoError(t, err)
+
+	_, err = c.SaveSince(io.Discard, token1, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	assert.Empty(t, codec.items)
+
+	dst := New[string, string]()
+	require.NoError(t, dst.LoadChain([]io.Reader{&base, &delta}))
+	assert.Equal(t, 10, dst.Len())
+	assert.False(t, dst.Has("0"))
+	assert.Equal(t, "value1b", dst.Get("1").Value())
+	assert.True(t, dst.Has("10"))
+
+	// tokens older than the one of the latest call are unavailable
+	token, err := c.SaveSince(io.Discard, token1)
+	assert.Equal(t, ErrDeltaUnavailable, err)
+	assert.Equal(t, token1, token)
+
+	// tokens of the shards are not interchangeable
+	_, err = c.SaveSince(io.Discard, SnapshotToken{Epoch: c.shards[0].epoch, Seq: 2})
+	assert.Equal(t, ErrDeltaUnavailable, err)
+
+	// failed writes keep the token valid
+	errWrite := errors.New("error")
+	c.Delete("1")
+
+	token, err = c.SaveSince(failingWriter{err: errWrite}, SnapshotToken{Epoch: c.epoch, Seq: 3})
+	assert.Equal(t, errWrite, err)
+
+	codec = &recordingSnapshotCodec{}
+
+	_, err = c.SaveSince(io.Discard, token, WithSnapshotCodec[string, string](codec))
+	require.NoError(t, err)
+	assert.Equal(t, []SnapshotItem[string, string]{{Key: "1", Deleted: true}}, codec.items)
+
+	// DeleteAll makes deltas unavailable
+	c.DeleteAll()
+
+	_, err = c.SaveSince(io.Discard, SnapshotToken{Epoch: c.epoch, Seq: 4})
+	assert.Equal(t, ErrDeltaUnavailable, err)
+}
+
+func Test_ShardedCache_LoadChain(t *testing.T) {
+	var base, delta bytes.Buffer
+
+	src := New[string, string](WithVersion[string, string](true))
+	for i := 0; i < 20; i++ {
+		src.Set(fmt.Sprint(i), fmt.Sprint("value", i), NoTTL)
+	}
+
+	token, err := src.SaveSince(&base, SnapshotToken{})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		src.Delete(fmt.Sprint(i))
+	}
+
+	_, err = src.SaveSince(&delta, token)
+	require.NoError(t, err)
+
+	dst := NewSharded[string, string](3)
+	dst.Set("0", "value0", NoTTL)
+	dst.Set("20", "value20", NoTTL)
+
+	require.NoError(t, dst.LoadChain([]io.Reader{&base, &delta}))
+	assert.Equal(t, 11, dst.Len())
+	assert.False(t, dst.Has("0"))
+	assert.True(t, dst.Has("10"))
+	assert.True(t, dst.Has("20"))
+}
+
+func Test_ShardedCache_Capacity(t *testing.T) {
+	c := NewSharded[string, string](4, WithCapacity[string, string](8))
+
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprint(i), "value", NoTTL)
+	}
+
+	assert.LessOrEqual(t, c.Len(), 8)
+
+	for _, s := range c.shards {
+		assert.Equal(t, 2, s.Len())
+	}
+
+	m := c.Metrics()
+	assert.Equal(t, uint64(100), m.Insertions)
+	assert.Equal(t, uint64(92), m.Evictions)
+}
+
+func Test_ShardedCache_DeleteAll(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprint(i), "value", NoTTL)
+	}
+
+	c.DeleteAll()
+	assert.Equal(t, 0, c.Len())
+}
+
+func Test_ShardedCache_DeleteExpired(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprint(i), "value", time.Nanosecond)
+	}
+
+	c.Set("keep", "value", time.Hour)
+	time.Sleep(time.Millisecond)
+
+	c.DeleteExpired()
+	assert.Equal(t, []string{"keep"}, c.Keys())
+}
+
+func Test_ShardedCache_Touch(t *testing.T) {
+	c := NewSharded[string, string](4)
+	item := c.Set("test", "value", time.Hour)
+	oldExp := item.ExpiresAt()
+
+	time.Sleep(time.Millisecond)
+
+	c.Touch("test")
+	assert.True(t, item.ExpiresAt().After(oldExp))
+}
+
+func Test_ShardedCache_KeysItems(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		keys = append(keys, key)
+		c.Set(key, "value", NoTTL)
+	}
+
+	assert.Equal(t, 20, c.Len())
+	assert.ElementsMatch(t, keys, c.Keys())
+
+	items := c.Items()
+	require.Len(t, items, 20)
+
+	for _, key := range keys {
+		require.NotNil(t, items[key])
+		assert.Equal(t, key, items[key].Key())
+	}
+}
+
+func Test_ShardedCache_Metrics(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprint(i), "value", NoTTL)
+	}
+
+	for i := 0; i < 15; i++ {
+		c.Get(fmt.Sprint(i))
+	}
+
+	c.Delete("1")
+
+	assert.Equal(t, Metrics{
+		Insertions: 10,
+		Hits:       10,
+		Misses:     5,
+		Evictions:  1,
+	}, c.Metrics())
+
+	c = NewSharded[string, string](4, WithMaxMemory[string, string](0))
+	c.Set("1", "value", NoTTL)
+	c.Set("2", "value", NoTTL)
+	assert.Equal(t, 2*memoryCost("1", "value"), c.Metrics().EstimatedBytes)
+}
+
+func Test_ShardedCache_StartStop(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprint(i), "value", time.Millisecond*10)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Start()
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, time.Second, time.Millisecond*5)
+
+	c.Stop()
+	<-done
+}
+
+func Test_ShardedCache_OnInsertionOnEviction(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		inserted  []string
+		evicted   []string
+		cache     = NewSharded[string, string](4)
+		insertion = cache.OnInsertion(func(_ context.Context, item *Item[string, string]) {
+			mu.Lock()
+			inserted = append(inserted, item.Key())
+			mu.Unlock()
+		})
+		eviction = cache.OnEviction(func(_ context.Context, r EvictionReason, item *Item[string, string]) {
+			assert.Equal(t, EvictionReasonDeleted, r)
+
+			mu.Lock()
+			evicted = append(evicted, item.Key())
+			mu.Unlock()
+		})
+	)
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		keys = append(keys, key)
+		cache.Set(key, "value", NoTTL)
+	}
+
+	cache.DeleteAll()
+
+	insertion()
+	eviction()
+
+	assert.ElementsMatch(t, keys, inserted)
+	assert.ElementsMatch(t, keys, evicted)
+
+	// subscriptions are removed from all shards
+	cache.Set("new", "value", NoTTL)
+	cache.Delete("new")
+
+	assert.Len(t, inserted, 20)
+	assert.Len(t, evicted, 20)
+}
+
+func Test_ShardedCache_Range(t *testing.T) {
+	c := NewSharded[string, string](4)
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		keys = append(keys, key)
+		c.Set(key, "value", NoTTL)
+	}
+
+	var visited []string
+	c.Range(func(item *Item[string, string]) bool {
+		visited = append(visited, item.Key())
+		return true
+	})
+	assert.ElementsMatch(t, keys, visited)
+
+	visited = nil
+	c.Range(func(item *Item[string, string]) bool {
+		visited = append(visited, item.Key())
+		return len(visited) < 3
+	})
+	assert.Len(t, visited, 3)
+}
@@ -0,0 +1,96 @@
+package ttlcache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// SIEVEPolicy is a SIEVE eviction policy.
+// Items are kept in a single FIFO queue and an access only marks the
+// item as visited, without moving it. When an item needs to be
+// evicted, a hand moves from the oldest item towards the newest one,
+// clearing the visited marks, and stops at the first unvisited item.
+type SIEVEPolicy[K comparable, V any] struct {
+	queue *list.List
+	elems map[K]*list.Element
+	hand  *list.Element
+}
+
+// sieveNode holds a single item and its visited mark.
+type sieveNode[K comparable, V any] struct {
+	item    *Item[K, V]
+	visited uint32
+}
+
+// NewSIEVEPolicy creates a new instance of SIEVE eviction policy.
+func NewSIEVEPolicy[K comparable, V any]() *SIEVEPolicy[K, V] {
+	return &SIEVEPolicy[K, V]{
+		queue: list.New(),
+		elems: make(map[K]*list.Element),
+	}
+}
+
+// OnInsert places the item at the head of the queue.
+func (p *SIEVEPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	p.elems[item.key] = p.queue.PushFront(&sieveNode[K, V]{item: item})
+}
+
+// OnAccess marks the item as visited.
+func (p *SIEVEPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	p.OnConcurrentAccess(item)
+}
+
+// OnConcurrentAccess marks the item as visited.
+func (p *SIEVEPolicy[K, V]) OnConcurrentAccess(item *Item[K, V]) {
+	if elem := p.elems[item.key]; elem != nil {
+		atomic.StoreUint32(&elem.Value.(*sieveNode[K, V]).visited, 1)
+	}
+}
+
+// OnUpdate marks the item as visited.
+func (p *SIEVEPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	p.OnConcurrentAccess(item)
+}
+
+// OnRemove removes the item from the queue.
+func (p *SIEVEPolicy[K, V]) OnRemove(item *Item[K, V], _ EvictionReason) {
+	elem := p.elems[item.key]
+	if elem == nil {
+		return
+	}
+
+	if p.hand == elem {
+		p.hand = elem.Prev()
+	}
+
+	p.queue.Remove(elem)
+	delete(p.elems, item.key)
+}
+
+// Victim moves the hand towards the head of the queue until an
+// unvisited item is found and returns it.
+func (p *SIEVEPolicy[K, V]) Victim() *Item[K, V] {
+	if p.queue.Len() == 0 {
+		return nil
+	}
+
+	elem := p.hand
+	if elem == nil {
+		elem = p.queue.Back()
+	}
+
+	for {
+		node := elem.Value.(*sieveNode[K, V])
+		if atomic.LoadUint32(&node.visited) == 0 {
+			p.hand = elem
+			return node.item
+		}
+
+		atomic.StoreUint32(&node.visited, 0)
+
+		elem = elem.Prev()
+		if elem == nil {
+			elem = p.queue.Back()
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewSIEVEPolicy(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	require.NotNil(t, p)
+	assert.NotNil(t, p.queue)
+	assert.NotNil(t, p.elems)
+	assert.Nil(t, p.hand)
+}
+
+func Test_SIEVEPolicy_OnInsert(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	p.OnInsert(&Item[string, string]{key: "1"})
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	assert.Len(t, p.elems, 2)
+	assert.Equal(t, "2", p.queue.Front().Value.(*sieveNode[string, string]).item.key)
+}
+
+func Test_SIEVEPolicy_OnAccess(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	p.OnAccess(item)
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*sieveNode[string, string]).visited)
+	assert.Zero(t, p.elems["2"].Value.(*sieveNode[string, string]).visited)
+
+	// items are not moved
+	assert.Equal(t, "1", p.queue.Back().Value.(*sieveNode[string, string]).item.key)
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "3"})
+	assert.Len(t, p.elems, 2)
+}
+
+func Test_SIEVEPolicy_OnConcurrentAccess(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			p.OnConcurrentAccess(item)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*sieveNode[string, string]).visited)
+}
+
+func Test_SIEVEPolicy_OnUpdate(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+
+	p.OnUpdate(item)
+	assert.Equal(t, uint32(1), p.elems["1"].Value.(*sieveNode[string, string]).visited)
+}
+
+func Test_SIEVEPolicy_OnRemove(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.hand = p.elems["1"]
+
+	// hand is moved towards the head
+	p.OnRemove(item1, EvictionReasonCapacityReached)
+	assert.Same(t, p.elems["2"], p.hand)
+	assert.Equal(t, 1, p.queue.Len())
+	assert.NotContains(t, p.elems, "1")
+
+	// unknown items are ignored
+	p.OnRemove(item1, EvictionReasonDeleted)
+	assert.Equal(t, 1, p.queue.Len())
+}
+
+func Test_SIEVEPolicy_Victim(t *testing.T) {
+	p := NewSIEVEPolicy[string, string]()
+	assert.Nil(t, p.Victim())
+
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnInsert(item3)
+
+	// oldest unvisited item
+	assert.Same(t, item1, p.Victim())
+
+	// visited items are skipped and their marks are cleared
+	p.OnAccess(item1)
+	assert.Same(t, item2, p.Victim())
+	assert.Zero(t, p.elems["1"].Value.(*sieveNode[string, string]).visited)
+
+	p.OnRemove(item2, EvictionReasonCapacityReached)
+
+	// hand wraps around
+	p.OnAccess(item3)
+	assert.Same(t, item1, p.Victim())
+	assert.Zero(t, p.elems["3"].Value.(*sieveNode[string, string]).visited)
+}
+
+func Test_Cache_SIEVEPolicy(t *testing.T) {
+	var wg sync.WaitGroup
+
+	cache := New[string, string](
+		WithCapacity[string, string](2),
+		WithEvictionPolicy[string, string](NewSIEVEPolicy[string, string]()),
+	)
+	del := cache.OnEviction(func(_ context.Context, r EvictionReason, item *Item[string, string]) {
+		assert.Equal(t, EvictionReasonCapacityReached, r)
+		assert.Equal(t, "2", item.Key())
+		wg.Done()
+	})
+	defer del()
+
+	wg.Add(1)
+
+	cache.Set("1", "1", NoTTL)
+	cache.Set("2", "2", NoTTL)
+	cache.Get("1")
+	cache.Set("3", "3", NoTTL)
+
+	wg.Wait()
+	assert.ElementsMatch(t, []string{"1", "3"}, cache.Keys())
+}
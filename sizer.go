@@ -0,0 +1,267 @@
+package ttlcache
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Sizer is an interface that may be implemented by cache values
+// (and keys) that are able to report their own in-memory size.
+type Sizer interface {
+	// Size should return the total number of bytes that are
+	// occupied by the value, including any indirectly referenced
+	// memory.
+	Size() uint64
+}
+
+// mapEntryOverhead is the approximate number of bytes that a Go map
+// uses to store a single entry in addition to its key and value.
+const mapEntryOverhead = 8
+
+// sizerType is the type of the Sizer interface.
+var sizerType = reflect.TypeOf((*Sizer)(nil)).Elem()
+
+// typeLayouts caches the layouts of all types whose sizes were
+// estimated.
+var typeLayouts sync.Map // reflect.Type -> *typeLayout
+
+// typeLayout holds the precomputed information about a single type
+// that is needed to estimate the sizes of its values.
+type typeLayout struct {
+	// size is the number of bytes that a value of the type occupies
+	// directly.
+	size uint64
+
+	// flat specifies whether values of the type never reference any
+	// additional memory.
+	flat bool
+
+	// sizer specifies whether the type or a pointer to it implements
+	// the Sizer interface (see implementsSizer).
+	sizer bool
+
+	// fields contains the indexes of struct fields that are not
+	// flat.
+	fields []int
+}
+
+// layoutOf returns the cached layout of the provided type.
+func layoutOf(t reflect.Type) *typeLayout {
+	if l, ok := typeLayouts.Load(t); ok {
+		return l.(*typeLayout)
+	}
+
+	l := &typeLayout{
+		size:  uint64(t.Size()),
+		flat:  isFlat(t),
+		sizer: implementsSizer(t),
+	}
+
+	if t.Kind() == reflect.Struct && !l.flat {
+		for i := 0; i < t.NumField(); i++ {
+			if !isFlat(t.Field(i).Type) {
+				l.fields = append(l.fields, i)
+			}
+		}
+	}
+
+	res, _ := typeLayouts.LoadOrStore(t, l)
+
+	return res.(*typeLayout)
+}
+
+// implementsSizer checks whether the provided type or a pointer to it
+// implements the Sizer interface. Pointers and interfaces are never
+// reported as such, since the values that they reference are checked
+// instead.
+func implementsSizer(t reflect.Type) bool {
+	if k := t.Kind(); k == reflect.Ptr || k == reflect.Interface {
+		return false
+	}
+
+	return t.Implements(sizerType) || reflect.PtrTo(t).Implements(sizerType)
+}
+
+// isFlat checks whether values of the provided type never reference
+// any additional memory that should be taken into account. Values
+// that report their own sizes are never flat.
+func isFlat(t reflect.Type) bool {
+	if implementsSizer(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return false
+	case reflect.Array:
+		return t.Len() == 0 || isFlat(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !isFlat(t.Field(i).Type) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return true
+	}
+}
+
+// EstimateSize returns the estimated number of bytes that the
+// provided value occupies in memory, including strings, slices,
+// maps and pointed-to values that it references.
+// If the value, or any value that it references through exported
+// struct fields, slice, array and map elements, pointers or
+// interfaces, implements the Sizer interface, its own reported size
+// is used instead.
+// Channels, functions and unsafe pointers are counted only by their
+// direct size.
+func EstimateSize(v interface{}) uint64 {
+	if v == nil {
+		return 0
+	}
+
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+
+	rv := reflect.ValueOf(v)
+	var s sizer
+
+	return layoutOf(rv.Type()).size + s.indirect(rv)
+}
+
+// memoryCost is a cost function that estimates the number of bytes
+// that a single cache item occupies in memory.
+func memoryCost[K comparable, V any](key K, value V) uint64 {
+	var item Item[K, V]
+
+	overhead := uint64(unsafe.Sizeof(item) - unsafe.Sizeof(item.key) - unsafe.Sizeof(item.value))
+
+	return overhead + EstimateSize(key) + EstimateSize(value)
+}
+
+// sizer estimates the indirectly referenced memory of values.
+// Each pointed-to value is counted only once.
+type sizer struct {
+	seen map[uintptr]struct{}
+}
+
+// indirect returns the number of bytes that are referenced by the
+// provided value, excluding the value's own direct size.
+func (s *sizer) indirect(v reflect.Value) uint64 {
+	l := layoutOf(v.Type())
+	if l.flat {
+		return 0
+	}
+
+	if l.sizer {
+		if res, ok := reportedSize(v, l); ok {
+			return res
+		}
+	}
+
+	var res uint64
+
+	switch v.Kind() {
+	case reflect.String:
+		res = uint64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+
+		el := layoutOf(v.Type().Elem())
+		res = uint64(v.Cap()) * el.size
+
+		if !el.flat {
+			for i := 0; i < v.Len(); i++ {
+				res += s.indirect(v.Index(i))
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return 0
+		}
+
+		kl, el := layoutOf(v.Type().Key()), layoutOf(v.Type().Elem())
+		res = uint64(v.Len()) * (kl.size + el.size + mapEntryOverhead)
+
+		if !kl.flat || !el.flat {
+			iter := v.MapRange()
+			for iter.Next() {
+				res += s.indirect(iter.Key()) + s.indirect(iter.Value())
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() || !s.visit(v.Pointer()) {
+			return 0
+		}
+
+		res = layoutOf(v.Type().Elem()).size + s.indirect(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		e := v.Elem()
+		res = layoutOf(e.Type()).size + s.indirect(e)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			res += s.indirect(v.Index(i))
+		}
+	case reflect.Struct:
+		for _, i := range l.fields {
+			res += s.indirect(v.Field(i))
+		}
+	}
+
+	return res
+}
+
+// reportedSize returns the number of bytes that the provided value
+// reports with its Size method, excluding the value's own direct
+// size. It returns false if the value is nil or cannot be accessed
+// (e.g. because it is stored in an unexported struct field).
+func reportedSize(v reflect.Value, l *typeLayout) (uint64, bool) {
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return 0, false
+	}
+
+	if !v.CanInterface() {
+		return 0, false
+	}
+
+	sz, ok := v.Interface().(Sizer)
+	if !ok && v.CanAddr() {
+		sz, ok = v.Addr().Interface().(Sizer)
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	if size := sz.Size(); size > l.size {
+		return size - l.size, true
+	}
+
+	return 0, true
+}
+
+// visit marks the provided pointer as seen. It returns false if the
+// pointer was already seen before.
+func (s *sizer) visit(p uintptr) bool {
+	if s.seen == nil {
+		s.seen = make(map[uintptr]struct{})
+	}
+
+	if _, ok := s.seen[p]; ok {
+		return false
+	}
+
+	s.seen[p] = struct{}{}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package ttlcache
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_layoutOf(t *testing.T) {
+	type value struct {
+		A int
+		B string
+		C [2]int
+		D []byte
+	}
+
+	l := layoutOf(reflect.TypeOf(value{}))
+	require.NotNil(t, l)
+	assert.Equal(t, uint64(unsafe.Sizeof(value{})), l.size)
+	assert.False(t, l.flat)
+	assert.Equal(t, []int{1, 3}, l.fields)
+
+	// cached layout is reused
+	assert.Same(t, l, layoutOf(reflect.TypeOf(value{})))
+}
+
+func Test_isFlat(t *testing.T) {
+	type flat struct {
+		A int
+		B [2]float64
+		C func()
+	}
+
+	type nested struct {
+		A flat
+		B [1]*int
+	}
+
+	assert.True(t, isFlat(reflect.TypeOf(1)))
+	assert.True(t, isFlat(reflect.TypeOf(flat{})))
+	assert.True(t, isFlat(reflect.TypeOf([0]string{})))
+	assert.False(t, isFlat(reflect.TypeOf("")))
+	assert.False(t, isFlat(reflect.TypeOf([]int{})))
+	assert.False(t, isFlat(reflect.TypeOf(map[int]int{})))
+	assert.False(t, isFlat(reflect.TypeOf(&flat{})))
+	assert.False(t, isFlat(reflect.TypeOf(nested{})))
+	assert.False(t, isFlat(reflect.TypeOf([]interface{}{}).Elem()))
+	assert.False(t, isFlat(reflect.TypeOf(testSizer(0))))
+	assert.False(t, isFlat(reflect.TypeOf([1]ptrSizer{})))
+}
+
+func Test_EstimateSize(t *testing.T) {
+	type node struct {
+		Next  *node
+		Value string
+	}
+
+	looped := &node{Value: "abc"}
+	looped.Next = looped
+
+	str := "hello"
+	sizer := testSizer(40)
+	ptrSize := uint64(unsafe.Sizeof(uintptr(0)))
+	strSize := uint64(unsafe.Sizeof(""))
+	sliceSize := uint64(unsafe.Sizeof([]byte{}))
+
+	cc := map[string]struct {
+		Value  interface{}
+		Result uint64
+	}{
+		"Nil value": {
+			Value:  nil,
+			Result: 0,
+		},
+		"Integer": {
+			Value:  int64(1),
+			Result: 8,
+		},
+		"String": {
+			Value:  str,
+			Result: strSize + 5,
+		},
+		"Byte slice": {
+			Value:  make([]byte, 3, 10),
+			Result: sliceSize + 10,
+		},
+		"Nil byte slice": {
+			Value:  []byte(nil),
+			Result: sliceSize,
+		},
+		"String slice": {
+			Value:  []string{"a", "bc"},
+			Result: sliceSize + 2*strSize + 3,
+		},
+		"Map of primitives": {
+			Value:  map[int32]int32{1: 1, 2: 2},
+			Result: ptrSize + 2*(4+4+mapEntryOverhead),
+		},
+		"Map of strings": {
+			Value:  map[string]int64{"ab": 1},
+			Result: ptrSize + strSize + 8 + mapEntryOverhead + 2,
+		},
+		"Pointer": {
+			Value:  &str,
+			Result: ptrSize + strSize + 5,
+		},
+		"Nil pointer": {
+			Value:  (*string)(nil),
+			Result: ptrSize,
+		},
+		"Struct": {
+			Value: struct {
+				A int64
+				B string
+			}{A: 1, B: "abc"},
+			Result: 8 + strSize + 3,
+		},
+		"Array": {
+			Value:  [2]string{"a", "b"},
+			Result: 2*strSize + 2,
+		},
+		"Interface slice": {
+			Value:  []interface{}{int64(1)},
+			Result: sliceSize + 2*ptrSize + 8,
+		},
+		"Self referencing pointer": {
+			Value:  looped,
+			Result: ptrSize + ptrSize + strSize + 3,
+		},
+		"Sizer": {
+			Value:  testSizer(123),
+			Result: 123,
+		},
+		"Nested sizers": {
+			Value: struct {
+				A testSizer
+				B []testSizer
+				C map[string]testSizer
+				D *testSizer
+				E interface{}
+			}{
+				A: 100,
+				B: []testSizer{10, 20},
+				C: map[string]testSizer{"a": 30},
+				D: &sizer,
+				E: testSizer(50),
+			},
+			Result: 100 + sliceSize + 10 + 20 + ptrSize + strSize + mapEntryOverhead + 1 + 30 + ptrSize + 40 + 2*ptrSize + 50,
+		},
+		"Pointer receiver sizer": {
+			Value:  []ptrSizer{{}, {}},
+			Result: sliceSize + 2*64,
+		},
+		"Sizer smaller than its direct size": {
+			Value:  []testSizer{1},
+			Result: sliceSize + 8,
+		},
+		"Sizer in unexported field": {
+			Value: struct {
+				a testSizer
+			}{a: 100},
+			Result: 8,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, c.Result, EstimateSize(c.Value))
+		})
+	}
+}
+
+func Test_memoryCost(t *testing.T) {
+	var item Item[string, []byte]
+
+	overhead := uint64(unsafe.Sizeof(item) - unsafe.Sizeof(item.key) - unsafe.Sizeof(item.value))
+	res := memoryCost("key", make([]byte, 100))
+	assert.Equal(t, overhead+EstimateSize("key")+EstimateSize(make([]byte, 100)), res)
+}
+
+func Test_Cache_WithMaxMemory(t *testing.T) {
+	cache := New[string, []byte](
+		WithMaxMemory[string, []byte](10000),
+	)
+
+	for _, key := range []string{"1", "2", "3"} {
+		cache.Set(key, make([]byte, 4000), NoTTL)
+	}
+
+	assert.ElementsMatch(t, []string{"2", "3"}, cache.Keys())
+	assert.Equal(t, 2*memoryCost("1", make([]byte, 4000)), cache.Metrics().EstimatedBytes)
+
+	assert.Nil(t, cache.Set("4", make([]byte, 20000), NoTTL))
+	assert.Equal(t, uint64(1), cache.Metrics().CostRejections)
+}
+
+type testSizer int
+
+func (s testSizer) Size() uint64 {
+	return uint64(s)
+}
+
+type ptrSizer struct {
+	A int64
+}
+
+func (s *ptrSizer) Size() uint64 {
+	return 64
+}
@@ -0,0 +1,234 @@
+package ttlcache
+
+import (
+	"container/list"
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+// SnapshotItem holds the data of a single item of a cache snapshot.
+type SnapshotItem[K comparable, V any] struct {
+	Key       K
+	Value     V
+	TTL       time.Duration
+	ExpiresAt time.Time
+	Version   int64
+	Cost      uint64
+
+	// Deleted reports whether the item was deleted. Deleted items
+	// are written only by SaveSince and hold only their keys.
+	Deleted bool
+}
+
+// newSnapshotItem creates a new snapshot item from the provided item.
+// Not concurrently safe.
+func newSnapshotItem[K comparable, V any](item *Item[K, V]) SnapshotItem[K, V] {
+	return SnapshotItem[K, V]{
+		Key:       item.key,
+		Value:     item.value,
+		TTL:       item.ttl,
+		ExpiresAt: item.expiresAt,
+		Version:   item.version,
+		Cost:      item.cost,
+	}
+}
+
+// isExpired returns a bool value that indicates whether the snapshot
+// item is expired.
+func (si SnapshotItem[K, V]) isExpired() bool {
+	return si.TTL > 0 && si.ExpiresAt.Before(time.Now())
+}
+
+// SnapshotCodec is an interface that handles the encoding and decoding
+// of the items of cache snapshots.
+type SnapshotCodec[K comparable, V any] interface {
+	// NewEncoder should return an encoder that writes the encoded
+	// snapshot items to the provided writer.
+	NewEncoder(w io.Writer) SnapshotEncoder[K, V]
+
+	// NewDecoder should return a decoder that reads the snapshot
+	// items from the provided reader.
+	NewDecoder(r io.Reader) SnapshotDecoder[K, V]
+}
+
+// SnapshotEncoder is an interface that handles the encoding of the
+// items of a single cache snapshot.
+// If the encoder also implements io.Closer, it is closed after the
+// last item is encoded, so that it can flush its buffered data.
+type SnapshotEncoder[K comparable, V any] interface {
+	// Encode should write the encoded snapshot item.
+	Encode(item SnapshotItem[K, V]) error
+}
+
+// SnapshotDecoder is an interface that handles the decoding of the
+// items of a single cache snapshot.
+type SnapshotDecoder[K comparable, V any] interface {
+	// Decode should read the next snapshot item.
+	// It should return io.EOF when there are no more items.
+	Decode() (SnapshotItem[K, V], error)
+}
+
+// GobSnapshotCodec is a SnapshotCodec that encodes snapshot items with
+// the encoding/gob package. It is used by default.
+// Interface keys or values require their concrete types to be
+// registered with gob.Register.
+type GobSnapshotCodec[K comparable, V any] struct{}
+
+// NewEncoder returns a gob encoder that writes to the provided writer.
+func (GobSnapshotCodec[K, V]) NewEncoder(w io.Writer) SnapshotEncoder[K, V] {
+	return gobSnapshotEncoder[K, V]{enc: gob.NewEncoder(w)}
+}
+
+// NewDecoder returns a gob decoder that reads from the provided reader.
+func (GobSnapshotCodec[K, V]) NewDecoder(r io.Reader) SnapshotDecoder[K, V] {
+	return gobSnapshotDecoder[K, V]{dec: gob.NewDecoder(r)}
+}
+
+// gobSnapshotEncoder encodes snapshot items with a gob encoder.
+type gobSnapshotEncoder[K comparable, V any] struct {
+	enc *gob.Encoder
+}
+
+// Encode writes the encoded snapshot item.
+func (e gobSnapshotEncoder[K, V]) Encode(item SnapshotItem[K, V]) error {
+	return e.enc.Encode(&item)
+}
+
+// gobSnapshotDecoder decodes snapshot items with a gob decoder.
+type gobSnapshotDecoder[K comparable, V any] struct {
+	dec *gob.Decoder
+}
+
+// Decode reads the next snapshot item.
+func (d gobSnapshotDecoder[K, V]) Decode() (SnapshotItem[K, V], error) {
+	var item SnapshotItem[K, V]
+	err := d.dec.Decode(&item)
+
+	return item, err
+}
+
+// encodeSnapshot writes the provided snapshot items to the provided
+// writer using the provided codec.
+func encodeSnapshot[K comparable, V any](w io.Writer, codec SnapshotCodec[K, V], items []SnapshotItem[K, V]) error {
+	enc := codec.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := enc.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// decodeSnapshot reads all snapshot items from the provided reader
+// using the provided codec and applies them to the provided state.
+func decodeSnapshot[K comparable, V any](r io.Reader, codec SnapshotCodec[K, V], state *snapshotState[K, V]) error {
+	dec := codec.NewDecoder(r)
+	for {
+		item, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if item.Deleted {
+			state.delete(item.Key)
+		} else {
+			state.set(item)
+		}
+	}
+}
+
+// snapshotState holds the items that are recovered from snapshots or
+// the write-ahead log, in the order in which they were last set or
+// touched, along with the keys of the items that were deleted.
+type snapshotState[K comparable, V any] struct {
+	list    *list.List
+	elems   map[K]*list.Element
+	deleted map[K]struct{}
+}
+
+// newSnapshotState creates a new empty snapshot state.
+func newSnapshotState[K comparable, V any]() *snapshotState[K, V] {
+	return &snapshotState[K, V]{
+		list:    list.New(),
+		elems:   make(map[K]*list.Element),
+		deleted: make(map[K]struct{}),
+	}
+}
+
+// set adds or replaces the item.
+func (s *snapshotState[K, V]) set(item SnapshotItem[K, V]) {
+	s.delete(item.Key)
+	delete(s.deleted, item.Key)
+	s.elems[item.Key] = s.list.PushBack(item)
+}
+
+// delete removes the item with the provided key.
+func (s *snapshotState[K, V]) delete(key K) {
+	s.deleted[key] = struct{}{}
+
+	if elem, ok := s.elems[key]; ok {
+		s.list.Remove(elem)
+		delete(s.elems, key)
+	}
+}
+
+// touch updates the expiration timestamp of the item with the
+// provided key.
+func (s *snapshotState[K, V]) touch(key K, expiresAt time.Time) {
+	elem, ok := s.elems[key]
+	if !ok {
+		return
+	}
+
+	item := elem.Value.(SnapshotItem[K, V])
+	item.ExpiresAt = expiresAt
+	elem.Value = item
+	s.list.MoveToBack(elem)
+}
+
+// clear removes all items.
+func (s *snapshotState[K, V]) clear() {
+	s.list.Init()
+	s.elems = make(map[K]*list.Element)
+	s.deleted = make(map[K]struct{})
+}
+
+// items returns all items that are not expired, from the least
+// recently set or touched one to the most recent one.
+func (s *snapshotState[K, V]) items() []SnapshotItem[K, V] {
+	items := make([]SnapshotItem[K, V], 0, s.list.Len())
+	for elem := s.list.Front(); elem != nil; elem = elem.Next() {
+		if item := elem.Value.(SnapshotItem[K, V]); !item.isExpired() {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// deletedKeys returns the keys of all items that were deleted or are
+// expired.
+func (s *snapshotState[K, V]) deletedKeys() []K {
+	keys := make([]K, 0, len(s.deleted))
+	for key := range s.deleted {
+		keys = append(keys, key)
+	}
+
+	for elem := s.list.Front(); elem != nil; elem = elem.Next() {
+		if item := elem.Value.(SnapshotItem[K, V]); item.isExpired() {
+			keys = append(keys, item.Key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,188 @@
+package ttlcache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newSnapshotItem(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	item := &Item[string, string]{
+		key:       "key",
+		value:     "value",
+		ttl:       time.Hour,
+		expiresAt: expiresAt,
+		version:   3,
+		cost:      5,
+	}
+
+	assert.Equal(t, SnapshotItem[string, string]{
+		Key:       "key",
+		Value:     "value",
+		TTL:       time.Hour,
+		ExpiresAt: expiresAt,
+		Version:   3,
+		Cost:      5,
+	}, newSnapshotItem(item))
+}
+
+func Test_SnapshotItem_isExpired(t *testing.T) {
+	// no ttl
+	si := SnapshotItem[string, string]{
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	assert.False(t, si.isExpired())
+
+	// expired
+	si.TTL = time.Hour
+	assert.True(t, si.isExpired())
+
+	// not expired
+	si.ExpiresAt = time.Now().Add(time.Hour)
+	assert.False(t, si.isExpired())
+}
+
+func Test_GobSnapshotCodec(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		codec GobSnapshotCodec[string, int]
+		items = []SnapshotItem[string, int]{
+			{Key: "1", Value: 1, TTL: time.Hour, ExpiresAt: time.Now().Add(time.Hour).Round(0), Version: 2, Cost: 3},
+			{Key: "2", Value: 2, TTL: NoTTL},
+		}
+	)
+
+	enc := codec.NewEncoder(&buf)
+	for _, item := range items {
+		require.NoError(t, enc.Encode(item))
+	}
+
+	dec := codec.NewDecoder(&buf)
+	for _, item := range items {
+		res, err := dec.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, item.Key, res.Key)
+		assert.Equal(t, item.Value, res.Value)
+		assert.Equal(t, item.TTL, res.TTL)
+		assert.True(t, item.ExpiresAt.Equal(res.ExpiresAt))
+		assert.Equal(t, item.Version, res.Version)
+		assert.Equal(t, item.Cost, res.Cost)
+	}
+
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_encodeSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+
+	items := []SnapshotItem[string, string]{{Key: "1"}, {Key: "2"}}
+	require.NoError(t, encodeSnapshot[string, string](&buf, GobSnapshotCodec[string, string]{}, items))
+
+	res, err := decodeSnapshotItems(&buf, GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	assert.Equal(t, items, res)
+
+	// failed write
+	errWrite := errors.New("error")
+	err = encodeSnapshot[string, string](failingWriter{err: errWrite}, GobSnapshotCodec[string, string]{}, items)
+	assert.Equal(t, errWrite, err)
+}
+
+func Test_decodeSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+
+	items := []SnapshotItem[string, string]{
+		{Key: "1", TTL: time.Hour, ExpiresAt: time.Now().Add(-time.Minute)},
+		{Key: "2", TTL: time.Hour, ExpiresAt: time.Now().Add(time.Minute)},
+		{Key: "3", TTL: NoTTL},
+		{Key: "4", Deleted: true},
+		{Key: "3", Deleted: true},
+	}
+	require.NoError(t, encodeSnapshot[string, string](&buf, GobSnapshotCodec[string, string]{}, items))
+
+	// expired and deleted items are not added
+	state := newSnapshotState[string, string]()
+	require.NoError(t, decodeSnapshot[string, string](bytes.NewReader(buf.Bytes()), GobSnapshotCodec[string, string]{}, state))
+	assert.Equal(t, []string{"2"}, snapshotStateKeys(state))
+	assert.ElementsMatch(t, []string{"1", "3", "4"}, state.deletedKeys())
+
+	// empty snapshot
+	state = newSnapshotState[string, string]()
+	assert.NoError(t, decodeSnapshot[string, string](&bytes.Buffer{}, GobSnapshotCodec[string, string]{}, state))
+	assert.Empty(t, state.items())
+
+	// corrupted snapshot
+	err := decodeSnapshot[string, string](bytes.NewReader(buf.Bytes()[:buf.Len()-1]), GobSnapshotCodec[string, string]{}, state)
+	assert.Error(t, err)
+}
+
+func Test_snapshotState(t *testing.T) {
+	state := newSnapshotState[string, string]()
+
+	state.set(SnapshotItem[string, string]{Key: "1", Value: "value1"})
+	state.set(SnapshotItem[string, string]{Key: "2", TTL: time.Hour, ExpiresAt: time.Now().Add(time.Hour)})
+	state.set(SnapshotItem[string, string]{Key: "3"})
+	state.set(SnapshotItem[string, string]{Key: "1", Value: "value2"})
+	assert.Equal(t, []string{"2", "3", "1"}, snapshotStateKeys(state))
+	assert.Equal(t, "value2", state.items()[2].Value)
+	assert.Empty(t, state.deletedKeys())
+
+	// expired items are skipped
+	state.touch("2", time.Now().Add(-time.Minute))
+	state.touch("4", time.Now())
+	assert.Equal(t, []string{"3", "1"}, snapshotStateKeys(state))
+	assert.Equal(t, []string{"2"}, state.deletedKeys())
+
+	state.touch("2", time.Now().Add(time.Minute))
+	assert.Equal(t, []string{"3", "1", "2"}, snapshotStateKeys(state))
+
+	state.delete("3")
+	state.delete("4")
+	assert.Equal(t, []string{"1", "2"}, snapshotStateKeys(state))
+	assert.ElementsMatch(t, []string{"3", "4"}, state.deletedKeys())
+
+	// deleted items may be set again
+	state.set(SnapshotItem[string, string]{Key: "3"})
+	assert.Equal(t, []string{"4"}, state.deletedKeys())
+
+	state.clear()
+	assert.Empty(t, state.items())
+	assert.Empty(t, state.elems)
+	assert.Empty(t, state.deletedKeys())
+}
+
+// decodeSnapshotItems returns the snapshot items that are read from
+// the provided reader and are not expired.
+func decodeSnapshotItems(r io.Reader, codec SnapshotCodec[string, string]) ([]SnapshotItem[string, string], error) {
+	state := newSnapshotState[string, string]()
+	if err := decodeSnapshot(r, codec, state); err != nil {
+		return nil, err
+	}
+
+	return state.items(), nil
+}
+
+func snapshotStateKeys(state *snapshotState[string, string]) []string {
+	var keys []string
+	for _, item := range state.items() {
+		keys = append(keys, item.Key)
+	}
+
+	return keys
+}
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(_ []byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,252 @@
+package ttlcache
+
+import "time"
+
+const (
+	// wheelLevels is the number of levels of a timing wheel.
+	wheelLevels = 4
+
+	// wheelBits is the number of bits of a tick number that select
+	// a slot within a single level.
+	wheelBits = 6
+
+	// wheelSlots is the number of slots of each timing wheel level.
+	wheelSlots = 1 << wheelBits
+
+	// wheelMask is used to extract a slot index from a tick number.
+	wheelMask = wheelSlots - 1
+)
+
+// timerSlot is a doubly linked list of items that are scheduled to
+// expire within the same timing wheel slot.
+type timerSlot[K comparable, V any] struct {
+	head *Item[K, V]
+}
+
+// timingWheel is a hierarchical timing wheel that stores items by
+// their expiration timestamps.
+// Each level consists of a fixed number of slots, and each slot of a
+// level spans all slots of the level below it. Items are scheduled
+// into the lowest level that is able to hold their expiration
+// timestamps and cascade down when the wheel reaches their slots, so
+// that scheduling, rescheduling and removal of an item take constant
+// time.
+// Items that never expire are not scheduled at all.
+type timingWheel[K comparable, V any] struct {
+	tick    time.Duration
+	levels  [wheelLevels][wheelSlots]timerSlot[K, V]
+	current int64
+	len     int
+}
+
+// newTimingWheel creates a new timing wheel with the provided
+// resolution.
+func newTimingWheel[K comparable, V any](tick time.Duration) *timingWheel[K, V] {
+	if tick <= 0 {
+		tick = time.Second
+	}
+
+	return &timingWheel[K, V]{
+		tick:    tick,
+		current: time.Now().UnixNano() / int64(tick),
+	}
+}
+
+// isEmpty checks if the wheel is empty.
+func (w *timingWheel[K, V]) isEmpty() bool {
+	return w.len == 0
+}
+
+// schedule adds the item to the wheel or moves it to the slot that
+// matches its current expiration timestamp. If the item never
+// expires, it is removed from the wheel.
+func (w *timingWheel[K, V]) schedule(item *Item[K, V]) {
+	w.remove(item)
+
+	if item.expiresAt.IsZero() {
+		return
+	}
+
+	if w.len == 0 {
+		// nothing is waiting, so there is no need to walk through
+		// the ticks that passed since the last advance
+		if now := time.Now().UnixNano() / int64(w.tick); now > w.current {
+			w.current = now
+		}
+	}
+
+	// the current tick is already processed
+	w.insert(item, 1)
+}
+
+// insert places the item into the slot that matches its expiration
+// timestamp, but not sooner than the provided number of ticks after
+// the current one.
+func (w *timingWheel[K, V]) insert(item *Item[K, V], minDelta int64) {
+	at := item.expiresAt.UnixNano() / int64(w.tick)
+
+	delta := at - w.current
+	if delta < minDelta {
+		delta, at = minDelta, w.current+minDelta
+	}
+
+	level := 0
+	for level < wheelLevels-1 && delta >= 1<<(wheelBits*(level+1)) {
+		level++
+	}
+
+	if max := int64(1) << (wheelBits * wheelLevels); delta >= max {
+		// beyond the wheel's span, the item is rescheduled when
+		// the wheel reaches the farthest slot
+		at = w.current + max - 1
+	}
+
+	slot := &w.levels[level][(at>>(wheelBits*level))&wheelMask]
+
+	item.wheelSlot = slot
+	item.wheelPrev = nil
+	item.wheelNext = slot.head
+
+	if slot.head != nil {
+		slot.head.wheelPrev = item
+	}
+
+	slot.head = item
+	w.len++
+}
+
+// remove removes the item from the wheel. If the item is not
+// scheduled, the method is no-op.
+func (w *timingWheel[K, V]) remove(item *Item[K, V]) {
+	slot := item.wheelSlot
+	if slot == nil {
+		return
+	}
+
+	if item.wheelPrev != nil {
+		item.wheelPrev.wheelNext = item.wheelNext
+	} else {
+		slot.head = item.wheelNext
+	}
+
+	if item.wheelNext != nil {
+		item.wheelNext.wheelPrev = item.wheelPrev
+	}
+
+	item.wheelSlot = nil
+	item.wheelPrev = nil
+	item.wheelNext = nil
+	w.len--
+}
+
+// detach removes all items from the slot and returns them as a
+// linked list.
+func (w *timingWheel[K, V]) detach(slot *timerSlot[K, V]) *Item[K, V] {
+	head := slot.head
+	slot.head = nil
+
+	for item := head; item != nil; item = item.wheelNext {
+		item.wheelSlot = nil
+		w.len--
+	}
+
+	return head
+}
+
+// advance moves the wheel forward up to the provided time and
+// returns all items that have expired by then.
+func (w *timingWheel[K, V]) advance(now time.Time) []*Item[K, V] {
+	target := now.UnixNano() / int64(w.tick)
+
+	var res []*Item[K, V]
+
+	for w.current < target {
+		// ticks without any due or cascading items are skipped
+		t := w.nextTick()
+		if w.len == 0 || t > target {
+			w.current = target
+			break
+		}
+
+		w.current = t
+
+		// cascade the higher level slots that start at this tick,
+		// starting with the highest one, so that none of the
+		// cascaded items land in an already detached slot
+		top := 0
+		for top < wheelLevels-1 && t&(1<<(wheelBits*(top+1))-1) == 0 {
+			top++
+		}
+
+		for level := top; level > 0; level-- {
+			w.reschedule(w.detach(&w.levels[level][(t>>(wheelBits*level))&wheelMask]), 0)
+		}
+
+		var due *Item[K, V]
+		for item := w.detach(&w.levels[0][t&wheelMask]); item != nil; {
+			next := item.wheelNext
+			item.wheelPrev, item.wheelNext = nil, nil
+
+			if item.expiresAt.After(now) {
+				// the item expires later within the current
+				// tick
+				item.wheelNext = due
+				due = item
+			} else {
+				res = append(res, item)
+			}
+
+			item = next
+		}
+
+		w.reschedule(due, 1)
+	}
+
+	return res
+}
+
+// nextTick returns the first tick after the current one at which
+// either a lowest level slot holds items that are due or a higher
+// level slot holding items needs to be cascaded.
+func (w *timingWheel[K, V]) nextTick() int64 {
+	next := w.current + 1<<(wheelBits*wheelLevels)
+
+	// lowest level items are never scheduled a full rotation ahead
+	for t := w.current + 1; t < w.current+wheelSlots; t++ {
+		if w.levels[0][t&wheelMask].head != nil {
+			next = t
+			break
+		}
+	}
+
+	for level := 1; level < wheelLevels; level++ {
+		span := int64(1) << (wheelBits * level)
+		start := (w.current/span + 1) * span
+
+		for i := int64(0); i < wheelSlots; i++ {
+			t := start + i*span
+			if t >= next {
+				break
+			}
+
+			if w.levels[level][(t>>(wheelBits*level))&wheelMask].head != nil {
+				next = t
+				break
+			}
+		}
+	}
+
+	return next
+}
+
+// reschedule inserts all items of the provided linked list into the
+// wheel again, but not sooner than the provided number of ticks after
+// the current one.
+func (w *timingWheel[K, V]) reschedule(head *Item[K, V], minDelta int64) {
+	for item := head; item != nil; {
+		next := item.wheelNext
+		item.wheelPrev, item.wheelNext = nil, nil
+		w.insert(item, minDelta)
+		item = next
+	}
+}
@@ -0,0 +1,277 @@
+package ttlcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newTimingWheel(t *testing.T) {
+	w := newTimingWheel[string, string](time.Millisecond)
+	require.NotNil(t, w)
+	assert.Equal(t, time.Millisecond, w.tick)
+	assert.InDelta(t, time.Now().UnixNano()/int64(time.Millisecond), w.current, 1)
+	assert.True(t, w.isEmpty())
+
+	w = newTimingWheel[string, string](0)
+	assert.Equal(t, time.Second, w.tick)
+}
+
+func Test_timingWheel_isEmpty(t *testing.T) {
+	w := newTimingWheel[string, string](time.Millisecond)
+	assert.True(t, w.isEmpty())
+
+	w.schedule(&Item[string, string]{key: "1", expiresAt: time.Now().Add(time.Hour)})
+	assert.False(t, w.isEmpty())
+}
+
+func Test_timingWheel_schedule(t *testing.T) {
+	w := newTimingWheel[string, string](time.Millisecond)
+
+	// never expires
+	item := &Item[string, string]{key: "1"}
+	w.schedule(item)
+	assert.True(t, w.isEmpty())
+	assert.Nil(t, item.wheelSlot)
+
+	// stale wheel is synchronised
+	w.current -= 1000
+	item.expiresAt = time.Now().Add(time.Millisecond * 10)
+	w.schedule(item)
+	assert.Equal(t, 1, w.len)
+	assert.InDelta(t, time.Now().UnixNano()/int64(time.Millisecond), w.current, 1)
+	assert.Same(t, &w.levels[0][(item.expiresAt.UnixNano()/int64(time.Millisecond))&wheelMask], item.wheelSlot)
+
+	// rescheduled
+	item.expiresAt = time.Now().Add(time.Second)
+	w.schedule(item)
+	assert.Equal(t, 1, w.len)
+	assert.Same(t, &w.levels[1][(item.expiresAt.UnixNano()/int64(time.Millisecond)>>wheelBits)&wheelMask], item.wheelSlot)
+
+	// removed
+	item.expiresAt = time.Time{}
+	w.schedule(item)
+	assert.True(t, w.isEmpty())
+	assert.Nil(t, item.wheelSlot)
+}
+
+func Test_timingWheel_insert(t *testing.T) {
+	const base = int64(1) << 30
+
+	at := func(ticks int64) time.Time {
+		return time.Unix(0, (base+ticks)*int64(time.Millisecond))
+	}
+
+	cc := map[string]struct {
+		Ticks    int64
+		MinDelta int64
+		Level    int
+		Slot     int64
+	}{
+		"Overdue": {
+			Ticks:    -5,
+			MinDelta: 1,
+			Level:    0,
+			Slot:     (base + 1) & wheelMask,
+		},
+		"Overdue during cascade": {
+			Ticks:    -5,
+			MinDelta: 0,
+			Level:    0,
+			Slot:     base & wheelMask,
+		},
+		"First level": {
+			Ticks:    63,
+			MinDelta: 1,
+			Level:    0,
+			Slot:     (base + 63) & wheelMask,
+		},
+		"Second level": {
+			Ticks:    64,
+			MinDelta: 1,
+			Level:    1,
+			Slot:     ((base + 64) >> wheelBits) & wheelMask,
+		},
+		"Last level": {
+			Ticks:    1 << (wheelBits * 3),
+			MinDelta: 1,
+			Level:    3,
+			Slot:     ((base + 1<<(wheelBits*3)) >> (wheelBits * 3)) & wheelMask,
+		},
+		"Beyond span": {
+			Ticks:    1 << (wheelBits * 5),
+			MinDelta: 1,
+			Level:    3,
+			Slot:     ((base + 1<<(wheelBits*4) - 1) >> (wheelBits * 3)) & wheelMask,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			w := &timingWheel[string, string]{
+				tick:    time.Millisecond,
+				current: base,
+			}
+
+			item := &Item[string, string]{key: "1", expiresAt: at(c.Ticks)}
+			w.insert(item, c.MinDelta)
+			assert.Equal(t, 1, w.len)
+			assert.Same(t, &w.levels[c.Level][c.Slot], item.wheelSlot)
+			assert.Same(t, item, item.wheelSlot.head)
+		})
+	}
+}
+
+func Test_timingWheel_remove(t *testing.T) {
+	w := newTimingWheel[string, string](time.Millisecond)
+	exp := time.Now().Add(time.Hour)
+
+	item1 := &Item[string, string]{key: "1", expiresAt: exp}
+	item2 := &Item[string, string]{key: "2", expiresAt: exp}
+	item3 := &Item[string, string]{key: "3", expiresAt: exp}
+	w.schedule(item1)
+	w.schedule(item2)
+	w.schedule(item3)
+
+	slot := item1.wheelSlot
+	require.Same(t, slot, item2.wheelSlot)
+	require.Same(t, slot, item3.wheelSlot)
+
+	// middle
+	w.remove(item2)
+	assert.Equal(t, 2, w.len)
+	assert.Nil(t, item2.wheelSlot)
+	assert.Same(t, item3, slot.head)
+	assert.Same(t, item1, item3.wheelNext)
+	assert.Same(t, item3, item1.wheelPrev)
+
+	// head
+	w.remove(item3)
+	assert.Same(t, item1, slot.head)
+	assert.Nil(t, item1.wheelPrev)
+
+	// last
+	w.remove(item1)
+	assert.Nil(t, slot.head)
+	assert.True(t, w.isEmpty())
+
+	// not scheduled
+	w.remove(item1)
+	assert.True(t, w.isEmpty())
+}
+
+func Test_timingWheel_detach(t *testing.T) {
+	w := newTimingWheel[string, string](time.Millisecond)
+	exp := time.Now().Add(time.Hour)
+
+	item1 := &Item[string, string]{key: "1", expiresAt: exp}
+	item2 := &Item[string, string]{key: "2", expiresAt: exp}
+	w.schedule(item1)
+	w.schedule(item2)
+
+	slot := item1.wheelSlot
+	head := w.detach(slot)
+	assert.True(t, w.isEmpty())
+	assert.Nil(t, slot.head)
+	assert.Same(t, item2, head)
+	assert.Same(t, item1, head.wheelNext)
+	assert.Nil(t, item1.wheelSlot)
+	assert.Nil(t, item2.wheelSlot)
+}
+
+func Test_timingWheel_nextTick(t *testing.T) {
+	w := &timingWheel[string, string]{
+		tick:    time.Millisecond,
+		current: 1<<18 - 3,
+	}
+
+	at := func(ticks int64) time.Time {
+		return time.Unix(0, (w.current+ticks)*int64(time.Millisecond))
+	}
+
+	// empty wheel
+	assert.Equal(t, w.current+1<<24, w.nextTick())
+
+	// highest level
+	item1 := &Item[string, string]{key: "1", expiresAt: at(1 << 20)}
+	w.insert(item1, 1)
+	assert.Equal(t, int64(1<<20), w.nextTick())
+
+	// lower level slot that starts earlier
+	item2 := &Item[string, string]{key: "2", expiresAt: at(100)}
+	w.insert(item2, 1)
+	assert.Equal(t, int64(1<<18+64), w.nextTick())
+
+	// lowest level
+	item3 := &Item[string, string]{key: "3", expiresAt: at(2)}
+	w.insert(item3, 1)
+	assert.Equal(t, w.current+2, w.nextTick())
+
+	// first slot of the next rotation
+	item4 := &Item[string, string]{key: "4", expiresAt: at(3)}
+	w.insert(item4, 1)
+	w.remove(item3)
+	assert.Equal(t, int64(1<<18), w.nextTick())
+}
+
+func Test_timingWheel_advance(t *testing.T) {
+	const base = int64(1)<<30 - 3
+
+	at := func(ticks int64) time.Time {
+		return time.Unix(0, (base+ticks)*int64(time.Millisecond))
+	}
+
+	w := &timingWheel[string, string]{
+		tick:    time.Millisecond,
+		current: base,
+	}
+
+	ticks := []int64{1, 2, 10, 63, 64, 65, 100, 4095, 4096, 5000, 1 << 18, 1<<18 + 7, 1 << 25}
+	items := make(map[int64]*Item[string, string])
+
+	for _, tk := range ticks {
+		items[tk] = &Item[string, string]{key: fmt.Sprint(tk), expiresAt: at(tk)}
+		w.insert(items[tk], 1)
+	}
+
+	// nothing expired yet
+	assert.Empty(t, w.advance(at(0)))
+	assert.Equal(t, len(ticks), w.len)
+
+	// expiration later within the current tick
+	late := &Item[string, string]{key: "late", expiresAt: at(1).Add(time.Microsecond * 500)}
+	w.insert(late, 1)
+	assert.Equal(t, []*Item[string, string]{items[1]}, w.advance(at(1)))
+	assert.Equal(t, base+1, w.current)
+	assert.Equal(t, len(ticks), w.len)
+	assert.Same(t, &w.levels[0][(base+2)&wheelMask], late.wheelSlot)
+	w.remove(late)
+	ticks = ticks[1:]
+	prev := int64(1)
+
+	for _, tk := range ticks {
+		res := w.advance(at(tk))
+		require.Len(t, res, 1, "tick %d", tk)
+		assert.Same(t, items[tk], res[0])
+		assert.Nil(t, res[0].wheelSlot)
+
+		if tk-prev > 1 {
+			assert.Empty(t, w.advance(at(tk)))
+		}
+
+		prev = tk
+	}
+
+	assert.True(t, w.isEmpty())
+
+	// empty wheel jumps forward
+	w.advance(at(1 << 30))
+	assert.Equal(t, base+1<<30, w.current)
+}
@@ -0,0 +1,456 @@
+package ttlcache
+
+import "container/list"
+
+// AdmissionPolicy is an EvictionPolicy that may refuse to admit
+// items into its main space when the capacity of the cache is
+// reached.
+type AdmissionPolicy[K comparable, V any] interface {
+	EvictionPolicy[K, V]
+
+	// Rejected reports whether the item that was last returned by
+	// Victim was refused admission rather than evicted from the
+	// policy's main space.
+	Rejected() bool
+}
+
+// Available W-TinyLFU segments.
+const (
+	tinyLFUWindow uint8 = iota
+	tinyLFUProbation
+	tinyLFUProtected
+)
+
+// TinyLFUPolicy is a W-TinyLFU eviction policy.
+// New items are placed into a small LRU admission window. When the
+// window is full, its least recently used item becomes a candidate
+// for the segmented (probation/protected) main LRU space and is
+// admitted into it only if it was used more frequently than the
+// main space's victim. Access frequencies are estimated with a
+// count-min sketch that is guarded by a doorkeeper bloom filter.
+type TinyLFUPolicy[K comparable, V any] struct {
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	entries   map[K]*tinyLFUEntry
+
+	// weighted specifies whether the segments are sized by the
+	// costs of their items rather than by their number.
+	weighted bool
+
+	windowCap     uint64
+	windowSize    uint64
+	protectedCap  uint64
+	protectedSize uint64
+
+	sketch *frequencySketch
+}
+
+// tinyLFUEntry holds the position of a single item inside of the
+// policy's segments.
+type tinyLFUEntry struct {
+	elem    *list.Element
+	segment uint8
+	weight  uint64
+}
+
+// NewTinyLFUPolicy creates a new instance of W-TinyLFU eviction
+// policy. The capacity parameter should match the capacity of the
+// cache; it is used to size the policy's segments and its frequency
+// sketch.
+func NewTinyLFUPolicy[K comparable, V any](capacity uint64) *TinyLFUPolicy[K, V] {
+	return newTinyLFUPolicy[K, V](capacity, capacity, false)
+}
+
+// NewWeightedTinyLFUPolicy creates a new instance of W-TinyLFU
+// eviction policy whose segments are sized by the costs of their
+// items rather than by their number. The maxCost parameter should
+// match the maximum cost of the cache.
+func NewWeightedTinyLFUPolicy[K comparable, V any](maxCost uint64) *TinyLFUPolicy[K, V] {
+	// the number of items is unknown, so the size of the frequency
+	// sketch is limited
+	keys := maxCost
+	if keys > maxWeightedSketchKeys {
+		keys = maxWeightedSketchKeys
+	}
+
+	return newTinyLFUPolicy[K, V](maxCost, keys, true)
+}
+
+// maxWeightedSketchKeys limits the size of the frequency sketch of
+// weighted policies.
+const maxWeightedSketchKeys = 1 << 16
+
+// newTinyLFUPolicy creates a new instance of W-TinyLFU eviction
+// policy with the provided budget of its segments and the provided
+// number of keys that its frequency sketch is sized for.
+func newTinyLFUPolicy[K comparable, V any](budget, keys uint64, weighted bool) *TinyLFUPolicy[K, V] {
+	windowCap := budget / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+
+	var mainCap uint64
+	if budget > windowCap {
+		mainCap = budget - windowCap
+	}
+
+	return &TinyLFUPolicy[K, V]{
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		entries:      make(map[K]*tinyLFUEntry),
+		weighted:     weighted,
+		windowCap:    windowCap,
+		protectedCap: mainCap * 8 / 10,
+		sketch:       newFrequencySketch(keys),
+	}
+}
+
+// OnInsert places the item into the admission window.
+// If the window overflows, its least recently used items are moved
+// into the main space.
+func (p *TinyLFUPolicy[K, V]) OnInsert(item *Item[K, V]) {
+	p.sketch.increment(hashKey(item.key))
+
+	entry := &tinyLFUEntry{
+		elem:    p.window.PushFront(item),
+		segment: tinyLFUWindow,
+		weight:  p.weight(item),
+	}
+	p.entries[item.key] = entry
+	p.windowSize += entry.weight
+
+	for p.windowSize > p.windowCap && p.window.Len() > 0 {
+		p.moveToProbation(p.window.Back())
+	}
+}
+
+// OnAccess records the access in the frequency sketch and promotes
+// the item within its segment.
+func (p *TinyLFUPolicy[K, V]) OnAccess(item *Item[K, V]) {
+	entry := p.entries[item.key]
+	if entry == nil {
+		return
+	}
+
+	p.sketch.increment(hashKey(item.key))
+
+	switch entry.segment {
+	case tinyLFUWindow:
+		p.window.MoveToFront(entry.elem)
+	case tinyLFUProbation:
+		p.probation.Remove(entry.elem)
+		entry.elem = p.protected.PushFront(item)
+		entry.segment = tinyLFUProtected
+		p.protectedSize += entry.weight
+
+		for p.protectedSize > p.protectedCap && p.protected.Len() > 0 {
+			p.moveToProbation(p.protected.Back())
+		}
+	case tinyLFUProtected:
+		p.protected.MoveToFront(entry.elem)
+	}
+}
+
+// OnUpdate updates the weight of the item, records the access in the
+// frequency sketch and promotes the item within its segment.
+func (p *TinyLFUPolicy[K, V]) OnUpdate(item *Item[K, V]) {
+	if entry := p.entries[item.key]; entry != nil {
+		if size := p.size(entry.segment); size != nil {
+			*size = *size - entry.weight + p.weight(item)
+		}
+
+		entry.weight = p.weight(item)
+	}
+
+	p.OnAccess(item)
+}
+
+// OnRemove removes the item from its segment.
+// If the item was evicted from the main space because the window's
+// candidate won the admission, the candidate is moved into the main
+// space.
+func (p *TinyLFUPolicy[K, V]) OnRemove(item *Item[K, V], reason EvictionReason) {
+	entry := p.entries[item.key]
+	if entry == nil {
+		return
+	}
+
+	admit := reason == EvictionReasonCapacityReached &&
+		entry.segment != tinyLFUWindow &&
+		p.windowFull()
+
+	p.segment(entry.segment).Remove(entry.elem)
+	if size := p.size(entry.segment); size != nil {
+		*size -= entry.weight
+	}
+
+	delete(p.entries, item.key)
+
+	if admit {
+		p.moveToProbation(p.window.Back())
+	}
+}
+
+// Victim returns the item that should be evicted next.
+// When the admission window is full, its least recently used item
+// competes with the main space's victim; the less frequently used
+// one of the two is returned.
+// The policy is not modified until the item is removed.
+func (p *TinyLFUPolicy[K, V]) Victim() *Item[K, V] {
+	item, _ := p.victim()
+	return item
+}
+
+// Rejected reports whether the item that was last returned by
+// Victim was refused admission into the main space.
+func (p *TinyLFUPolicy[K, V]) Rejected() bool {
+	_, rejected := p.victim()
+	return rejected
+}
+
+// victim returns the item that should be evicted next and reports
+// whether it is the window's candidate that was refused admission.
+func (p *TinyLFUPolicy[K, V]) victim() (*Item[K, V], bool) {
+	victim := p.probation.Back()
+	if victim == nil {
+		victim = p.protected.Back()
+	}
+
+	candidate := p.window.Back()
+
+	switch {
+	case candidate == nil && victim == nil:
+		return nil, false
+	case victim == nil:
+		return candidate.Value.(*Item[K, V]), false
+	case candidate == nil || !p.windowFull():
+		return victim.Value.(*Item[K, V]), false
+	}
+
+	candidateItem := candidate.Value.(*Item[K, V])
+	victimItem := victim.Value.(*Item[K, V])
+
+	if p.sketch.estimate(hashKey(candidateItem.key)) > p.sketch.estimate(hashKey(victimItem.key)) {
+		return victimItem, false
+	}
+
+	return candidateItem, true
+}
+
+// windowFull reports whether the window cannot take another item
+// that weighs as much as its least recently used item, in which case
+// an insertion would push that item into the main space.
+func (p *TinyLFUPolicy[K, V]) windowFull() bool {
+	candidate := p.window.Back()
+	if candidate == nil {
+		return false
+	}
+
+	return p.windowSize+p.entries[candidate.Value.(*Item[K, V]).key].weight > p.windowCap
+}
+
+// weight returns the weight of the provided item.
+func (p *TinyLFUPolicy[K, V]) weight(item *Item[K, V]) uint64 {
+	if p.weighted {
+		return item.cost
+	}
+
+	return 1
+}
+
+// size returns the size of the provided segment, or nil if the
+// segment is not limited.
+func (p *TinyLFUPolicy[K, V]) size(s uint8) *uint64 {
+	switch s {
+	case tinyLFUWindow:
+		return &p.windowSize
+	case tinyLFUProtected:
+		return &p.protectedSize
+	default:
+		return nil
+	}
+}
+
+// moveToProbation moves the provided element from its current
+// segment to the front of the probation segment.
+func (p *TinyLFUPolicy[K, V]) moveToProbation(elem *list.Element) {
+	item := elem.Value.(*Item[K, V])
+	entry := p.entries[item.key]
+
+	p.segment(entry.segment).Remove(elem)
+	if size := p.size(entry.segment); size != nil {
+		*size -= entry.weight
+	}
+
+	entry.elem = p.probation.PushFront(item)
+	entry.segment = tinyLFUProbation
+}
+
+// segment returns the list of the provided segment.
+func (p *TinyLFUPolicy[K, V]) segment(s uint8) *list.List {
+	switch s {
+	case tinyLFUProbation:
+		return p.probation
+	case tinyLFUProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+// maxSketchWidth limits the number of counters in each row of the
+// frequency sketch.
+const maxSketchWidth = 1 << 24
+
+// frequencySketch is a count-min sketch with 4-bit counters that is
+// used to estimate the access frequencies of keys. Two counters are
+// packed into each byte. The first access of a key is recorded only
+// in the doorkeeper bloom filter, so that keys that are seen once do
+// not pollute the sketch.
+// All counters are periodically halved to keep the estimations
+// fresh.
+type frequencySketch struct {
+	counters   [4][]uint8
+	mask       uint64
+	doorkeeper []uint64
+	doorMask   uint64
+
+	additions  uint64
+	sampleSize uint64
+}
+
+// newFrequencySketch creates a new frequency sketch sized for the
+// provided number of keys.
+func newFrequencySketch(capacity uint64) *frequencySketch {
+	if capacity > maxSketchWidth {
+		capacity = maxSketchWidth
+	}
+
+	width := uint64(64)
+	for width < capacity {
+		width <<= 1
+	}
+
+	sampleSize := capacity * 10
+	if sampleSize < width {
+		sampleSize = width
+	}
+
+	s := &frequencySketch{
+		mask:       width - 1,
+		doorkeeper: make([]uint64, width/8),
+		doorMask:   width*8 - 1,
+		sampleSize: sampleSize,
+	}
+
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, width/2)
+	}
+
+	return s
+}
+
+// counter returns the value of the counter with the provided index in
+// the i-th row of the sketch.
+func (s *frequencySketch) counter(i int, idx uint64) uint8 {
+	return s.counters[i][idx>>1] >> ((idx & 1) << 2) & 0x0f
+}
+
+// index returns the counter index of the provided hash in the i-th
+// row of the sketch.
+func (s *frequencySketch) index(h uint64, i int) uint64 {
+	return s.hash(h, i) & s.mask
+}
+
+// hash derives the i-th hash function's value from the provided
+// hash.
+func (s *frequencySketch) hash(h uint64, i int) uint64 {
+	h1, h2 := h, h>>32|h<<32
+	return h1 + uint64(i)*h2
+}
+
+// doorIndex returns the bit index of the provided hash for the i-th
+// hash function of the doorkeeper.
+func (s *frequencySketch) doorIndex(h uint64, i int) uint64 {
+	return s.hash(h, i+len(s.counters)) & s.doorMask
+}
+
+// increment records a single occurrence of the provided hash.
+func (s *frequencySketch) increment(h uint64) {
+	if !s.admitDoorkeeper(h) {
+		for i := range s.counters {
+			idx := s.index(h, i)
+			if s.counter(i, idx) < 15 {
+				s.counters[i][idx>>1] += 1 << ((idx & 1) << 2)
+			}
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// estimate returns the estimated number of occurrences of the
+// provided hash.
+func (s *frequencySketch) estimate(h uint64) uint8 {
+	res := uint8(15)
+	for i := range s.counters {
+		if v := s.counter(i, s.index(h, i)); v < res {
+			res = v
+		}
+	}
+
+	if s.inDoorkeeper(h) {
+		res++
+	}
+
+	return res
+}
+
+// admitDoorkeeper adds the provided hash to the doorkeeper.
+// It returns true if the hash was not present in it yet.
+func (s *frequencySketch) admitDoorkeeper(h uint64) bool {
+	if s.inDoorkeeper(h) {
+		return false
+	}
+
+	for i := 0; i < 2; i++ {
+		idx := s.doorIndex(h, i)
+		s.doorkeeper[idx/64] |= 1 << (idx % 64)
+	}
+
+	return true
+}
+
+// inDoorkeeper checks whether the provided hash is present in the
+// doorkeeper.
+func (s *frequencySketch) inDoorkeeper(h uint64) bool {
+	for i := 0; i < 2; i++ {
+		idx := s.doorIndex(h, i)
+		if s.doorkeeper[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reset halves all counters and clears the doorkeeper.
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			// both packed counters are halved at once
+			s.counters[i][j] = s.counters[i][j] >> 1 & 0x77
+		}
+	}
+
+	for i := range s.doorkeeper {
+		s.doorkeeper[i] = 0
+	}
+
+	s.additions /= 2
+}
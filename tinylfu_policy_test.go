@@ -0,0 +1,301 @@
+package ttlcache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewTinyLFUPolicy(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](1000)
+	require.NotNil(t, p)
+	assert.NotNil(t, p.window)
+	assert.NotNil(t, p.probation)
+	assert.NotNil(t, p.protected)
+	assert.NotNil(t, p.entries)
+	assert.NotNil(t, p.sketch)
+	assert.False(t, p.weighted)
+	assert.Equal(t, uint64(10), p.windowCap)
+	assert.Equal(t, uint64(792), p.protectedCap)
+
+	p = NewTinyLFUPolicy[string, string](1)
+	assert.Equal(t, uint64(1), p.windowCap)
+	assert.Zero(t, p.protectedCap)
+}
+
+func Test_NewWeightedTinyLFUPolicy(t *testing.T) {
+	p := NewWeightedTinyLFUPolicy[string, string](1 << 30)
+	require.NotNil(t, p)
+	assert.True(t, p.weighted)
+	assert.Equal(t, uint64(1<<30/100), p.windowCap)
+	assert.Equal(t, (uint64(1<<30)-1<<30/100)*8/10, p.protectedCap)
+	assert.Equal(t, uint64(maxWeightedSketchKeys-1), p.sketch.mask)
+}
+
+func Test_TinyLFUPolicy_OnInsert(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](10)
+	p.OnInsert(&Item[string, string]{key: "1"})
+	assert.Equal(t, 1, p.window.Len())
+	assert.Equal(t, tinyLFUWindow, p.entries["1"].segment)
+
+	// window overflow
+	p.OnInsert(&Item[string, string]{key: "2"})
+	assert.Equal(t, 1, p.window.Len())
+	assert.Equal(t, 1, p.probation.Len())
+	assert.Equal(t, tinyLFUProbation, p.entries["1"].segment)
+	assert.Equal(t, tinyLFUWindow, p.entries["2"].segment)
+}
+
+func Test_TinyLFUPolicy_OnAccess(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](3)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnInsert(item3)
+	require.Equal(t, 2, p.probation.Len())
+
+	// window item
+	p.OnAccess(item3)
+	assert.Equal(t, tinyLFUWindow, p.entries["3"].segment)
+
+	// promotion from probation to protected
+	p.OnAccess(item1)
+	assert.Equal(t, tinyLFUProtected, p.entries["1"].segment)
+	assert.Equal(t, 1, p.protected.Len())
+
+	// protected item
+	p.OnAccess(item1)
+	assert.Equal(t, tinyLFUProtected, p.entries["1"].segment)
+
+	// protected overflow demotes its least recently used item
+	p.OnAccess(item2)
+	assert.Equal(t, tinyLFUProtected, p.entries["2"].segment)
+	assert.Equal(t, tinyLFUProbation, p.entries["1"].segment)
+	assert.Equal(t, 1, p.protected.Len())
+
+	// unknown items are ignored
+	p.OnAccess(&Item[string, string]{key: "4"})
+	assert.Len(t, p.entries, 3)
+}
+
+func Test_TinyLFUPolicy_OnUpdate(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](3)
+	item := &Item[string, string]{key: "1"}
+	p.OnInsert(item)
+	p.OnInsert(&Item[string, string]{key: "2"})
+
+	p.OnUpdate(item)
+	assert.Equal(t, tinyLFUProtected, p.entries["1"].segment)
+
+	// weights are updated
+	p = NewWeightedTinyLFUPolicy[string, string](1000)
+	item = &Item[string, string]{key: "1", cost: 5}
+	p.OnInsert(item)
+	assert.Equal(t, uint64(5), p.windowSize)
+
+	item.cost = 8
+	p.OnUpdate(item)
+	assert.Equal(t, uint64(8), p.windowSize)
+	assert.Equal(t, uint64(8), p.entries["1"].weight)
+}
+
+func Test_TinyLFUPolicy_OnRemove(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](3)
+	item1 := &Item[string, string]{key: "1"}
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+
+	p.OnRemove(item1, EvictionReasonDeleted)
+	assert.Zero(t, p.probation.Len())
+	assert.NotContains(t, p.entries, "1")
+
+	p.OnRemove(item2, EvictionReasonDeleted)
+	assert.Zero(t, p.window.Len())
+	assert.Zero(t, p.windowSize)
+	assert.Empty(t, p.entries)
+
+	// unknown items are ignored
+	p.OnRemove(item1, EvictionReasonDeleted)
+
+	// the candidate is admitted when the victim is evicted
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	p.OnRemove(item1, EvictionReasonCapacityReached)
+	assert.Equal(t, tinyLFUProbation, p.entries["2"].segment)
+	assert.Zero(t, p.window.Len())
+
+	// but not when it is deleted
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item3)
+	p.OnRemove(item2, EvictionReasonDeleted)
+	assert.Equal(t, tinyLFUWindow, p.entries["3"].segment)
+}
+
+func Test_TinyLFUPolicy_Victim(t *testing.T) {
+	p := NewTinyLFUPolicy[string, string](2)
+	assert.Nil(t, p.Victim())
+
+	// main space is empty
+	item1 := &Item[string, string]{key: "1"}
+	p.OnInsert(item1)
+	assert.Same(t, item1, p.Victim())
+	assert.False(t, p.Rejected())
+
+	// candidate is not used more frequently than the victim
+	item2 := &Item[string, string]{key: "2"}
+	p.OnInsert(item2)
+	assert.Same(t, item2, p.Victim())
+	assert.True(t, p.Rejected())
+
+	// candidate is used more frequently than the victim
+	p.OnAccess(item2)
+	p.OnAccess(item2)
+	assert.Same(t, item1, p.Victim())
+	assert.False(t, p.Rejected())
+
+	// the policy is not modified
+	assert.Same(t, item1, p.Victim())
+	assert.Equal(t, tinyLFUWindow, p.entries["2"].segment)
+	assert.Equal(t, 1, p.window.Len())
+
+	// window is not full
+	p.OnRemove(item1, EvictionReasonCapacityReached)
+	item3 := &Item[string, string]{key: "3"}
+	p.OnInsert(item3)
+	p.OnRemove(item3, EvictionReasonDeleted)
+	assert.Same(t, item2, p.Victim())
+	assert.False(t, p.Rejected())
+
+	// weighted window is full when its candidate does not fit again
+	p = NewWeightedTinyLFUPolicy[string, string](1000)
+	item1 = &Item[string, string]{key: "1", cost: 100}
+	item2 = &Item[string, string]{key: "2", cost: 4}
+	p.OnInsert(item1)
+	p.OnInsert(item2)
+	assert.Equal(t, tinyLFUProbation, p.entries["1"].segment)
+	assert.Same(t, item1, p.Victim())
+
+	p.OnInsert(&Item[string, string]{key: "3", cost: 3})
+	assert.Same(t, item2, p.Victim())
+	assert.True(t, p.Rejected())
+}
+
+func Test_frequencySketch(t *testing.T) {
+	s := newFrequencySketch(10)
+	assert.Equal(t, uint64(63), s.mask)
+	assert.Equal(t, uint64(511), s.doorMask)
+	assert.Equal(t, uint64(100), s.sampleSize)
+
+	assert.Len(t, s.counters[0], 32)
+
+	s = newFrequencySketch(1000)
+	assert.Equal(t, uint64(1023), s.mask)
+	assert.Len(t, s.counters[0], 512)
+
+	// huge capacities are clamped
+	s = newFrequencySketch(math.MaxUint64 / 4)
+	assert.Equal(t, uint64(maxSketchWidth-1), s.mask)
+	assert.Equal(t, uint64(maxSketchWidth*10), s.sampleSize)
+
+	s = newFrequencySketch(1)
+	assert.Equal(t, uint64(64), s.sampleSize)
+
+	h := hashKey("test")
+	assert.Zero(t, s.estimate(h))
+
+	// first occurrence is recorded only by the doorkeeper
+	s.increment(h)
+	assert.Equal(t, uint8(1), s.estimate(h))
+	assert.True(t, s.inDoorkeeper(h))
+
+	s.increment(h)
+	s.increment(h)
+	assert.Equal(t, uint8(3), s.estimate(h))
+
+	// counters are saturated
+	for i := 0; i < 20; i++ {
+		s.increment(h)
+	}
+	assert.Equal(t, uint8(16), s.estimate(h))
+
+	// neighbouring counters are not affected
+	for i := range s.counters {
+		idx := s.index(h, i)
+		assert.Equal(t, uint8(15), s.counter(i, idx))
+		assert.Zero(t, s.counter(i, idx^1))
+	}
+
+	s.reset()
+	assert.Equal(t, uint8(7), s.estimate(h))
+	assert.False(t, s.inDoorkeeper(h))
+	assert.Equal(t, uint64(11), s.additions)
+}
+
+func Test_frequencySketch_increment(t *testing.T) {
+	s := newFrequencySketch(1)
+
+	for i := uint64(0); i < s.sampleSize; i++ {
+		s.increment(hashKey(i))
+	}
+
+	assert.Equal(t, s.sampleSize/2, s.additions)
+}
+
+func Test_Cache_TinyLFUPolicy(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		reasons = make(map[EvictionReason]int)
+	)
+
+	cache := New[string, string](
+		WithCapacity[string, string](10),
+		WithEvictionPolicy[string, string](NewTinyLFUPolicy[string, string](10)),
+	)
+	del := cache.OnEviction(func(_ context.Context, r EvictionReason, _ *Item[string, string]) {
+		mu.Lock()
+		reasons[r]++
+		mu.Unlock()
+
+		wg.Done()
+	})
+	defer del()
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprint("hot", i)
+		cache.Set(key, key, NoTTL)
+
+		for j := 0; j < 5; j++ {
+			cache.Get(key)
+		}
+	}
+
+	// a scan of one-hit keys does not flush the hot keys
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint("scan", i)
+		cache.Set(key, key, NoTTL)
+	}
+	wg.Wait()
+
+	var hot int
+	for _, key := range cache.Keys() {
+		if key[:3] == "hot" {
+			hot++
+		}
+	}
+
+	// with LRU, all hot keys would be evicted
+	assert.GreaterOrEqual(t, hot, 7)
+	assert.Equal(t, 100, reasons[EvictionReasonCapacityReached]+reasons[EvictionReasonRejected])
+	assert.Equal(t, reasons[EvictionReasonRejected], int(cache.Metrics().Rejections))
+	assert.NotZero(t, cache.Metrics().Rejections)
+}
@@ -0,0 +1,106 @@
+package ttlcache
+
+import (
+	"container/list"
+	"time"
+)
+
+// defaultTombstoneCapacity is the maximum number of tombstones that
+// are stored when no other limit is set.
+const defaultTombstoneCapacity = 10000
+
+// tombstone marks a single key that is known to be absent.
+type tombstone[K comparable] struct {
+	key       K
+	expiresAt time.Time
+}
+
+// tombstones stores the keys of items that are known to be absent
+// (e.g. because the loader did not find them). Since all tombstones
+// live for the same duration, they are kept in a list in the order
+// of their expiration.
+type tombstones[K comparable] struct {
+	ttl      time.Duration
+	capacity uint64
+	list     *list.List
+	elems    map[K]*list.Element
+}
+
+// newTombstones creates a new tombstone storage whose tombstones live
+// for the provided duration. The oldest tombstones are dropped once
+// the capacity is reached. If the capacity is 0, the default one is
+// used.
+func newTombstones[K comparable](ttl time.Duration, capacity uint64) *tombstones[K] {
+	if capacity == 0 {
+		capacity = defaultTombstoneCapacity
+	}
+
+	return &tombstones[K]{
+		ttl:      ttl,
+		capacity: capacity,
+		list:     list.New(),
+		elems:    make(map[K]*list.Element),
+	}
+}
+
+// add marks the key as absent. If the key is already marked, its
+// tombstone is renewed. Expired tombstones are removed along the way.
+func (t *tombstones[K]) add(key K) {
+	t.remove(key)
+	t.deleteExpired()
+
+	if uint64(t.list.Len()) >= t.capacity {
+		t.remove(t.list.Front().Value.(*tombstone[K]).key)
+	}
+
+	t.elems[key] = t.list.PushBack(&tombstone[K]{
+		key:       key,
+		expiresAt: time.Now().Add(t.ttl),
+	})
+}
+
+// has checks whether the key is marked as absent and its tombstone
+// is not expired.
+func (t *tombstones[K]) has(key K) bool {
+	elem := t.elems[key]
+	if elem == nil {
+		return false
+	}
+
+	return time.Now().Before(elem.Value.(*tombstone[K]).expiresAt)
+}
+
+// remove removes the key's tombstone. If the key is not marked as
+// absent, the method is no-op.
+func (t *tombstones[K]) remove(key K) {
+	if elem := t.elems[key]; elem != nil {
+		t.list.Remove(elem)
+		delete(t.elems, key)
+	}
+}
+
+// deleteExpired removes all expired tombstones.
+func (t *tombstones[K]) deleteExpired() {
+	now := time.Now()
+
+	for elem := t.list.Front(); elem != nil; elem = t.list.Front() {
+		ts := elem.Value.(*tombstone[K])
+		if now.Before(ts.expiresAt) {
+			return
+		}
+
+		t.list.Remove(elem)
+		delete(t.elems, ts.key)
+	}
+}
+
+// reset removes all tombstones.
+func (t *tombstones[K]) reset() {
+	t.list.Init()
+	t.elems = make(map[K]*list.Element)
+}
+
+// len returns the number of stored tombstones.
+func (t *tombstones[K]) len() int {
+	return t.list.Len()
+}
@@ -0,0 +1,99 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newTombstones(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 5)
+	require.NotNil(t, ts)
+	assert.Equal(t, time.Minute, ts.ttl)
+	assert.Equal(t, uint64(5), ts.capacity)
+	assert.NotNil(t, ts.list)
+	assert.NotNil(t, ts.elems)
+
+	// default capacity
+	ts = newTombstones[string](time.Minute, 0)
+	assert.Equal(t, uint64(defaultTombstoneCapacity), ts.capacity)
+}
+
+func Test_tombstones_add(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 2)
+
+	ts.add("1")
+	ts.add("2")
+	assert.Equal(t, 2, ts.len())
+
+	// renewal moves the tombstone to the back
+	ts.add("1")
+	assert.Equal(t, 2, ts.len())
+	assert.Equal(t, "1", ts.list.Back().Value.(*tombstone[string]).key)
+
+	// the oldest tombstone is dropped when the capacity is reached
+	ts.add("3")
+	assert.Equal(t, 2, ts.len())
+	assert.False(t, ts.has("2"))
+	assert.True(t, ts.has("1"))
+	assert.True(t, ts.has("3"))
+
+	// expired tombstones are removed
+	ts.elems["1"].Value.(*tombstone[string]).expiresAt = time.Now().Add(-time.Minute)
+	ts.add("4")
+	assert.Equal(t, 2, ts.len())
+	assert.NotContains(t, ts.elems, "1")
+	assert.True(t, ts.has("3"))
+	assert.True(t, ts.has("4"))
+}
+
+func Test_tombstones_has(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 0)
+	assert.False(t, ts.has("1"))
+
+	ts.add("1")
+	assert.True(t, ts.has("1"))
+
+	ts.elems["1"].Value.(*tombstone[string]).expiresAt = time.Now().Add(-time.Minute)
+	assert.False(t, ts.has("1"))
+}
+
+func Test_tombstones_remove(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 0)
+	ts.add("1")
+
+	ts.remove("2")
+	assert.Equal(t, 1, ts.len())
+
+	ts.remove("1")
+	assert.Zero(t, ts.len())
+	assert.Empty(t, ts.elems)
+}
+
+func Test_tombstones_deleteExpired(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 0)
+	ts.add("1")
+	ts.add("2")
+	ts.add("3")
+
+	ts.elems["1"].Value.(*tombstone[string]).expiresAt = time.Now().Add(-time.Minute)
+	ts.elems["2"].Value.(*tombstone[string]).expiresAt = time.Now().Add(-time.Minute)
+
+	ts.deleteExpired()
+	assert.Equal(t, 1, ts.len())
+	assert.True(t, ts.has("3"))
+	assert.NotContains(t, ts.elems, "1")
+	assert.NotContains(t, ts.elems, "2")
+}
+
+func Test_tombstones_reset(t *testing.T) {
+	ts := newTombstones[string](time.Minute, 0)
+	ts.add("1")
+	ts.add("2")
+
+	ts.reset()
+	assert.Zero(t, ts.len())
+	assert.Empty(t, ts.elems)
+}
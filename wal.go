@@ -0,0 +1,776 @@
+package ttlcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWALCorrupted is returned by Open when a record that is not at
+	// the end of the write-ahead log cannot be read. Incomplete records
+	// at the end of the log, which are left by crashes, are discarded
+	// instead.
+	ErrWALCorrupted = errors.New("ttlcache: write-ahead log is corrupted")
+
+	// ErrWALClosed is returned by Sync and Compact when the write-ahead
+	// log is closed or the cache was not created with Open.
+	ErrWALClosed = errors.New("ttlcache: write-ahead log is closed")
+)
+
+// WALSyncPolicy specifies when the write-ahead log is synced to the
+// disk.
+type WALSyncPolicy int
+
+// Available sync policies.
+const (
+	// WALSyncAlways syncs the log after each appended record, so no
+	// acknowledged change is lost, even if the machine crashes.
+	WALSyncAlways WALSyncPolicy = iota
+
+	// WALSyncInterval syncs the log periodically (see
+	// WALConfig.SyncInterval), so the changes of the last interval
+	// may be lost if the machine crashes.
+	WALSyncInterval
+
+	// WALSyncNever leaves the syncing of the log to the operating
+	// system, so the changes survive crashes of the process, but not
+	// necessarily of the machine.
+	WALSyncNever
+)
+
+// WALConfig holds the configuration of the write-ahead log of a cache.
+type WALConfig struct {
+	// Dir specifies the directory that holds the log segments and
+	// snapshots. It is created if it does not exist.
+	Dir string
+
+	// SyncPolicy specifies when the log is synced to the disk.
+	SyncPolicy WALSyncPolicy
+
+	// SyncInterval specifies how often the log is synced when the
+	// WALSyncInterval policy is used.
+	// If it is 0 or below, the log is synced every second.
+	SyncInterval time.Duration
+
+	// SegmentSize specifies the size in bytes after which the current
+	// log segment is closed and a new one is started.
+	// If it is 0 or below, 64 MiB are used.
+	SegmentSize int64
+
+	// CompactionThreshold specifies the total size in bytes of the log
+	// segments that were written since the latest snapshot after which
+	// a fresh snapshot is written in the background, so that the
+	// segments can be removed.
+	// If it is 0, four times the segment size is used. If it is below
+	// 0, the log is compacted only when Compact is called.
+	CompactionThreshold int64
+}
+
+const (
+	walSegmentExt  = ".wal"
+	walSnapshotExt = ".snapshot"
+	walTempName    = "snapshot.tmp"
+
+	// walHeaderSize is the size of the header of each record, which
+	// consists of the length and the CRC-32 checksum of its payload.
+	walHeaderSize = 8
+)
+
+// walCRCTable is used to calculate the checksums of log records.
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walOp specifies the change that is recorded by a log record.
+type walOp byte
+
+// Available log record operations.
+const (
+	walOpSet walOp = iota + 1
+	walOpDelete
+	walOpTouch
+	walOpClear
+
+	// walOpSealed marks the records whose payloads, including their
+	// actual operations, are encrypted.
+	walOpSealed walOp = 0x80
+)
+
+// wal is an append-only log of the changes of a cache. The log is
+// split into numbered segment files. Snapshots are numbered after the
+// first segment that they do not cover, so that the state of the cache
+// can be recovered from the latest snapshot and the segments that
+// follow it.
+type wal[K comparable, V any] struct {
+	cfg           WALConfig
+	codec         codecSnapshotCodec[K, V]
+	snapshotCodec SnapshotCodec[K, V]
+
+	mu     sync.Mutex
+	file   *os.File
+	seq    uint64
+	size   int64
+	dirty  bool
+	closed bool
+	buf    []byte
+
+	// logSize is the total size of the segments that were written
+	// since the start of the latest compaction.
+	logSize int64
+
+	// err is the first error that occurred while writing the log.
+	// No records are appended after it occurs.
+	err error
+
+	// compactErr is the error of the latest background compaction.
+	compactErr error
+
+	// compactMu is held while the log is being compacted.
+	compactMu sync.Mutex
+
+	// keys is nil unless the snapshot codec encrypts snapshots (see
+	// NewSecureSnapshotCodec), in which case the payloads of the
+	// records are encrypted too. New records are encrypted by aead
+	// with the key whose ID is keyID, while aeads holds the ciphers
+	// of the keys that were used to read the log.
+	keys   KeyProvider
+	keyID  string
+	aead   cipher.AEAD
+	aeads  map[string]cipher.AEAD
+	sealed []byte
+	plain  []byte
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// openWAL opens the write-ahead log in the configured directory and
+// returns it along with the snapshot items that were recovered from
+// it, in the order in which they were last set.
+func openWAL[K comparable, V any](cfg WALConfig, codec codecSnapshotCodec[K, V], snapshotCodec SnapshotCodec[K, V]) (*wal[K, V], []SnapshotItem[K, V], error) {
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = 64 << 20
+	}
+
+	if cfg.CompactionThreshold == 0 {
+		cfg.CompactionThreshold = 4 * cfg.SegmentSize
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	w := &wal[K, V]{
+		cfg:           cfg,
+		codec:         codec,
+		snapshotCodec: snapshotCodec,
+		seq:           1,
+		stopCh:        make(chan struct{}),
+	}
+
+	if sc, ok := snapshotCodec.(secureSnapshotCodec[K, V]); ok && sc.cfg.Keys != nil {
+		w.keys = sc.cfg.Keys
+		w.aeads = make(map[string]cipher.AEAD)
+	}
+
+	segments, snapshots, err := w.list()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := newSnapshotState[K, V]()
+
+	if len(snapshots) > 0 {
+		w.seq = snapshots[len(snapshots)-1]
+
+		if err := w.readSnapshot(state); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var live []uint64
+	for _, seq := range segments {
+		if seq >= w.seq {
+			live = append(live, seq)
+		}
+	}
+
+	for i, seq := range live {
+		size, err := w.replaySegment(seq, state, i == len(live)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		w.seq = seq
+		w.logSize += size
+	}
+
+	if err := w.removeStale(w.seq); err != nil {
+		return nil, nil, err
+	}
+
+	if err := w.openSegment(); err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.SyncPolicy == WALSyncInterval {
+		w.wg.Add(1)
+		go w.syncLoop()
+	}
+
+	return w, state.items(), nil
+}
+
+// path returns the path of the file with the provided sequence number
+// and extension.
+func (w *wal[K, V]) path(seq uint64, ext string) string {
+	return filepath.Join(w.cfg.Dir, fmt.Sprintf("%020d%s", seq, ext))
+}
+
+// list returns the sequence numbers of all segments and snapshots in
+// the log's directory in ascending order.
+func (w *wal[K, V]) list() ([]uint64, []uint64, error) {
+	entries, err := os.ReadDir(w.cfg.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var segments, snapshots []uint64
+
+	// entries are sorted by their names, which are zero-padded
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if ext != walSegmentExt && ext != walSnapshotExt {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), ext), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if ext == walSegmentExt {
+			segments = append(segments, seq)
+		} else {
+			snapshots = append(snapshots, seq)
+		}
+	}
+
+	return segments, snapshots, nil
+}
+
+// readSnapshot reads the items of the snapshot that is numbered after
+// the current segment into the provided state.
+func (w *wal[K, V]) readSnapshot(state *snapshotState[K, V]) error {
+	f, err := os.Open(w.path(w.seq, walSnapshotExt))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return decodeSnapshot(bufio.NewReader(f), w.snapshotCodec, state)
+}
+
+// replaySegment applies the records of the segment with the provided
+// sequence number to the provided state and returns the segment's
+// size. Incomplete or corrupted records at the end of the last segment
+// are discarded by truncating it.
+func (w *wal[K, V]) replaySegment(seq uint64, state *snapshotState[K, V], last bool) (int64, error) {
+	path := w.path(seq, walSegmentExt)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var off int
+	for off+walHeaderSize <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[off:]))
+		if n == 0 || n > len(data)-off-walHeaderSize {
+			break
+		}
+
+		payload := data[off+walHeaderSize : off+walHeaderSize+n]
+		if crc32.Checksum(payload, walCRCTable) != binary.LittleEndian.Uint32(data[off+4:]) {
+			break
+		}
+
+		payload, err := w.open(payload, seq, int64(off))
+		if err != nil {
+			return 0, err
+		}
+
+		if err := w.apply(state, payload); err != nil {
+			return 0, err
+		}
+
+		off += walHeaderSize + n
+	}
+
+	if off == len(data) {
+		return int64(off), nil
+	}
+
+	if !last {
+		return 0, fmt.Errorf("%w: %s at offset %d", ErrWALCorrupted, filepath.Base(path), off)
+	}
+
+	if err := os.Truncate(path, int64(off)); err != nil {
+		return 0, err
+	}
+
+	return int64(off), nil
+}
+
+// apply applies the change that is recorded by the provided record
+// payload to the provided state.
+func (w *wal[K, V]) apply(state *snapshotState[K, V], payload []byte) error {
+	r := bytes.NewReader(payload[1:])
+
+	switch walOp(payload[0]) {
+	case walOpSet:
+		item, err := w.codec.readItem(r, &w.buf)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		state.set(item)
+	case walOpDelete:
+		key, err := w.codec.readKey(r, &w.buf)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		state.delete(key)
+	case walOpTouch:
+		key, err := w.codec.readKey(r, &w.buf)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		expiresAt, err := binary.ReadVarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+
+		state.touch(key, unixNanoToTime(expiresAt))
+	case walOpClear:
+		state.clear()
+	default:
+		return fmt.Errorf("%w: unknown operation %d", ErrWALCorrupted, payload[0])
+	}
+
+	return nil
+}
+
+// open returns the decrypted payload of the provided record payload,
+// which starts at the provided offset of the segment with the
+// provided sequence number. Payloads are returned as they are if the
+// records are not encrypted.
+func (w *wal[K, V]) open(payload []byte, seq uint64, off int64) ([]byte, error) {
+	sealed := walOp(payload[0]) == walOpSealed
+
+	switch {
+	case sealed && w.keys == nil:
+		return nil, fmt.Errorf("%w: log record is encrypted, but no keys are provided", ErrSnapshotFormat)
+	case !sealed && w.keys != nil:
+		return nil, fmt.Errorf("%w: log record is not encrypted", ErrSnapshotFormat)
+	case !sealed:
+		return payload, nil
+	}
+
+	data := payload[1:]
+
+	n, size := binary.Uvarint(data)
+	if size <= 0 || n > uint64(len(data)-size) {
+		return nil, fmt.Errorf("%w: invalid encrypted record", ErrWALCorrupted)
+	}
+
+	id := string(data[size : size+int(n)])
+	data = data[size+int(n):]
+
+	aead, ok := w.aeads[id]
+	if !ok {
+		key, err := w.keys.Key(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if aead, err = newSnapshotAEAD(key); err != nil {
+			return nil, err
+		}
+
+		w.aeads[id] = aead
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid encrypted record", ErrWALCorrupted)
+	}
+
+	var err error
+
+	w.plain, err = aead.Open(w.plain[:0], data[:aead.NonceSize()], data[aead.NonceSize():], walRecordAAD(seq, off))
+	if err != nil {
+		return nil, ErrSnapshotDecryption
+	}
+
+	if len(w.plain) == 0 {
+		return nil, fmt.Errorf("%w: empty encrypted record", ErrWALCorrupted)
+	}
+
+	return w.plain, nil
+}
+
+// seal returns the provided record with its payload encrypted.
+// The record is returned as it is if the records are not encrypted.
+// Not concurrently safe.
+func (w *wal[K, V]) seal(record []byte) ([]byte, error) {
+	if w.aead == nil {
+		return record, nil
+	}
+
+	buf := append(w.sealed[:0], record[:walHeaderSize]...)
+	buf = append(buf, byte(walOpSealed))
+	buf = appendUvarint(buf, uint64(len(w.keyID)))
+	buf = append(buf, w.keyID...)
+
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return record, err
+	}
+
+	buf = append(buf, nonce...)
+	w.sealed = w.aead.Seal(buf, nonce, record[walHeaderSize:], walRecordAAD(w.seq, w.size))
+
+	return w.sealed, nil
+}
+
+// walRecordAAD returns the additional authenticated data of an
+// encrypted record, which binds it to its position in the log, so
+// that records cannot be reordered or moved between segments.
+func walRecordAAD(seq uint64, off int64) []byte {
+	var aad [16]byte
+	binary.BigEndian.PutUint64(aad[:], seq)
+	binary.BigEndian.PutUint64(aad[8:], uint64(off))
+
+	return aad[:]
+}
+
+// removeStale removes the segments and snapshots that are numbered
+// below the provided sequence number, as well as the leftovers of
+// interrupted compactions.
+func (w *wal[K, V]) removeStale(seq uint64) error {
+	segments, snapshots, err := w.list()
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, s := range segments {
+		if s < seq {
+			paths = append(paths, w.path(s, walSegmentExt))
+		}
+	}
+
+	for _, s := range snapshots {
+		if s < seq {
+			paths = append(paths, w.path(s, walSnapshotExt))
+		}
+	}
+
+	paths = append(paths, filepath.Join(w.cfg.Dir, walTempName))
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openSegment opens the current segment for appending. If the records
+// are encrypted, the current key is used for the records of the
+// segment.
+// Not concurrently safe.
+func (w *wal[K, V]) openSegment() error {
+	if w.keys != nil {
+		id, key, err := w.keys.CurrentKey()
+		if err != nil {
+			return err
+		}
+
+		aead, err := newSnapshotAEAD(key)
+		if err != nil {
+			return err
+		}
+
+		w.keyID, w.aead = id, aead
+	}
+
+	f, err := os.OpenFile(w.path(w.seq, walSegmentExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if info.Size() == 0 && w.cfg.SyncPolicy != WALSyncNever {
+		syncDir(w.cfg.Dir)
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	return nil
+}
+
+// append appends a record of the provided operation on the provided
+// item to the log and reports whether the log should be compacted.
+// The item is not used by the clear operation.
+// The item must not be modified concurrently.
+func (w *wal[K, V]) append(op walOp, item *Item[K, V]) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || w.err != nil {
+		return false
+	}
+
+	var (
+		header [walHeaderSize]byte
+		err    error
+	)
+
+	buf := append(w.buf[:0], header[:]...)
+	buf = append(buf, byte(op))
+
+	switch op {
+	case walOpSet:
+		buf, err = w.codec.appendItem(buf, newSnapshotItem(item))
+	case walOpDelete:
+		buf, err = w.codec.appendKey(buf, item.key)
+	case walOpTouch:
+		buf, err = w.codec.appendKey(buf, item.key)
+		buf = appendVarint(buf, timeToUnixNano(item.expiresAt))
+	}
+
+	w.buf = buf
+
+	if err == nil {
+		buf, err = w.seal(buf)
+	}
+
+	if err != nil {
+		w.err = err
+		return false
+	}
+
+	putWALHeader(buf)
+
+	n, err := w.file.Write(buf)
+	w.size += int64(n)
+	w.logSize += int64(n)
+
+	if err != nil {
+		w.err = err
+		return false
+	}
+
+	if w.cfg.SyncPolicy == WALSyncAlways {
+		w.err = w.file.Sync()
+	} else {
+		w.dirty = true
+	}
+
+	if w.err == nil && w.size >= w.cfg.SegmentSize {
+		w.err = w.rotateUnsafe()
+	}
+
+	return w.err == nil && w.cfg.CompactionThreshold > 0 && w.logSize >= w.cfg.CompactionThreshold
+}
+
+// putWALHeader writes the header of the provided record, whose payload
+// follows the space reserved for the header.
+func putWALHeader(record []byte) {
+	payload := record[walHeaderSize:]
+	binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, walCRCTable))
+}
+
+// rotateUnsafe closes the current segment and starts a new one.
+// Not concurrently safe.
+func (w *wal[K, V]) rotateUnsafe() error {
+	if w.dirty && w.cfg.SyncPolicy != WALSyncNever {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	w.dirty = false
+	w.seq++
+
+	return w.openSegment()
+}
+
+// rotate starts a new segment, so that all existing segments can be
+// replaced by a snapshot, and returns its sequence number.
+func (w *wal[K, V]) rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWALClosed
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	if w.err = w.rotateUnsafe(); w.err != nil {
+		return 0, w.err
+	}
+
+	w.logSize = 0
+
+	return w.seq, nil
+}
+
+// writeSnapshot writes the provided snapshot items as the snapshot
+// that is numbered after the provided segment and then removes all
+// segments and snapshots that it replaces.
+func (w *wal[K, V]) writeSnapshot(seq uint64, items []SnapshotItem[K, V]) error {
+	tmp := filepath.Join(w.cfg.Dir, walTempName)
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	if err := encodeSnapshot[K, V](bw, w.snapshotCodec, items); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	// the snapshot must be durable before the segments are removed
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, w.path(seq, walSnapshotExt)); err != nil {
+		return err
+	}
+
+	syncDir(w.cfg.Dir)
+
+	return w.removeStale(seq)
+}
+
+// syncLoop periodically syncs the log until it is closed.
+func (w *wal[K, V]) syncLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			_ = w.sync()
+		}
+	}
+}
+
+// sync syncs the records that were appended since the last sync and
+// returns the first error that occurred while writing the log.
+func (w *wal[K, V]) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+
+	if w.err == nil && w.dirty {
+		w.err = w.file.Sync()
+		w.dirty = false
+	}
+
+	return w.err
+}
+
+// close waits for the background work to finish, syncs the log and
+// closes it. It returns the first error that occurred while writing
+// the log, or the error of the latest background compaction.
+func (w *wal[K, V]) close() error {
+	close(w.stopCh)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	err := w.err
+	if err == nil && w.dirty {
+		err = w.file.Sync()
+	}
+
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = w.compactErr
+	}
+
+	return err
+}
+
+// syncDir syncs the directory, so that the files that were created
+// in or renamed into it are durable. Errors are ignored, since
+// directories cannot be synced on all platforms.
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	_ = f.Sync()
+	_ = f.Close()
+}
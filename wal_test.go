@@ -0,0 +1,398 @@
+package ttlcache
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openWAL(t *testing.T) {
+	codec := newCodecSnapshotCodec[string, string](nil, nil)
+
+	// defaults
+	dir := filepath.Join(t.TempDir(), "wal")
+	w, items, err := openWAL[string, string](WALConfig{Dir: dir}, codec, GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Equal(t, time.Second, w.cfg.SyncInterval)
+	assert.Equal(t, int64(64<<20), w.cfg.SegmentSize)
+	assert.Equal(t, int64(256<<20), w.cfg.CompactionThreshold)
+	assert.Equal(t, uint64(1), w.seq)
+	assert.FileExists(t, w.path(1, walSegmentExt))
+	require.NoError(t, w.close())
+
+	// snapshot and the segments that follow it
+	writeWALSnapshot(t, w, 3, SnapshotItem[string, string]{Key: "1", Value: "value1", TTL: NoTTL})
+	writeWALSegment(t, w, 2, walRecord(t, w, walOpSet, "0", "value0"))
+	writeWALSegment(t, w, 3, walRecord(t, w, walOpSet, "2", "value2"))
+	writeWALSegment(t, w, 4, walRecord(t, w, walOpDelete, "1", ""))
+
+	w, items, err = openWAL[string, string](WALConfig{Dir: dir, SyncPolicy: WALSyncInterval}, codec, GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "2", items[0].Key)
+	assert.Equal(t, uint64(4), w.seq)
+	assert.Greater(t, w.logSize, int64(0))
+	assert.NoFileExists(t, w.path(1, walSegmentExt))
+	assert.NoFileExists(t, w.path(2, walSegmentExt))
+	require.NoError(t, w.close())
+
+	// corrupted segment in the middle of the log
+	writeWALSegment(t, w, 5, walRecord(t, w, walOpSet, "3", "value3"))
+	data, err := os.ReadFile(w.path(4, walSegmentExt))
+	require.NoError(t, err)
+	data[len(data)-1]++
+	require.NoError(t, os.WriteFile(w.path(4, walSegmentExt), data, 0o644))
+
+	_, _, err = openWAL[string, string](WALConfig{Dir: dir}, codec, GobSnapshotCodec[string, string]{})
+	assert.ErrorIs(t, err, ErrWALCorrupted)
+
+	// invalid directory
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	_, _, err = openWAL[string, string](WALConfig{Dir: file}, codec, GobSnapshotCodec[string, string]{})
+	assert.Error(t, err)
+}
+
+func Test_wal_list(t *testing.T) {
+	w := &wal[string, string]{cfg: WALConfig{Dir: t.TempDir()}}
+
+	for _, name := range []string{"00000000000000000002.wal", "00000000000000000001.wal", "00000000000000000002.snapshot", "invalid.wal", walTempName} {
+		require.NoError(t, os.WriteFile(filepath.Join(w.cfg.Dir, name), nil, 0o644))
+	}
+
+	segments, snapshots, err := w.list()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2}, segments)
+	assert.Equal(t, []uint64{2}, snapshots)
+}
+
+func Test_wal_replaySegment(t *testing.T) {
+	w := &wal[string, string]{
+		cfg:   WALConfig{Dir: t.TempDir()},
+		codec: newCodecSnapshotCodec[string, string](nil, nil),
+	}
+
+	record := walRecord(t, w, walOpSet, "1", "value1")
+	writeWALSegment(t, w, 1, record, record[:len(record)-1])
+
+	// incomplete record in the middle of the log
+	state := newSnapshotState[string, string]()
+	_, err := w.replaySegment(1, state, false)
+	assert.ErrorIs(t, err, ErrWALCorrupted)
+
+	// incomplete record at the end of the log
+	state = newSnapshotState[string, string]()
+	size, err := w.replaySegment(1, state, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(record)), size)
+	assert.Len(t, state.items(), 1)
+
+	info, err := os.Stat(w.path(1, walSegmentExt))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(record)), info.Size())
+
+	// checksum mismatch
+	corrupted := append([]byte(nil), record...)
+	corrupted[walHeaderSize+1]++
+	writeWALSegment(t, w, 2, corrupted)
+
+	size, err = w.replaySegment(2, newSnapshotState[string, string](), true)
+	require.NoError(t, err)
+	assert.Zero(t, size)
+
+	// undecodable record
+	writeWALSegment(t, w, 3, walFrame([]byte{byte(walOpClear) + 1}))
+
+	_, err = w.replaySegment(3, newSnapshotState[string, string](), true)
+	assert.ErrorIs(t, err, ErrWALCorrupted)
+}
+
+func Test_wal_apply(t *testing.T) {
+	w := &wal[string, string]{
+		codec: newCodecSnapshotCodec[string, string](nil, nil),
+	}
+	state := newSnapshotState[string, string]()
+
+	payload := func(record []byte) []byte {
+		return record[walHeaderSize:]
+	}
+
+	require.NoError(t, w.apply(state, payload(walRecord(t, w, walOpSet, "1", "value1"))))
+	require.NoError(t, w.apply(state, payload(walRecord(t, w, walOpSet, "2", "value2"))))
+	assert.Len(t, state.items(), 2)
+
+	require.NoError(t, w.apply(state, payload(walRecord(t, w, walOpTouch, "1", ""))))
+	assert.Equal(t, "1", state.items()[1].Key)
+
+	require.NoError(t, w.apply(state, payload(walRecord(t, w, walOpDelete, "1", ""))))
+	assert.Len(t, state.items(), 1)
+
+	require.NoError(t, w.apply(state, payload(walRecord(t, w, walOpClear, "", ""))))
+	assert.Empty(t, state.items())
+
+	// incomplete payloads
+	for _, op := range []walOp{walOpSet, walOpDelete, walOpTouch} {
+		assert.ErrorIs(t, w.apply(state, []byte{byte(op)}), io.ErrUnexpectedEOF)
+	}
+
+	assert.ErrorIs(t, w.apply(state, []byte{byte(walOpClear) + 1}), ErrWALCorrupted)
+}
+
+func Test_wal_open(t *testing.T) {
+	keys := StaticKeyProvider{
+		CurrentID: "1",
+		Keys: map[string][]byte{
+			"1": bytes.Repeat([]byte{1}, 32),
+			"2": bytes.Repeat([]byte{2}, 16),
+		},
+	}
+
+	w := &wal[string, string]{
+		codec: newCodecSnapshotCodec[string, string](nil, nil),
+		keys:  keys,
+		aeads: make(map[string]cipher.AEAD),
+		seq:   3,
+		size:  100,
+	}
+
+	payload := walRecord(t, w, walOpSet, "1", "value1")[walHeaderSize:]
+
+	// records are returned as they are if they are not encrypted
+	sealed, err := w.seal(append(make([]byte, walHeaderSize), payload...))
+	require.NoError(t, err)
+	assert.Equal(t, payload, sealed[walHeaderSize:])
+
+	aead, err := newSnapshotAEAD(keys.Keys["1"])
+	require.NoError(t, err)
+	w.keyID, w.aead = "1", aead
+
+	sealed, err = w.seal(append(make([]byte, walHeaderSize), payload...))
+	require.NoError(t, err)
+	sealed = append([]byte(nil), sealed[walHeaderSize:]...)
+	assert.Equal(t, byte(walOpSealed), sealed[0])
+	assert.NotContains(t, string(sealed), "value1")
+
+	res, err := w.open(sealed, 3, 100)
+	require.NoError(t, err)
+	assert.Equal(t, payload, res)
+
+	// rotated key
+	w.keys = StaticKeyProvider{CurrentID: "2", Keys: keys.Keys}
+	w.aeads = make(map[string]cipher.AEAD)
+
+	res, err = w.open(sealed, 3, 100)
+	require.NoError(t, err)
+	assert.Equal(t, payload, res)
+	assert.Len(t, w.aeads, 1)
+
+	// moved records
+	_, err = w.open(sealed, 4, 100)
+	assert.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	_, err = w.open(sealed, 3, 0)
+	assert.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	// unknown key
+	w.keys = StaticKeyProvider{}
+	w.aeads = make(map[string]cipher.AEAD)
+
+	_, err = w.open(sealed, 3, 100)
+	assert.ErrorIs(t, err, ErrSnapshotKeyNotFound)
+
+	// invalid records
+	w.keys = keys
+
+	for _, p := range [][]byte{{byte(walOpSealed)}, {byte(walOpSealed), 2, '1'}, {byte(walOpSealed), 1, '1', 0}} {
+		_, err = w.open(p, 3, 100)
+		assert.ErrorIs(t, err, ErrWALCorrupted)
+	}
+
+	// unencrypted records
+	_, err = w.open(payload, 3, 100)
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	// missing keys
+	w.keys = nil
+
+	_, err = w.open(sealed, 3, 100)
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+
+	res, err = w.open(payload, 3, 100)
+	require.NoError(t, err)
+	assert.Equal(t, payload, res)
+}
+
+func Test_wal_append(t *testing.T) {
+	w, _, err := openWAL[string, string](WALConfig{
+		Dir:                 t.TempDir(),
+		SegmentSize:         100,
+		CompactionThreshold: 150,
+	}, newCodecSnapshotCodec[string, string](BinaryCodec[string]{}, BinaryCodec[string]{}), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+
+	item := newItem("1", "value1", time.Hour, false)
+
+	// segment rotation
+	var compact bool
+	for w.seq == 1 {
+		compact = w.append(walOpSet, item)
+	}
+
+	assert.False(t, compact)
+	assert.Zero(t, w.size)
+	assert.FileExists(t, w.path(2, walSegmentExt))
+
+	// compaction threshold
+	for !compact {
+		compact = w.append(walOpTouch, item)
+	}
+
+	assert.GreaterOrEqual(t, w.logSize, int64(150))
+	require.NoError(t, w.close())
+
+	reopened, items, err := openWAL[string, string](w.cfg, w.codec, w.snapshotCodec)
+	require.NoError(t, err)
+	require.NoError(t, reopened.close())
+	require.Len(t, items, 1)
+	assert.Equal(t, "value1", items[0].Value)
+	assert.True(t, item.expiresAt.Equal(items[0].ExpiresAt))
+
+	// closed log
+	assert.False(t, w.append(walOpSet, item))
+
+	// failed marshal
+	errMarshal := errors.New("error")
+
+	w, _, err = openWAL[string, string](WALConfig{Dir: t.TempDir(), SyncPolicy: WALSyncNever}, newCodecSnapshotCodec[string, string](failingCodec[string]{err: errMarshal}, nil), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+
+	assert.False(t, w.append(walOpDelete, item))
+	assert.Equal(t, errMarshal, w.sync())
+	assert.Equal(t, errMarshal, w.close())
+}
+
+func Test_wal_rotate(t *testing.T) {
+	w, _, err := openWAL[string, string](WALConfig{Dir: t.TempDir()}, newCodecSnapshotCodec[string, string](nil, nil), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+
+	w.append(walOpClear, nil)
+
+	seq, err := w.rotate()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+	assert.Zero(t, w.logSize)
+	assert.False(t, w.dirty)
+	assert.FileExists(t, w.path(2, walSegmentExt))
+
+	require.NoError(t, w.close())
+
+	_, err = w.rotate()
+	assert.Equal(t, ErrWALClosed, err)
+}
+
+func Test_wal_writeSnapshot(t *testing.T) {
+	w, _, err := openWAL[string, string](WALConfig{Dir: t.TempDir()}, newCodecSnapshotCodec[string, string](nil, nil), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+	defer w.close()
+
+	seq, err := w.rotate()
+	require.NoError(t, err)
+
+	items := []SnapshotItem[string, string]{{Key: "1", Value: "value1", TTL: NoTTL}}
+	require.NoError(t, w.writeSnapshot(seq, items))
+	assert.FileExists(t, w.path(seq, walSnapshotExt))
+	assert.NoFileExists(t, w.path(1, walSegmentExt))
+	assert.NoFileExists(t, filepath.Join(w.cfg.Dir, walTempName))
+
+	state := newSnapshotState[string, string]()
+	require.NoError(t, w.readSnapshot(state))
+	assert.Equal(t, items, state.items())
+
+	// failed encoding
+	w.snapshotCodec = NewSnapshotCodec[string, string](failingCodec[string]{err: errors.New("error")}, nil)
+	assert.Error(t, w.writeSnapshot(seq+1, items))
+	assert.NoFileExists(t, w.path(seq+1, walSnapshotExt))
+}
+
+func Test_wal_sync(t *testing.T) {
+	w, _, err := openWAL[string, string](WALConfig{
+		Dir:          t.TempDir(),
+		SyncPolicy:   WALSyncInterval,
+		SyncInterval: time.Millisecond,
+	}, newCodecSnapshotCodec[string, string](nil, nil), GobSnapshotCodec[string, string]{})
+	require.NoError(t, err)
+
+	w.append(walOpClear, nil)
+	assert.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		return !w.dirty
+	}, time.Second, time.Millisecond)
+
+	w.append(walOpClear, nil)
+	require.NoError(t, w.close())
+	assert.Equal(t, ErrWALClosed, w.sync())
+}
+
+// walRecord returns the framed log record of the provided operation.
+func walRecord(t *testing.T, w *wal[string, string], op walOp, key, value string) []byte {
+	t.Helper()
+
+	payload := []byte{byte(op)}
+
+	var err error
+
+	switch op {
+	case walOpSet:
+		payload, err = w.codec.appendItem(payload, SnapshotItem[string, string]{Key: key, Value: value, TTL: NoTTL})
+	case walOpDelete:
+		payload, err = w.codec.appendKey(payload, key)
+	case walOpTouch:
+		payload, err = w.codec.appendKey(payload, key)
+		payload = appendVarint(payload, 0)
+	}
+
+	require.NoError(t, err)
+
+	return walFrame(payload)
+}
+
+// walFrame prepends the record header to the provided payload.
+func walFrame(payload []byte) []byte {
+	record := append(make([]byte, walHeaderSize), payload...)
+	putWALHeader(record)
+
+	return record
+}
+
+func writeWALSegment(t *testing.T, w *wal[string, string], seq uint64, records ...[]byte) {
+	t.Helper()
+
+	var data []byte
+	for _, record := range records {
+		data = append(data, record...)
+	}
+
+	require.NoError(t, os.WriteFile(w.path(seq, walSegmentExt), data, 0o644))
+}
+
+func writeWALSnapshot(t *testing.T, w *wal[string, string], seq uint64, items ...SnapshotItem[string, string]) {
+	t.Helper()
+
+	f, err := os.Create(w.path(seq, walSnapshotExt))
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, encodeSnapshot[string, string](f, GobSnapshotCodec[string, string]{}, items))
+}